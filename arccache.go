@@ -0,0 +1,190 @@
+package memcache
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateARCCache creates and returns an 'Adaptive Replacement Cache' implementation of Cache
+//
+// ARC splits the cache between items that have only been seen once and items that have been seen again, and remembers
+// the keys (not the items) it's recently evicted from each. A miss on a remembered key shifts the split towards the side
+// it was evicted from, so the cache adapts between recency and frequency as the workload changes. A one-off scan can only
+// push out the items seen once, leaving the frequently used ones in place
+func CreateARCCache(maxsize int) (Cache) {
+	policy := &arcPolicy { ghosts: make(map[string]*policyEntry) }
+	cache := createPolicyCache(maxsize, policy)
+	policy.cache = cache
+	return cache
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: arcPolicy (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// arcPolicy is the evictionPolicy for the ARC cache
+//
+// t1 holds entries seen once and t2 entries seen more than once, most recently used at the head of each. b1 and b2 are
+// the 'ghost' lists, the keys and sizes of entries recently evicted from t1 and t2. Ghosts don't hold onto the item so
+// only cost the key. Everything is measured in size rather than count so it works with the cache's max size
+type arcPolicy struct {
+
+	// cache is the cache the policy belongs to, its max size is the size the lists adapt within
+	cache *policyCache
+
+	// t1 holds the live entries that have only been seen once
+	t1 entryList
+
+	// t2 holds the live entries that have been seen more than once
+	t2 entryList
+
+	// b1 holds the ghosts of entries evicted from t1
+	b1 entryList
+
+	// b2 holds the ghosts of entries evicted from t2
+	b2 entryList
+
+	// ghosts is the map of key(string) to ghost entry, for keys in b1 or b2
+	ghosts map[string]*policyEntry
+
+	// target is the size t1 is aiming for, between 0 and max size. The rest of the cache is for t2
+	target int
+
+	// ghostHit is set by admitting when the key being added was a ghost, so it goes straight into t2
+	ghostHit bool
+
+	// incomingInB2 is set by admitting when the key being added was a ghost in b2, it breaks ties in victim towards t1
+	incomingInB2 bool
+}
+
+// trimGhosts drops the oldest ghosts so t1 + b1 stays within max size and all four lists stay within twice max size
+//
+// Zero size ghosts take up no size, so each ghost list is also kept to as many keys as there are live entries (at least
+// one). Otherwise churning zero size items would remember keys forever
+func (this *arcPolicy) trimGhosts() {
+	c := this.cache.maxSize
+	for this.t1.size + this.b1.size > c && this.b1.tail != nil {
+		this.dropGhost(this.b1.tail)
+	}
+	for this.t1.size + this.t2.size + this.b1.size + this.b2.size > 2 * c && this.b2.tail != nil {
+		this.dropGhost(this.b2.tail)
+	}
+
+	maxGhosts := max(this.t1.len + this.t2.len, 1)
+	for this.b1.len > maxGhosts {
+		this.dropGhost(this.b1.tail)
+	}
+	for this.b2.len > maxGhosts {
+		this.dropGhost(this.b2.tail)
+	}
+}
+
+// dropGhost forgets a ghost entry
+func (this *arcPolicy) dropGhost(ghost *policyEntry) {
+	ghost.list.remove(ghost)
+	delete(this.ghosts, ghost.key)
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// evictionPolicy Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// admitting adapts the target size of t1 if the key was recently evicted. A ghost in b1 means t1 was too small so it
+// grows, a ghost in b2 means t2 was too small so t1 shrinks. The bigger the other ghost list the bigger the step
+func (this *arcPolicy) admitting(key string) {
+	ghost, present := this.ghosts[key]
+	if !present {
+		return
+	}
+
+	// Ghosts can have a size of 0, so the lists they're in can too. Fall back to a step of 1 rather than divide by 0,
+	// and count a zero size ghost as 1 so it still adapts the target
+	c := this.cache.maxSize
+	delta := 1
+	if ghost.list == &this.b1 {
+		if this.b1.size > 0 {
+			delta = max(this.b2.size / this.b1.size, 1)
+		}
+		this.target += max(ghost.size, 1) * delta
+		this.target = min(this.target, c)
+	} else {
+		if this.b2.size > 0 {
+			delta = max(this.b1.size / this.b2.size, 1)
+		}
+		this.target -= max(ghost.size, 1) * delta
+		this.target = max(this.target, 0)
+		this.incomingInB2 = true
+	}
+	this.ghostHit = true
+	this.dropGhost(ghost)
+}
+
+// added puts the entry at the head of t2 if the key has been seen before, otherwise at the head of t1
+func (this *arcPolicy) added(entry *policyEntry) {
+	if entry.freq > 1 || this.ghostHit {
+		this.t2.pushFront(entry)
+	} else {
+		this.t1.pushFront(entry)
+	}
+	this.ghostHit = false
+	this.incomingInB2 = false
+	this.trimGhosts()
+}
+
+// accessed moves the entry to the head of t2, its now been seen more than once
+func (this *arcPolicy) accessed(entry *policyEntry) {
+	entry.list.remove(entry)
+	this.t2.pushFront(entry)
+}
+
+// removed takes the entry out of its list. If it was evicted then its remembered as a ghost in b1 or b2, either way
+// there's one less live entry so the ghosts are trimmed
+func (this *arcPolicy) removed(entry *policyEntry, evicted bool) {
+	from := entry.list
+	from.remove(entry)
+	if evicted {
+		ghost := &policyEntry { key: entry.key, size: entry.size }
+		if from == &this.t1 {
+			this.b1.pushFront(ghost)
+		} else {
+			this.b2.pushFront(ghost)
+		}
+		this.ghosts[ghost.key] = ghost
+	}
+	this.trimGhosts()
+}
+
+// victim returns the least recently used entry of t1 if its over its target size, otherwise of t2
+func (this *arcPolicy) victim() *policyEntry {
+	if this.t1.tail != nil && (this.t1.size > this.target || (this.incomingInB2 && this.t1.size >= this.target) || this.t2.tail == nil) {
+		return this.t1.tail
+	}
+	return this.t2.tail
+}
+
+// peekVictim returns victim, which only reads the lists so calling it doesn't change anything
+func (this *arcPolicy) peekVictim() *policyEntry {
+	return this.victim()
+}
+
+// each iterates over t2 then t1, most recently used first within each
+func (this *arcPolicy) each(fn func(entry *policyEntry) bool) {
+	for _, list := range []*entryList { &this.t2, &this.t1 } {
+		for entry := list.head; entry != nil; entry = entry.next {
+			if !fn(entry) {
+				return
+			}
+		}
+	}
+}
+
+// reset drops all entries and ghosts, and starts the target size again from 0
+func (this *arcPolicy) reset() {
+	this.t1 = entryList { }
+	this.t2 = entryList { }
+	this.b1 = entryList { }
+	this.b2 = entryList { }
+	this.ghosts = make(map[string]*policyEntry)
+	this.target = 0
+	this.ghostHit = false
+	this.incomingInB2 = false
+}
\ No newline at end of file
@@ -0,0 +1,161 @@
+package memcache
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestARCCache(t *testing.T) {
+	cache := CreateARCCache(MaxSize)
+
+	// Check cache is created correctly
+	if cache == nil {
+		t.Error("Cache is nil")
+	}
+
+	// Add some keys and access them again so they move into the frequent list
+	for i := 0; i < 5; i++ {
+		cache.Add("hot" + strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+		cache.Get("hot" + strconv.Itoa(i))
+	}
+
+	// Scan through way more keys than will fit, each only seen once
+	for i := 0; i < 50; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// The scan should only have pushed out other keys seen once
+	for i := 0; i < 5; i++ {
+		if !cache.Contains("hot" + strconv.Itoa(i)) {
+			t.Error("hot" + strconv.Itoa(i), "should have survived the scan")
+		}
+	}
+	if cache.Contains("44") || !cache.Contains("45") || !cache.Contains("49") {
+		t.Error("Expected the 5 most recent scanned keys, got", cache.Keys())
+	}
+	if cache.Size() != MaxSize {
+		t.Error("Expected size of", MaxSize, "got", cache.Size())
+	}
+}
+
+func TestARCCacheGhostHit(t *testing.T) {
+	cache := CreateARCCache(30)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	cache.Get("b")
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+
+	// "a" was evicted but is remembered
+	if cache.Contains("a") {
+		t.Error("a should have been evicted")
+	}
+
+	// Adding it again should treat it as seen before and push out the other key only seen once, not "b"
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"a", "b", "d"})
+}
+
+func TestARCCacheRemove(t *testing.T) {
+	cache := CreateARCCache(MaxSize)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Get("b")
+
+	cache.Remove("a")
+	cache.Remove("b")
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected empty cache, got", cache.Len(), "items of size", cache.Size())
+	}
+
+	// Removed keys aren't remembered, so adding again is just like the first time
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"c", "a"})
+}
+
+func TestARCCacheClear(t *testing.T) {
+	cache := CreateARCCache(20)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	cache.Clear()
+
+	// Ghosts should have gone too, "a" goes back in as seen once
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	cache.Add("e", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"e", "d"})
+}
+
+func TestARCCacheZeroCostGhosts(t *testing.T) {
+	cache := CreateARCCache(2)
+	item := &DummyCacheItem{DummySize: 1}
+	cache.AddWithCost("y", item, 2)
+	cache.Get("y")
+	cache.AddWithCost("x", item, 2)
+	cache.Get("x")
+	cache.AddWithCost("z", item, 0)
+	cache.AddWithCost("y", item, 2)
+
+	// z is a ghost with nothing else in its list, adapting the target on it shouldn't divide by zero
+	if err := cache.AddWithCost("z", item, 0); err != nil {
+		t.Error("Expected add to succeed, got", err)
+	}
+	if !cache.Contains("z") {
+		t.Error("Expected z to be present")
+	}
+}
+
+func TestARCCacheZeroSizeGhostsBounded(t *testing.T) {
+	cache := CreateARCCache(10)
+	policy := cache.(*policyCache).policy.(*arcPolicy)
+
+	// Zero size items are evicted along with the sized ones, their ghosts take up no size so only a count keeps them down
+	for i := 0; i < 100; i++ {
+		for j := 0; j <= 100; j++ {
+			key := strconv.Itoa(i) + "-" + strconv.Itoa(j)
+			cache.Add(key, &DummyCacheItem{DummySize: j / 100})
+			cache.Get(key)
+		}
+	}
+
+	// Removing the live items leaves nothing for the ghosts to be ghosts of
+	for _, key := range cache.Keys() {
+		cache.Remove(key)
+	}
+	live := max(cache.Len(), 1)
+	if policy.b1.len > live || policy.b2.len > live || len(policy.ghosts) != policy.b1.len + policy.b2.len {
+		t.Error("Expected at most", live, "ghosts in each list, got", policy.b1.len, "and", policy.b2.len)
+	}
+}
+
+func TestARCCacheHitRate(t *testing.T) {
+	arc := CreateARCCache(MaxSize)
+	lru := CreateLRUCache(MaxSize)
+
+	// A small hot set that's read constantly, interrupted by scans over keys that are never read again
+	random := rand.New(rand.NewSource(1))
+	scan := 0
+	for i := 0; i < 20000; i++ {
+		var key string
+		if i % 1000 < 200 {
+			key = "scan" + strconv.Itoa(scan)
+			scan++
+		} else {
+			key = "hot" + strconv.Itoa(random.Intn(8))
+		}
+
+		for _, cache := range []Cache { arc, lru } {
+			if _, present := cache.Get(key); !present {
+				cache.Add(key, &DummyCacheItem{DummySize: 10})
+			}
+		}
+	}
+
+	arcStats, lruStats := arc.Stats(), lru.Stats()
+	if arcStats.Hits <= lruStats.Hits {
+		t.Error("Expected ARC to get more hits than LRU, got", arcStats.Hits, "vs", lruStats.Hits)
+	}
+}
\ No newline at end of file
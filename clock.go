@@ -0,0 +1,17 @@
+package memcache
+
+import (
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Interface: clock (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// clock tells a cache what the time is, for working out expiry, ages and ramps. Caches use time.Now while their clock is
+// nil, so only tests that need to control the time give them one
+type clock interface {
+
+	// Now returns the current time
+	Now() time.Time
+}
\ No newline at end of file
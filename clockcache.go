@@ -0,0 +1,126 @@
+package memcache
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateClockCache creates and returns a 'Clock' (second chance) implementation of Cache
+//
+// Items are kept in a circle with a hand pointing at the next one to evict. Get just marks an item as referenced rather
+// than moving it, so its cheaper than LRU. When the cache goes over max size the hand sweeps round, clearing the mark on
+// referenced items and skipping them, and evicts the first item that isn't marked
+func CreateClockCache(maxsize int) (Cache) {
+	return createPolicyCache(maxsize, &clockPolicy { })
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: clockPolicy (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// clockPolicy is the evictionPolicy for the Clock cache
+//
+// The circle is an entryList read from head to tail and then back round to the head. New entries go in just before the
+// hand, so they're the last the hand comes to
+type clockPolicy struct {
+
+	// entries holds every entry in the circle
+	entries entryList
+
+	// hand is the next entry to look at when something needs evicting, nil if there are no entries
+	hand *policyEntry
+}
+
+// advance moves the hand on to the next entry round the circle
+func (this *clockPolicy) advance() {
+	if this.hand.next != nil {
+		this.hand = this.hand.next
+	} else {
+		this.hand = this.entries.head
+	}
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// evictionPolicy Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// admitting does nothing, Clock doesn't remember keys once they've gone
+func (this *clockPolicy) admitting(key string) {
+}
+
+// added puts the entry in just before the hand. A key that's been seen before starts off referenced
+func (this *clockPolicy) added(entry *policyEntry) {
+	entry.referenced = entry.freq > 1
+	if this.hand == nil {
+		this.entries.pushFront(entry)
+		this.hand = entry
+	} else {
+		this.entries.insertBefore(entry, this.hand)
+	}
+}
+
+// accessed marks the entry as referenced, it doesn't move
+func (this *clockPolicy) accessed(entry *policyEntry) {
+	entry.referenced = true
+}
+
+// removed takes the entry out of the circle, moving the hand on if it was pointing at it
+func (this *clockPolicy) removed(entry *policyEntry, evicted bool) {
+	if this.hand == entry {
+		this.advance()
+	}
+	this.entries.remove(entry)
+	if this.entries.len == 0 {
+		this.hand = nil
+	}
+}
+
+// victim sweeps the hand round until it finds an entry that isn't referenced, clearing the ones it passes
+func (this *clockPolicy) victim() *policyEntry {
+	for this.hand != nil && this.hand.referenced {
+		this.hand.referenced = false
+		this.advance()
+	}
+	return this.hand
+}
+
+// peekVictim returns the entry victim would sweep round to, without clearing any marks or moving the hand. If every
+// entry's referenced then victim clears them all and comes back round to the hand
+func (this *clockPolicy) peekVictim() *policyEntry {
+	entry := this.hand
+	for i := 0; i < this.entries.len; i++ {
+		if !entry.referenced {
+			return entry
+		}
+		if entry.next != nil {
+			entry = entry.next
+		} else {
+			entry = this.entries.head
+		}
+	}
+	return this.hand
+}
+
+// each iterates backwards round the circle from just before the hand, so the entry under the hand is last
+func (this *clockPolicy) each(fn func(entry *policyEntry) bool) {
+	if this.hand == nil {
+		return
+	}
+
+	entry := this.hand
+	for {
+		if entry.prev != nil {
+			entry = entry.prev
+		} else {
+			entry = this.entries.tail
+		}
+		if !fn(entry) || entry == this.hand {
+			return
+		}
+	}
+}
+
+// reset drops all entries
+func (this *clockPolicy) reset() {
+	this.entries = entryList { }
+	this.hand = nil
+}
\ No newline at end of file
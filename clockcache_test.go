@@ -0,0 +1,79 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestClockCache(t *testing.T) {
+	cache := CreateClockCache(MaxSize)
+
+	// Check cache is created correctly
+	if cache == nil {
+		t.Error("Cache is nil")
+	}
+
+	// With nothing referenced items are evicted in the order they were added
+	for i := 0; i < 15; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	assertKeys(t, cache.Keys(), []string{"14", "13", "12", "11", "10", "9", "8", "7", "6", "5"})
+	if cache.Size() != MaxSize {
+		t.Error("Expected size of", MaxSize, "got", cache.Size())
+	}
+}
+
+func TestClockCacheSecondChance(t *testing.T) {
+	cache := CreateClockCache(30)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// "a" has been accessed so the hand should skip it and evict "b"
+	cache.Get("a")
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"d", "a", "c"})
+
+	// The hand cleared "a" on the way past, so it only survives the one sweep
+	cache.Add("e", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"e", "d", "a"})
+	cache.Add("f", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"f", "e", "d"})
+}
+
+func TestClockCacheAllReferenced(t *testing.T) {
+	cache := CreateClockCache(30)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	cache.Get("a")
+	cache.Get("b")
+	cache.Get("c")
+
+	// Hand goes all the way round clearing them and ends up back at the oldest
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"d", "c", "b"})
+}
+
+func TestClockCacheRemove(t *testing.T) {
+	cache := CreateClockCache(30)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Removing the item under the hand should move the hand on
+	cache.Remove("a")
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	cache.Add("e", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"e", "d", "c"})
+
+	// Removing everything and starting again should work
+	cache.Remove("c")
+	cache.Remove("d")
+	cache.Remove("e")
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected empty cache, got", cache.Len(), "items of size", cache.Size())
+	}
+	cache.Add("f", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"f"})
+}
\ No newline at end of file
@@ -0,0 +1,117 @@
+package memcache
+
+import (
+	"context"
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateCopyOnGetCache creates and returns a Cache that hands out copies of the items in the underlying Cache
+//
+// Any item that implements Cloner (e.g. BytesCacheItem) is cloned before its returned by Get, Peek or GetOrAdd, so
+// callers can change what they get back without changing it for everyone else. Other items are returned as they are.
+// Copying costs an allocation on every read so only use this if callers can't be trusted to leave items alone. Items
+// are stored as they're passed to Add, so the caller mustn't change them after adding
+func CreateCopyOnGetCache(underlying Cache) (Cache) {
+	return &copyOnGetCache { Cache: underlying }
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Interface: Cloner
+// ------------------------------------------------------------------------------------------------------------------------
+
+// Cloner is a CacheItem that can make a full copy of itself
+type Cloner interface {
+	CacheItem
+
+	// Clone returns a copy of the item that shares no memory with it
+	Clone() CacheItem
+}
+
+// clone returns a copy of the item if its a Cloner, otherwise the item itself
+func clone(item CacheItem) CacheItem {
+	if cloner, isCloner := item.(Cloner); isCloner {
+		return cloner.Clone()
+	}
+	return item
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: copyOnGetCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// copyOnGetCache wraps a Cache and clones items on the way out. Everything else goes straight to the underlying Cache
+type copyOnGetCache struct {
+	Cache
+}
+
+// Get retrieves a copy of an item from the underlying cache if its present
+func (this *copyOnGetCache) Get(key string) (CacheItem, bool) {
+	item, present := this.Cache.Get(key)
+	if !present {
+		return nil, false
+	}
+	return clone(item), true
+}
+
+// GetOrErr retrieves a copy of an item from the underlying cache, returning ErrNotFound if it isn't present
+func (this *copyOnGetCache) GetOrErr(key string) (CacheItem, error) {
+	return getOrErr(this.Get(key))
+}
+
+// Peek retrieves a copy of an item from the underlying cache without counting as an access
+func (this *copyOnGetCache) Peek(key string) (CacheItem, bool) {
+	item, present := this.Cache.Peek(key)
+	if !present {
+		return nil, false
+	}
+	return clone(item), true
+}
+
+// GetAll returns a snapshot of the underlying cache with every item copied
+func (this *copyOnGetCache) GetAll() map[string]CacheItem {
+	all := this.Cache.GetAll()
+	for key, item := range all {
+		all[key] = clone(item)
+	}
+	return all
+}
+
+// GetWithAge retrieves a copy of an item from the underlying cache, along with how long its been since it was added
+func (this *copyOnGetCache) GetWithAge(key string) (CacheItem, time.Duration, bool) {
+	item, age, present := this.Cache.GetWithAge(key)
+	if !present {
+		return nil, 0, false
+	}
+	return clone(item), age, true
+}
+
+// GetOrAdd retrieves a copy of an item from the underlying cache, computing and adding it if its missing
+func (this *copyOnGetCache) GetOrAdd(key string, compute func() (CacheItem, error)) (CacheItem, error) {
+	item, err := this.Cache.GetOrAdd(key, compute)
+	if err != nil {
+		return nil, err
+	}
+	return clone(item), nil
+}
+
+// GetOrAddContext retrieves a copy of an item from the underlying cache like GetOrAdd, giving up if ctx is done first
+func (this *copyOnGetCache) GetOrAddContext(ctx context.Context, key string, compute func(ctx context.Context) (CacheItem, error)) (CacheItem, error) {
+	item, err := this.Cache.GetOrAddContext(ctx, key, compute)
+	if err != nil {
+		return nil, err
+	}
+	return clone(item), nil
+}
+
+// GetStaleWhileRevalidate retrieves a copy of an item from the underlying cache like GetStaleWhileRevalidate
+func (this *copyOnGetCache) GetStaleWhileRevalidate(key string, ttl, staleWindow time.Duration, refresh func() (CacheItem, error)) (CacheItem, bool) {
+	item, present := this.Cache.GetStaleWhileRevalidate(key, ttl, staleWindow, refresh)
+	if !present {
+		return nil, false
+	}
+	return clone(item), true
+}
\ No newline at end of file
@@ -0,0 +1,56 @@
+package memcache
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCopyOnGetCache(t *testing.T) {
+	underlying := CreateLRUCache(MaxSize)
+	cache := CreateCopyOnGetCache(underlying)
+	cache.Add("bytes", NewBytes([]byte("original")))
+
+	// Changing what Get returns, even appending in place, shouldn't affect the cached copy
+	item, _ := cache.Get("bytes")
+	data := item.(*BytesCacheItem).Data
+	data[0] = 'X'
+	_ = append(data[:1], "ppended"...)
+	if cached, _ := underlying.Peek("bytes"); !bytes.Equal(cached.(*BytesCacheItem).Data, []byte("original")) {
+		t.Error("Expected the cached bytes to be unchanged, got", string(cached.(*BytesCacheItem).Data))
+	}
+
+	// Peek, GetOrAdd and GetAll should copy too
+	peeked, _ := cache.Peek("bytes")
+	got, _ := cache.GetOrAdd("bytes", func() (CacheItem, error) { return nil, nil })
+	all := cache.GetAll()
+	cached, _ := underlying.Peek("bytes")
+	for _, copied := range []CacheItem{peeked, got, all["bytes"]} {
+		if copied == cached || &copied.(*BytesCacheItem).Data[0] == &cached.(*BytesCacheItem).Data[0] {
+			t.Error("Expected a copy that doesn't share memory with the cached item")
+		}
+	}
+
+	// Items that can't be cloned come back as they are
+	dummy := &DummyCacheItem{DummySize: 10}
+	cache.Add("dummy", dummy)
+	if got, present := cache.Get("dummy"); !present || got != dummy {
+		t.Error("Expected the dummy item itself, got", got, present)
+	}
+
+	// Everything else goes to the underlying cache
+	if cache.Len() != 2 || cache.Size() != underlying.Size() {
+		t.Error("Expected the underlying cache's length and size, got", cache.Len(), cache.Size())
+	}
+}
+
+func TestWithoutCopyOnGet(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+	cache.Add("bytes", NewBytes([]byte("original")))
+
+	// Without copy on get, changes are seen by everyone
+	item, _ := cache.Get("bytes")
+	item.(*BytesCacheItem).Data[0] = 'X'
+	if cached, _ := cache.Get("bytes"); string(cached.(*BytesCacheItem).Data) != "Xriginal" {
+		t.Error("Expected the change to be shared, got", string(cached.(*BytesCacheItem).Data))
+	}
+}
\ No newline at end of file
@@ -0,0 +1,129 @@
+package memcache
+
+import (
+	"sync"
+)
+
+// EventType is what happened in a cache to produce a CacheEvent
+type EventType int
+
+const (
+	// EventAdd means an item was added, or replaced the item under its key
+	EventAdd EventType = iota
+
+	// EventGet means Get was called for the key, whether or not the item was found
+	EventGet
+
+	// EventEvict means an item was evicted to make room, or because it expired
+	EventEvict
+
+	// EventRemove means an item was removed by the caller, with Remove or similar
+	EventRemove
+)
+
+// CacheEvent describes something that happened to a key in a cache, as sent on the channel returned by Events
+type CacheEvent struct {
+
+	// Type is what happened
+	Type EventType
+
+	// Key is the key it happened to
+	Key string
+
+	// Hit is true if a Get found its item, its only set for EventGet
+	Hit bool
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: eventStream (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// eventStream sends CacheEvents on a buffered channel without ever blocking the cache
+//
+// If the buffer's full then the oldest event is dropped to make room, so a slow (or absent) consumer only misses events.
+// Its methods are safe to call on a nil eventStream, they do nothing
+type eventStream struct {
+
+	// events is the channel events are sent on
+	events chan CacheEvent
+
+	// mutex makes sure only one goroutine is sending at a time, so dropping the oldest always makes room
+	mutex sync.Mutex
+
+	// closed is set once events has been closed, nothing else is sent after that
+	closed bool
+}
+
+// newEventStream creates an eventStream that buffers up to bufferSize events, at least 1
+func newEventStream(bufferSize int) (*eventStream) {
+	return &eventStream { events: make(chan CacheEvent, max(bufferSize, 1)) }
+}
+
+// emit sends an event, dropping the oldest buffered event if there isn't room for it
+func (this *eventStream) emit(event CacheEvent) {
+	if this == nil {
+		return
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.closed {
+		return
+	}
+	for {
+		select {
+		case this.events <- event:
+			return
+		default:
+		}
+
+		// Full, the consumer might take one in the meantime so this doesn't wait either
+		select {
+		case <-this.events:
+		default:
+		}
+	}
+}
+
+// add sends an EventAdd for the key
+func (this *eventStream) add(key string) {
+	this.emit(CacheEvent { Type: EventAdd, Key: key })
+}
+
+// get sends an EventGet for the key, hit if it was found
+func (this *eventStream) get(key string, hit bool) {
+	this.emit(CacheEvent { Type: EventGet, Key: key, Hit: hit })
+}
+
+// removed sends an EventRemove if the item was removed by the caller, otherwise an EventEvict
+func (this *eventStream) removed(key string, reason EvictReason) {
+	if reason == ReasonManual {
+		this.emit(CacheEvent { Type: EventRemove, Key: key })
+	} else {
+		this.emit(CacheEvent { Type: EventEvict, Key: key })
+	}
+}
+
+// channel returns the channel events are sent on, nil if there's no stream
+func (this *eventStream) channel() <-chan CacheEvent {
+	if this == nil {
+		return nil
+	}
+	return this.events
+}
+
+// close stops any more events being sent and closes the channel, its safe to call more than once
+func (this *eventStream) close() {
+	if this == nil {
+		return
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if !this.closed {
+		this.closed = true
+		close(this.events)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,76 @@
+package memcache
+
+import (
+	"math"
+	"time"
+)
+
+// never is the expiry time of entries that don't expire, its far enough off that they always sort last
+var never = time.Unix(math.MaxInt64 >> 1, 0)
+
+// expiry returns when an entry added at added expires, never if ttl is 0 or less
+func expiry(added time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return never
+	}
+	return added.Add(ttl)
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Interface: heapEntry (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// heapEntry is an entry that can be kept in an expiryHeap
+type heapEntry interface {
+
+	// expiresAt returns when the entry expires
+	expiresAt() time.Time
+
+	// setHeapIndex is called with the entry's position in the heap every time it moves, so it can be passed to heap.Fix
+	// or heap.Remove
+	setHeapIndex(index int)
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: expiryHeap (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// expiryHeap is a min-heap of entries ordered by when they expire, so the next to expire is always at the top
+//
+// Sweeps only have to look at the top of the heap to find expired entries rather than going through all of them. It
+// implements heap.Interface, use the container/heap functions rather than calling its methods directly
+type expiryHeap[E heapEntry] []E
+
+// Len returns the number of entries in the heap
+func (this expiryHeap[E]) Len() int {
+	return len(this)
+}
+
+// Less orders entries soonest to expire first
+func (this expiryHeap[E]) Less(i, j int) bool {
+	return this[i].expiresAt().Before(this[j].expiresAt())
+}
+
+// Swap swaps two entries, keeping their indexes up to date
+func (this expiryHeap[E]) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+	this[i].setHeapIndex(i)
+	this[j].setHeapIndex(j)
+}
+
+// Push adds an entry to the end of the heap
+func (this *expiryHeap[E]) Push(x any) {
+	entry := x.(E)
+	entry.setHeapIndex(len(*this))
+	*this = append(*this, entry)
+}
+
+// Pop removes the entry from the end of the heap
+func (this *expiryHeap[E]) Pop() any {
+	old := *this
+	entry := old[len(old) - 1]
+	var zero E
+	old[len(old) - 1] = zero
+	*this = old[:len(old) - 1]
+	return entry
+}
\ No newline at end of file
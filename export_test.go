@@ -0,0 +1,85 @@
+package memcache
+
+import (
+	"sync"
+	"time"
+)
+
+// CheckInvariants returns an error if an LRU Cache's hash, linked-list and heap don't agree. Sharded caches check each
+// of their shards, other caches have nothing to check
+func CheckInvariants(cache Cache) error {
+	switch cache := cache.(type) {
+	case *lruCache:
+		return cache.checkInvariants()
+	case *shardedCache:
+		for _, shard := range cache.shards {
+			if err := CheckInvariants(shard); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetClock makes a cache get the time from the clock passed in, so tests can control expiry, ages and ramps. Sharded
+// and loading caches set it on the caches they wrap too, caches that don't use the time are left alone
+//
+// A ramping LRU Cache started its ramp at the real time, so the clock should start from around then
+func SetClock(cache Cache, now clock) {
+	switch cache := cache.(type) {
+	case *lruCache:
+		cache.mutex.Lock()
+		cache.clock = now
+		cache.mutex.Unlock()
+	case *timedCache:
+		cache.mutex.Lock()
+		cache.clock = now
+		cache.mutex.Unlock()
+	case *policyCache:
+		cache.mutex.Lock()
+		cache.clock = now
+		cache.mutex.Unlock()
+	case *priorityCache:
+		SetClock(cache.policyCache, now)
+	case *shardedCache:
+		for _, shard := range cache.shards {
+			SetClock(shard, now)
+		}
+	case *loadingCache:
+		cache.mutex.Lock()
+		cache.clock = now
+		cache.mutex.Unlock()
+		SetClock(cache.Cache, now)
+	}
+}
+
+// FakeClock is a clock that only moves when its told to
+type FakeClock struct {
+
+	// mutex makes it safe to use from the janitor and the test at the same time
+	mutex sync.Mutex
+
+	// now is the time it says it is
+	now time.Time
+}
+
+// NewFakeClock creates a FakeClock stopped at the real time
+func NewFakeClock() (*FakeClock) {
+	return &FakeClock{now: time.Now()}
+}
+
+// Now returns the time the clock's stopped at
+func (this *FakeClock) Now() time.Time {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return this.now
+}
+
+// Advance moves the clock on by d
+func (this *FakeClock) Advance(d time.Duration) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.now = this.now.Add(d)
+}
\ No newline at end of file
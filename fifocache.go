@@ -0,0 +1,20 @@
+package memcache
+
+import (
+	"sync"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateFIFOCache creates and returns a 'First In First Out' implementation of Cache
+//
+// FIFO removes items in the order they were added, accessing an item with Get doesn't save it from being removed. Adding
+// an item that's already present counts as adding it again, so it moves to the back of the queue
+//
+// It shares the size accounting of the LRU implementation, if the current size > max size then the oldest items are
+// removed until it falls under max size
+func CreateFIFOCache(maxsize int) (Cache) {
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { }, insertionOrder: true }
+}
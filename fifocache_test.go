@@ -0,0 +1,45 @@
+package memcache
+
+import (
+	"testing"
+	"strconv"
+)
+
+func TestFIFOCache(t *testing.T) {
+	cache := CreateFIFOCache(MaxSize)
+
+	// Check cache is created correctly
+	if cache == nil {
+		t.Error("Cache is nil")
+	}
+
+	// Add 10 cacheitems of size 10, all should remain present
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Access 0, unlike LRU this shouldn't move it to the front of the queue
+	if _, present := cache.Get("0"); !present {
+		t.Error("0 should be present")
+	}
+
+	// Go over max size, 0 was added first so should still be knocked off the queue
+	cache.Add("10", &DummyCacheItem{DummySize: 10})
+	if _, present := cache.Get("0"); present {
+		t.Error("0 should have been removed from the end of the queue even though it was accessed")
+	}
+
+	// Access 1 then add another, 1 should be knocked off next
+	cache.Get("1")
+	cache.Add("11", &DummyCacheItem{DummySize: 10})
+
+	if _, present := cache.Get("1"); present {
+		t.Error("1 should have been removed from the queue")
+	}
+	if _, present := cache.Get("2"); !present {
+		t.Error("2 should be present in the queue")
+	}
+
+	// Order should be insertion order regardless of the Gets above
+	assertKeys(t, cache.Keys(), []string{"11", "10", "9", "8", "7", "6", "5", "4", "3", "2"})
+}
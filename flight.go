@@ -0,0 +1,191 @@
+package memcache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: flightGroup (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// flightGroup makes sure only one call for a key is running at a time, callers for the same key wait and share the result
+//
+// The zero value is ready to use
+type flightGroup struct {
+
+	// calls is the map of key to the call currently running for it
+	calls map[string]*flightCall
+
+	// mutex is used to synchronize calls as it can be accessed by multiple goroutines
+	mutex sync.Mutex
+}
+
+// flightCall is a single call running in a flightGroup
+type flightCall struct {
+
+	// done is closed once the call has finished and item / err are set
+	done chan struct{}
+
+	// item is the item the call returned
+	item CacheItem
+
+	// err is the error the call returned
+	err error
+
+	// cancelled is true if the ctx the call was made with was done by the time it returned, so item / err shouldn't be
+	// shared with callers that were waiting
+	cancelled bool
+}
+
+// do calls fn and returns its result, unless a call for the key is already running in which case it waits for that
+// call to finish and returns its result instead
+func (this *flightGroup) do(key string, fn func() (CacheItem, error)) (CacheItem, error) {
+	return this.doContext(context.Background(), key, fn)
+}
+
+// doContext works like do, but stops waiting on another call if ctx is done first
+//
+// If the call being waited on was itself given up on because its ctx was done then its result isn't ours, so we try
+// again, running fn ourselves if nobody else has started a call in the meantime
+func (this *flightGroup) doContext(ctx context.Context, key string, fn func() (CacheItem, error)) (CacheItem, error) {
+	for {
+		this.mutex.Lock()
+		call, running := this.calls[key]
+		if !running {
+			break
+		}
+		this.mutex.Unlock()
+
+		select {
+		case <-call.done:
+			if !call.cancelled {
+				return call.item, call.err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if this.calls == nil {
+		this.calls = make(map[string]*flightCall)
+	}
+	call := &flightCall { done: make(chan struct{}) }
+	this.calls[key] = call
+	this.mutex.Unlock()
+
+	// Make sure waiters are released and the key is freed up even if fn panics
+	defer this.finish(key, call)
+
+	call.item, call.err = fn()
+	call.cancelled = ctx.Err() != nil
+	return call.item, call.err
+}
+
+// start calls fn on a new goroutine and returns true, unless a call for the key is already running in which case it
+// does nothing and returns false. Callers using do for the key wait for fn like any other call
+func (this *flightGroup) start(key string, fn func() (CacheItem, error)) bool {
+	this.mutex.Lock()
+	if _, running := this.calls[key]; running {
+		this.mutex.Unlock()
+		return false
+	}
+
+	if this.calls == nil {
+		this.calls = make(map[string]*flightCall)
+	}
+	call := &flightCall { done: make(chan struct{}) }
+	this.calls[key] = call
+	this.mutex.Unlock()
+
+	go func() {
+		defer this.finish(key, call)
+		call.item, call.err = fn()
+	}()
+	return true
+}
+
+// finish frees up the key and releases anyone waiting on the call
+func (this *flightGroup) finish(key string, call *flightCall) {
+	this.mutex.Lock()
+	delete(this.calls, key)
+	this.mutex.Unlock()
+	close(call.done)
+}
+
+// getOrAdd implements Cache.GetOrAdd for caches that have a flightGroup
+func getOrAdd(cache Cache, flights *flightGroup, key string, compute func() (CacheItem, error)) (CacheItem, error) {
+	return getOrAddContext(context.Background(), cache, flights, key, func(ctx context.Context) (CacheItem, error) {
+		return compute()
+	})
+}
+
+// getOrAddContext implements Cache.GetOrAddContext for caches that have a flightGroup
+//
+// The cache isn't locked while compute is running, instead the flightGroup stops compute being called more than once for
+// the same key. Once we have the flight we check again in case another caller added the item while we were waiting
+func getOrAddContext(ctx context.Context, cache Cache, flights *flightGroup, key string, compute func(ctx context.Context) (CacheItem, error)) (CacheItem, error) {
+	if item, present := cache.Get(key); present {
+		return item, nil
+	}
+
+	return flights.doContext(ctx, key, func() (CacheItem, error) {
+		if item, present := cache.Peek(key); present {
+			return item, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		item, err := compute(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// Whatever compute came back with, if ctx is done then it might not be complete so don't keep it
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := cache.Add(key, item); err != nil {
+			return nil, err
+		}
+		return item, nil
+	})
+}
+
+// getStaleWhileRevalidate implements Cache.GetStaleWhileRevalidate for caches that have a flightGroup
+//
+// Refreshes go through the flightGroup, so a background refresh and callers blocked on a hard miss share the same call
+// to refresh
+func getStaleWhileRevalidate(cache Cache, flights *flightGroup, key string, ttl, staleWindow time.Duration, 
+	refresh func() (CacheItem, error)) (CacheItem, bool) {
+
+	refreshAndAdd := func() (CacheItem, error) {
+		item, err := refresh()
+		if err != nil {
+			return nil, err
+		}
+		if err := cache.Add(key, item); err != nil {
+			return nil, err
+		}
+		return item, nil
+	}
+
+	item, age, present := cache.GetWithAge(key)
+	if present && age <= ttl {
+		return item, true
+	}
+
+	// Stale but still usable, serve it and refresh in the background. If that fails the stale item is left alone
+	if present && age <= ttl + staleWindow {
+		flights.start(key, refreshAndAdd)
+		return item, true
+	}
+
+	item, err := flights.do(key, refreshAndAdd)
+	if err != nil {
+		return nil, false
+	}
+	return item, true
+}
\ No newline at end of file
@@ -0,0 +1,252 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrAdd(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"arc": CreateARCCache(MaxSize),
+		"2q": CreateTwoQueueCache(MaxSize),
+		"clock": CreateClockCache(MaxSize),
+		"random": CreateRandomCache(MaxSize),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+	} {
+		// Missing so compute should be called and its item added
+		item := &DummyCacheItem{DummySize: 10}
+		got, err := cache.GetOrAdd("a", func() (CacheItem, error) { return item, nil })
+		if err != nil || got != item {
+			t.Error(name, "expected computed item, got", got, err)
+		}
+		if !cache.Contains("a") {
+			t.Error(name, "a should have been added")
+		}
+
+		// Present so compute shouldn't be called
+		got, err = cache.GetOrAdd("a", func() (CacheItem, error) {
+			t.Error(name, "compute shouldn't be called for a present item")
+			return nil, nil
+		})
+		if err != nil || got != item {
+			t.Error(name, "expected existing item, got", got, err)
+		}
+
+		// Errors should be returned and nothing added
+		computeErr := errors.New("compute failed")
+		if _, err = cache.GetOrAdd("b", func() (CacheItem, error) { return nil, computeErr }); err != computeErr {
+			t.Error(name, "expected compute error, got", err)
+		}
+		if cache.Contains("b") {
+			t.Error(name, "b shouldn't have been added")
+		}
+	}
+}
+
+func TestGetOrAddConcurrent(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+
+	var calls atomic.Int32
+	item := &DummyCacheItem{DummySize: 10}
+	compute := func() (CacheItem, error) {
+		calls.Add(1)
+
+		// Give the other goroutines time to pile up behind us
+		time.Sleep(10 * time.Millisecond)
+		return item, nil
+	}
+
+	// Lots of goroutines all missing the same key at once
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := cache.GetOrAdd("a", compute); err != nil || got != item {
+				t.Error("Expected computed item, got", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Error("Expected compute to be called once, got", calls.Load())
+	}
+}
+
+func TestGetOrAddContextCancelled(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"arc": CreateARCCache(MaxSize),
+		"2q": CreateTwoQueueCache(MaxSize),
+		"clock": CreateClockCache(MaxSize),
+		"random": CreateRandomCache(MaxSize),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+	} {
+		// Cancel while compute is running, it hands back an item anyway
+		ctx, cancel := context.WithCancel(context.Background())
+		_, err := cache.GetOrAddContext(ctx, "a", func(ctx context.Context) (CacheItem, error) {
+			cancel()
+			return &DummyCacheItem{DummySize: 10}, nil
+		})
+		if err != context.Canceled {
+			t.Error(name, "expected context.Canceled, got", err)
+		}
+		if cache.Contains("a") {
+			t.Error(name, "a shouldn't have been added after the context was cancelled")
+		}
+
+		// Key shouldn't be stuck, the next call should compute and add as normal
+		item := &DummyCacheItem{DummySize: 10}
+		got, err := cache.GetOrAddContext(context.Background(), "a", func(ctx context.Context) (CacheItem, error) {
+			return item, nil
+		})
+		if err != nil || got != item || !cache.Contains("a") {
+			t.Error(name, "expected computed item to be added, got", got, err)
+		}
+
+		// Already done before we start, compute shouldn't be called
+		_, err = cache.GetOrAddContext(ctx, "b", func(ctx context.Context) (CacheItem, error) {
+			t.Error(name, "compute shouldn't be called")
+			return nil, nil
+		})
+		if err != context.Canceled {
+			t.Error(name, "expected context.Canceled, got", err)
+		}
+	}
+}
+
+func TestGetOrAddContextWaiters(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+
+	// First caller's compute blocks until its context is cancelled
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error)
+	go func() {
+		_, err := cache.GetOrAddContext(ctx, "a", func(ctx context.Context) (CacheItem, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		firstDone <- err
+	}()
+	<-started
+
+	// A waiter whose own context times out should stop waiting
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 10 * time.Millisecond)
+	defer cancelTimeout()
+	if _, err := cache.GetOrAddContext(timeout, "a", func(ctx context.Context) (CacheItem, error) {
+		t.Error("compute shouldn't be called while the first is running")
+		return nil, nil
+	}); err != context.DeadlineExceeded {
+		t.Error("Expected context.DeadlineExceeded, got", err)
+	}
+
+	// A waiter with a live context shouldn't get the first caller's cancellation, it should compute for itself
+	item := &DummyCacheItem{DummySize: 10}
+	waiterDone := make(chan CacheItem)
+	go func() {
+		got, err := cache.GetOrAddContext(context.Background(), "a", func(ctx context.Context) (CacheItem, error) {
+			return item, nil
+		})
+		if err != nil {
+			t.Error("Unexpected error:", err)
+		}
+		waiterDone <- got
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-firstDone; err != context.Canceled {
+		t.Error("Expected context.Canceled, got", err)
+	}
+	if got := <-waiterDone; got != item {
+		t.Error("Expected the waiter to compute its own item, got", got)
+	}
+}
+
+func TestGetStaleWhileRevalidate(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+	} {
+		var refreshes atomic.Int32
+		refreshed := make(chan struct{}, 10)
+		fresh := &DummyCacheItem{DummySize: 10}
+		refresh := func() (CacheItem, error) {
+			refreshes.Add(1)
+			time.Sleep(10 * time.Millisecond)
+			defer func() { refreshed <- struct{}{} }()
+			return fresh, nil
+		}
+
+		// Hard miss, should block on refresh and add its item
+		if got, present := cache.GetStaleWhileRevalidate("a", TestTTL, TestTTL, refresh); !present || got != fresh {
+			t.Error(name, "expected the refreshed item, got", got, present)
+		}
+		<-refreshed
+
+		// Fresh, returned without refreshing
+		if got, present := cache.GetStaleWhileRevalidate("a", TestTTL, TestTTL, refresh); !present || got != fresh {
+			t.Error(name, "expected the cached item, got", got, present)
+		}
+		if refreshes.Load() != 1 {
+			t.Error(name, "expected 1 refresh, got", refreshes.Load())
+		}
+
+		// Stale within the window, the old item should come straight back with one refresh in the background
+		stale := &DummyCacheItem{DummySize: 10}
+		cache.Add("b", stale)
+		time.Sleep(TestTTL + 10 * time.Millisecond)
+		for i := 0; i < 5; i++ {
+			if got, present := cache.GetStaleWhileRevalidate("b", TestTTL, TestTTL, refresh); !present || got != stale {
+				t.Error(name, "expected the stale item, got", got, present)
+			}
+		}
+		<-refreshed
+		if refreshes.Load() != 2 {
+			t.Error(name, "expected only one background refresh, got", refreshes.Load() - 1)
+		}
+		if got, _ := cache.Peek("b"); got != fresh {
+			t.Error(name, "expected the background refresh to replace the stale item, got", got)
+		}
+
+		// Beyond the stale window, should block again
+		cache.Add("c", stale)
+		time.Sleep(TestTTL + 10 * time.Millisecond)
+		if got, present := cache.GetStaleWhileRevalidate("c", TestTTL, 0, refresh); !present || got != fresh {
+			t.Error(name, "expected the refreshed item, got", got, present)
+		}
+		<-refreshed
+	}
+}
+
+func TestGetStaleWhileRevalidateError(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+	refreshErr := errors.New("refresh failed")
+	failing := func() (CacheItem, error) { return nil, refreshErr }
+
+	// Nothing to serve and the refresh failed
+	if got, present := cache.GetStaleWhileRevalidate("a", TestTTL, TestTTL, failing); got != nil || present {
+		t.Error("Expected a miss, got", got, present)
+	}
+
+	// A failed background refresh leaves the stale item in place
+	stale := &DummyCacheItem{DummySize: 10}
+	cache.Add("b", stale)
+	time.Sleep(TestTTL + 10 * time.Millisecond)
+	cache.GetStaleWhileRevalidate("b", TestTTL, TestTTL, failing)
+	time.Sleep(10 * time.Millisecond)
+	if got, present := cache.GetStaleWhileRevalidate("b", TestTTL, TestTTL, failing); !present || got != stale {
+		t.Error("Expected the stale item to still be served, got", got, present)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,169 @@
+package memcache
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// NewBytes creates and returns a CacheItem holding a byte slice, its size is the length of the slice
+//
+// The slice isn't copied, so changes to it will be seen by anything that gets it from the cache
+func NewBytes(b []byte) (CacheItem) {
+	return &BytesCacheItem { Data: b }
+}
+
+// NewString creates and returns a CacheItem holding a string, its size is the length of the string in bytes
+func NewString(s string) (CacheItem) {
+	return StringCacheItem(s)
+}
+
+// NewInt creates and returns a CacheItem holding an int64, e.g. a counter to be used with Increment
+func NewInt(n int64) (CacheItem) {
+	return IntCacheItem(n)
+}
+
+// NewJSON creates and returns a JSONCacheItem holding v encoded as JSON, its size is the length of the JSON
+//
+// v is encoded straight away, so changing it afterwards doesn't change the item. If v can't be encoded the error from
+// json.Marshal is returned
+func NewJSON(v interface{}) (*JSONCacheItem, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONCacheItem { Raw: raw }, nil
+}
+
+// NewReaderAt creates and returns a ReaderAtCacheItem that reads its data from r, e.g. a memory mapped file
+//
+// size is the number of bytes that can be read and what the item counts as towards the size of the cache. r isn't read
+// until ReadAt is called, so a large item doesn't have to be loaded into one slice
+func NewReaderAt(r io.ReaderAt, size int) (ReaderAtCacheItem) {
+	return &readerAtCacheItem { reader: r, size: size }
+}
+
+// GetBytes retrieves a BytesCacheItem from the cache and returns its Data
+//
+// If the item is missing, or its not a BytesCacheItem, then nil, false is returned. It counts as an access like Get
+func GetBytes(cache Cache, key string) ([]byte, bool) {
+	item, present := cache.Get(key)
+	if !present {
+		return nil, false
+	}
+	if bytesItem, isBytes := item.(*BytesCacheItem); isBytes {
+		return bytesItem.Data, true
+	}
+	return nil, false
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: BytesCacheItem
+// ------------------------------------------------------------------------------------------------------------------------
+
+// BytesCacheItem is a CacheItem for plain byte slices
+//
+// Its a struct rather than a []byte so it can be compared, caches compare items to see if the same one is being re-added
+type BytesCacheItem struct {
+
+	// Data is the cached bytes
+	Data []byte
+}
+
+// Size returns the length of Data
+func (this *BytesCacheItem) Size() int {
+	return len(this.Data)
+}
+
+// ReadAt reads from Data starting at off, so a BytesCacheItem can be used as a ReaderAtCacheItem
+func (this *BytesCacheItem) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(this.Data).ReadAt(p, off)
+}
+
+// Clone returns a new BytesCacheItem with its own copy of Data, changing one doesn't affect the other
+func (this *BytesCacheItem) Clone() CacheItem {
+	return &BytesCacheItem { Data: bytes.Clone(this.Data) }
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: JSONCacheItem
+// ------------------------------------------------------------------------------------------------------------------------
+
+// JSONCacheItem is a CacheItem for structured values, stored as JSON so each Get decodes its own copy
+type JSONCacheItem struct {
+
+	// Raw is the encoded JSON
+	Raw []byte
+}
+
+// Size returns the length of Raw
+func (this *JSONCacheItem) Size() int {
+	return len(this.Raw)
+}
+
+// Decode decodes Raw into dst, which should be a pointer as with json.Unmarshal
+func (this *JSONCacheItem) Decode(dst interface{}) error {
+	return json.Unmarshal(this.Raw, dst)
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Type: StringCacheItem
+// ------------------------------------------------------------------------------------------------------------------------
+
+// StringCacheItem is a CacheItem for strings, e.g. rendered HTML or JSON
+type StringCacheItem string
+
+// Size returns the length of the string in bytes (not runes), as that's what it takes up in memory
+func (this StringCacheItem) Size() int {
+	return len(this)
+}
+
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Type: IntCacheItem
+// ------------------------------------------------------------------------------------------------------------------------
+
+// IntCacheItem is a CacheItem for numbers. Its what CounterCache.Increment and Decrement work on
+type IntCacheItem int64
+
+// Size returns 8, the size of an int64
+func (this IntCacheItem) Size() int {
+	return 8
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Interface: ReaderAtCacheItem
+// ------------------------------------------------------------------------------------------------------------------------
+
+// ReaderAtCacheItem is a CacheItem whose data is read in ranges with ReadAt, rather than being returned in one go
+//
+// Size is the number of bytes that can be read. To serve HTTP Range requests wrap it in an io.SectionReader, which can be
+// passed to http.ServeContent
+type ReaderAtCacheItem interface {
+	CacheItem
+	io.ReaderAt
+}
+
+// readerAtCacheItem is the ReaderAtCacheItem returned by NewReaderAt
+type readerAtCacheItem struct {
+
+	// reader is where the data is read from
+	reader io.ReaderAt
+
+	// size is how many bytes can be read
+	size int
+}
+
+// Size returns the number of bytes that can be read
+func (this *readerAtCacheItem) Size() int {
+	return this.size
+}
+
+// ReadAt reads from the underlying reader, stopping at size even if it has more
+func (this *readerAtCacheItem) ReadAt(p []byte, off int64) (int, error) {
+	return io.NewSectionReader(this.reader, 0, int64(this.size)).ReadAt(p, off)
+}
\ No newline at end of file
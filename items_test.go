@@ -0,0 +1,154 @@
+package memcache
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBytesCacheItem(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+
+	data := []byte("some bytes")
+	cache.Add("bytes", NewBytes(data))
+
+	item, present := cache.Get("bytes")
+	if !present {
+		t.Fatal("bytes should be present")
+	}
+	if item.Size() != len(data) || cache.Size() != len(data) {
+		t.Error("Expected size", len(data), "got", item.Size(), cache.Size())
+	}
+	if !bytes.Equal(item.(*BytesCacheItem).Data, data) {
+		t.Error("Expected", data, "got", item.(*BytesCacheItem).Data)
+	}
+
+	// Re-adding the same item shouldn't blow up comparing it
+	if err := cache.Add("bytes", item); err != nil {
+		t.Error("Unexpected error re-adding:", err)
+	}
+}
+
+func TestStringCacheItem(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+
+	// 5 runes but "é" and "ü" are 2 bytes each
+	text := "héllü"
+	cache.Add("string", NewString(text))
+
+	item, present := cache.Get("string")
+	if !present {
+		t.Fatal("string should be present")
+	}
+	if item.Size() != 7 || cache.Size() != 7 {
+		t.Error("Expected size to be 7 bytes, got", item.Size(), cache.Size())
+	}
+	if string(item.(StringCacheItem)) != text {
+		t.Error("Expected", text, "got", item)
+	}
+}
+
+
+func TestIntCacheItem(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+	cache.Add("int", NewInt(42))
+
+	item, present := cache.Get("int")
+	if !present || item.(IntCacheItem) != 42 || cache.Size() != 8 {
+		t.Error("Expected 42 with size 8, got", item, present, cache.Size())
+	}
+}
+
+func TestGetBytes(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+	cache.Add("bytes", NewBytes([]byte("data")))
+	cache.Add("string", NewString("data"))
+
+	if data, present := GetBytes(cache, "bytes"); !present || string(data) != "data" {
+		t.Error("Expected the cached bytes, got", data, present)
+	}
+	if data, present := GetBytes(cache, "missing"); present || data != nil {
+		t.Error("Expected a miss, got", data, present)
+	}
+
+	// Something other than bytes should be a miss rather than a panic
+	if data, present := GetBytes(cache, "string"); present || data != nil {
+		t.Error("Expected a miss for a string item, got", data, present)
+	}
+}
+
+func TestReaderAtCacheItem(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+
+	// Only the first 10 bytes are part of the item, and that's what it counts as
+	data := strings.NewReader("0123456789 past the end")
+	cache.Add("file", NewReaderAt(data, 10))
+	cache.Add("bytes", NewBytes([]byte("0123456789")))
+	if cache.Size() != 20 {
+		t.Error("Expected size 20, got", cache.Size())
+	}
+
+	for _, key := range []string{"file", "bytes"} {
+		item, _ := cache.Get(key)
+		readerAt := item.(ReaderAtCacheItem)
+
+		// Serve a range without reading the whole thing
+		content := io.NewSectionReader(readerAt, 0, int64(readerAt.Size()))
+		request := httptest.NewRequest("GET", "/" + key, nil)
+		request.Header.Set("Range", "bytes=3-6")
+		response := httptest.NewRecorder()
+		http.ServeContent(response, request, key, time.Time{}, content)
+
+		if response.Code != http.StatusPartialContent || response.Body.String() != "3456" {
+			t.Error(key, "expected 206 with 3456, got", response.Code, response.Body.String())
+		}
+		if contentRange := response.Header().Get("Content-Range"); contentRange != "bytes 3-6/10" {
+			t.Error(key, "expected Content-Range bytes 3-6/10, got", contentRange)
+		}
+	}
+}
+
+func TestJSONCacheItem(t *testing.T) {
+	type user struct {
+		Name string
+		Age int
+		Tags []string
+	}
+	cache := CreateLRUCache(MaxSize)
+
+	original := user{Name: "sam", Age: 30, Tags: []string{"a", "b"}}
+	item, err := NewJSON(original)
+	if err != nil {
+		t.Fatal("Unexpected error encoding:", err)
+	}
+	cache.Add("user", item)
+	if cache.Size() != len(item.Raw) {
+		t.Error("Expected size", len(item.Raw), "got", cache.Size())
+	}
+
+	got, _ := cache.Get("user")
+	var decoded user
+	if err := got.(*JSONCacheItem).Decode(&decoded); err != nil {
+		t.Fatal("Unexpected error decoding:", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Error("Expected", original, "got", decoded)
+	}
+
+	// Changing the original afterwards shouldn't change what's cached
+	original.Tags[0] = "changed"
+	decoded = user{}
+	got.(*JSONCacheItem).Decode(&decoded)
+	if decoded.Tags[0] != "a" {
+		t.Error("Expected the cached value to be unchanged, got", decoded)
+	}
+
+	if _, err := NewJSON(make(chan int)); err == nil {
+		t.Error("Expected an error encoding a channel")
+	}
+}
\ No newline at end of file
@@ -0,0 +1,127 @@
+package memcache
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// NewKeyedCache creates and returns a GenericCache that takes keys of type K, stored in underlying under keyFn(key)
+//
+// keyFn is used by every method so a key always ends up under the same string. It must be deterministic, and it must
+// never give two different keys the same string or they'll overwrite each other. For struct keys that means every
+// field has to be in the string, with a separator that can't appear in the fields themselves (or the fields quoted).
+// strconv.Itoa is fine for int keys
+func NewKeyedCache[K comparable](underlying Cache, keyFn func(K) string) (GenericCache[K]) {
+	return &keyedCache[K] { underlying: underlying, keyFn: keyFn }
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Interface: GenericCache
+// ------------------------------------------------------------------------------------------------------------------------
+
+// GenericCache is a cache with keys of type K rather than strings
+type GenericCache[K comparable] interface {
+
+	// Add adds a CacheItem to the cache, it can be retrieved using Get and passing in the same key
+	Add(key K, val CacheItem) error
+
+	// Get retrieves an item from the cache if its present
+	//
+	// If item is present then the item, true is returned. Otherwise, nil, false
+	Get(key K) (CacheItem, bool)
+
+	// Remove removes an item from the cache
+	Remove(key K)
+
+	// Peek retrieves an item from the cache if its present, without counting as an access
+	Peek(key K) (CacheItem, bool)
+
+	// Contains returns true if the item is present in the cache, without counting as an access
+	Contains(key K) bool
+
+	// GetOrAdd retrieves an item from the cache if its present, otherwise it calls compute and adds the item it returns
+	GetOrAdd(key K, compute func() (CacheItem, error)) (CacheItem, error)
+
+	// Touch marks an item as used without retrieving it, returning true if it was present
+	Touch(key K) bool
+
+	// Len returns the number of items currently stored in the cache
+	Len() int
+
+	// Size returns the total size of all the items currently stored in the cache
+	Size() int
+
+	// Clear removes all items from the cache
+	Clear()
+
+	// Stats returns counts of the hits, misses, evictions, expirations and adds since the cache was created
+	Stats() Stats
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: keyedCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// keyedCache turns keys into strings with keyFn and passes everything on to the underlying Cache
+type keyedCache[K comparable] struct {
+
+	// underlying is the Cache the items are stored in
+	underlying Cache
+
+	// keyFn turns a key into the string its stored under
+	keyFn func(K) string
+}
+
+// Add adds an item to the underlying cache under keyFn(key)
+func (this *keyedCache[K]) Add(key K, val CacheItem) error {
+	return this.underlying.Add(this.keyFn(key), val)
+}
+
+// Get retrieves an item from the underlying cache if its present
+func (this *keyedCache[K]) Get(key K) (CacheItem, bool) {
+	return this.underlying.Get(this.keyFn(key))
+}
+
+// Remove removes an item from the underlying cache
+func (this *keyedCache[K]) Remove(key K) {
+	this.underlying.Remove(this.keyFn(key))
+}
+
+// Peek retrieves an item from the underlying cache if its present, without counting as an access
+func (this *keyedCache[K]) Peek(key K) (CacheItem, bool) {
+	return this.underlying.Peek(this.keyFn(key))
+}
+
+// Contains returns true if the item is present in the underlying cache
+func (this *keyedCache[K]) Contains(key K) bool {
+	return this.underlying.Contains(this.keyFn(key))
+}
+
+// GetOrAdd retrieves an item from the underlying cache, computing and adding it if its missing
+func (this *keyedCache[K]) GetOrAdd(key K, compute func() (CacheItem, error)) (CacheItem, error) {
+	return this.underlying.GetOrAdd(this.keyFn(key), compute)
+}
+
+// Touch marks an item in the underlying cache as used without retrieving it
+func (this *keyedCache[K]) Touch(key K) bool {
+	return this.underlying.Touch(this.keyFn(key))
+}
+
+// Len returns the number of items in the underlying cache
+func (this *keyedCache[K]) Len() int {
+	return this.underlying.Len()
+}
+
+// Size returns the total size of the items in the underlying cache
+func (this *keyedCache[K]) Size() int {
+	return this.underlying.Size()
+}
+
+// Clear removes all items from the underlying cache
+func (this *keyedCache[K]) Clear() {
+	this.underlying.Clear()
+}
+
+// Stats returns the underlying cache's counts
+func (this *keyedCache[K]) Stats() Stats {
+	return this.underlying.Stats()
+}
\ No newline at end of file
@@ -0,0 +1,60 @@
+package memcache
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+func TestKeyedCacheIntKeys(t *testing.T) {
+	underlying := CreateLRUCache(MaxSize)
+	cache := NewKeyedCache[int](underlying, strconv.Itoa)
+
+	item := &DummyCacheItem{DummySize: 10}
+	cache.Add(42, item)
+	if got, present := cache.Get(42); !present || got != item {
+		t.Error("Expected to get back the item, got", got, present)
+	}
+	if !underlying.Contains("42") {
+		t.Error("Expected the item to be stored under 42, got", underlying.Keys())
+	}
+
+	// Remove should use the same key
+	cache.Remove(42)
+	if cache.Contains(42) || underlying.Len() != 0 {
+		t.Error("Expected 42 to be removed, got", underlying.Keys())
+	}
+}
+
+type testKey struct {
+	Tenant string
+	ID int
+}
+
+func TestKeyedCacheStructKeys(t *testing.T) {
+	// %q quotes the tenant so a ":" in it can't make two keys collide
+	cache := NewKeyedCache[testKey](CreateLRUCache(MaxSize), func(key testKey) string {
+		return fmt.Sprintf("%q:%d", key.Tenant, key.ID)
+	})
+
+	first, second := &DummyCacheItem{DummySize: 10}, &DummyCacheItem{DummySize: 20}
+	cache.Add(testKey{Tenant: "a:1", ID: 2}, first)
+	cache.Add(testKey{Tenant: "a", ID: 1}, second)
+	if got, _ := cache.Get(testKey{Tenant: "a:1", ID: 2}); got != first {
+		t.Error("Expected the first item, got", got)
+	}
+	if got, _ := cache.Get(testKey{Tenant: "a", ID: 1}); got != second {
+		t.Error("Expected the second item, got", got)
+	}
+	if cache.Len() != 2 || cache.Size() != 30 {
+		t.Error("Expected 2 items of size 30, got", cache.Len(), "items of size", cache.Size())
+	}
+
+	// GetOrAdd should find the same item rather than computing
+	got, err := cache.GetOrAdd(testKey{Tenant: "a", ID: 1}, func() (CacheItem, error) {
+		return nil, fmt.Errorf("shouldn't be called")
+	})
+	if got != second || err != nil {
+		t.Error("Expected the second item, got", got, err)
+	}
+}
\ No newline at end of file
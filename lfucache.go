@@ -0,0 +1,167 @@
+package memcache
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateLFUCache creates and returns a 'Least Frequently Used' implementation of Cache
+//
+// LFU keeps a count of how many times each item has been added / accessed. When the cache goes over max size the item
+// with the lowest count is removed, if there's a tie then the least recently used of them goes first
+func CreateLFUCache(maxsize int) (Cache) {
+	return createPolicyCache(maxsize, &lfuPolicy { buckets: make(map[int]*lfuBucket) })
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: lfuBucket (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// lfuBucket holds all the entries that have been accessed the same number of times
+//
+// Buckets are linked together in order of frequency so the lowest can always be found without searching
+type lfuBucket struct {
+
+	// entries are the entries with this frequency, most recently used at the head
+	entries entryList
+
+	// freq is the access count of every entry in the bucket
+	freq int
+
+	// prev is the bucket with the next lowest frequency, nil if we're the lowest
+	prev *lfuBucket
+
+	// next is the bucket with the next highest frequency, nil if we're the highest
+	next *lfuBucket
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: lfuPolicy (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// lfuPolicy is the evictionPolicy for the LFU cache
+//
+// Entries are kept in frequency buckets. Moving an entry up a frequency only ever touches its own bucket and the one
+// next to it, and the victim is the tail of the lowest bucket, so both are O(1)
+type lfuPolicy struct {
+
+	// buckets is the map of frequency to bucket, only buckets with entries in them are kept
+	buckets map[int]*lfuBucket
+
+	// lowest is the bucket with the lowest frequency
+	lowest *lfuBucket
+
+	// highest is the bucket with the highest frequency
+	highest *lfuBucket
+}
+
+// bucketFor returns the bucket for a frequency, creating it if it doesn't exist
+//
+// If it has to be made then its linked in after the highest bucket with a lower frequency. The search starts from after
+// (nil to start from the lowest bucket) so when its known to be the next bucket along this is O(1)
+func (this *lfuPolicy) bucketFor(freq int, after *lfuBucket) *lfuBucket {
+	if bucket, present := this.buckets[freq]; present {
+		return bucket
+	}
+
+	// Find the bucket we'll sit after, nil if we're the new lowest
+	if after == nil && this.lowest != nil && this.lowest.freq < freq {
+		after = this.lowest
+	}
+	for after != nil && after.next != nil && after.next.freq < freq {
+		after = after.next
+	}
+
+	bucket := &lfuBucket { freq: freq, prev: after }
+	if after != nil {
+		bucket.next = after.next
+		after.next = bucket
+	} else {
+		bucket.next = this.lowest
+		this.lowest = bucket
+	}
+	if bucket.next != nil {
+		bucket.next.prev = bucket
+	} else {
+		this.highest = bucket
+	}
+
+	this.buckets[freq] = bucket
+	return bucket
+}
+
+// removeFrom takes an entry out of a bucket, dropping the bucket if its now empty
+func (this *lfuPolicy) removeFrom(bucket *lfuBucket, entry *policyEntry) {
+	bucket.entries.remove(entry)
+	if bucket.entries.len > 0 {
+		return
+	}
+
+	if bucket.prev != nil {
+		bucket.prev.next = bucket.next
+	} else {
+		this.lowest = bucket.next
+	}
+	if bucket.next != nil {
+		bucket.next.prev = bucket.prev
+	} else {
+		this.highest = bucket.prev
+	}
+	delete(this.buckets, bucket.freq)
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// evictionPolicy Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// added puts the entry at the head of the bucket for its frequency
+func (this *lfuPolicy) added(entry *policyEntry) {
+	this.bucketFor(entry.freq, nil).entries.pushFront(entry)
+}
+
+// accessed moves the entry up from the bucket for its previous frequency to the next one
+func (this *lfuPolicy) accessed(entry *policyEntry) {
+	from := this.buckets[entry.freq - 1]
+	to := this.bucketFor(entry.freq, from)
+	this.removeFrom(from, entry)
+	to.entries.pushFront(entry)
+}
+
+// admitting does nothing, LFU doesn't remember keys once they've gone
+func (this *lfuPolicy) admitting(key string) {
+}
+
+// removed takes the entry out of its bucket
+func (this *lfuPolicy) removed(entry *policyEntry, evicted bool) {
+	this.removeFrom(this.buckets[entry.freq], entry)
+}
+
+// victim returns the least recently used entry in the lowest frequency bucket
+func (this *lfuPolicy) victim() *policyEntry {
+	if this.lowest == nil {
+		return nil
+	}
+	return this.lowest.entries.tail
+}
+
+// peekVictim returns victim, which only reads the buckets so calling it doesn't change anything
+func (this *lfuPolicy) peekVictim() *policyEntry {
+	return this.victim()
+}
+
+// each iterates from the highest frequency bucket down, most recently used first within each
+func (this *lfuPolicy) each(fn func(entry *policyEntry) bool) {
+	for bucket := this.highest; bucket != nil; bucket = bucket.prev {
+		for entry := bucket.entries.head; entry != nil; entry = entry.next {
+			if !fn(entry) {
+				return
+			}
+		}
+	}
+}
+
+// reset drops all the buckets
+func (this *lfuPolicy) reset() {
+	this.buckets = make(map[int]*lfuBucket)
+	this.lowest = nil
+	this.highest = nil
+}
@@ -0,0 +1,143 @@
+package memcache
+
+import (
+	"testing"
+	"strconv"
+)
+
+func TestLFUCache(t *testing.T) {
+	cache := CreateLFUCache(MaxSize)
+
+	// Check cache is created correctly
+	if cache == nil {
+		t.Error("Cache is nil")
+	}
+
+	// Add a hot key and access it lots of times
+	cache.Add("hot", &DummyCacheItem{DummySize: 10})
+	for i := 0; i < 10; i++ {
+		cache.Get("hot")
+	}
+
+	// Add a burst of cold keys, way more than will fit
+	for i := 0; i < 50; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Hot key should have survived the burst
+	if _, present := cache.Get("hot"); !present {
+		t.Error("hot should have survived the cold keys being added")
+	}
+
+	// The most recent 9 cold keys should have survived, the ones before should have been evicted
+	if _, present := cache.Get("40"); present {
+		t.Error("40 should have been evicted")
+	}
+	for i := 41; i < 50; i++ {
+		if _, present := cache.Get(strconv.Itoa(i)); !present {
+			t.Error(strconv.Itoa(i), "should be present")
+		}
+	}
+	if cache.Len() != 10 || cache.Size() != MaxSize {
+		t.Error("Expected 10 items of size", MaxSize, "got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestLFUCacheTieBreak(t *testing.T) {
+	cache := CreateLFUCache(30)
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// All have the same frequency, "a" is the least recently used so goes first
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	if _, present := cache.Get("a"); present {
+		t.Error("a should have been evicted as the least recently used")
+	}
+
+	// Re-adding "c" bumps its count, "b" is then the least recently used with a count of 1
+	item := &DummyCacheItem{DummySize: 10}
+	cache.Add("c", item)
+	cache.Add("c", item)
+	cache.Add("e", &DummyCacheItem{DummySize: 10})
+	if _, present := cache.Get("b"); present {
+		t.Error("b should have been evicted")
+	}
+
+	// Order should be highest frequency first, most recently used first for the same frequency
+	assertKeys(t, cache.Keys(), []string{"c", "e", "d"})
+}
+
+func TestLFUCacheRemove(t *testing.T) {
+	cache := CreateLFUCache(MaxSize)
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Get("b")
+	cache.Remove("a")
+	cache.Remove("b")
+
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected empty cache, got", cache.Len(), "items of size", cache.Size())
+	}
+
+	// Buckets should have been tidied up so adds still work
+	cache.Add("c", &DummyCacheItem{DummySize: 60})
+	cache.Add("d", &DummyCacheItem{DummySize: 60})
+	assertKeys(t, cache.Keys(), []string{"d"})
+
+	cache.Clear()
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected empty cache after Clear, got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestLFUCacheStats(t *testing.T) {
+	cache := CreateLFUCache(20)
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// "a" is accessed so "b" is evicted when "c" is added
+	cache.Get("a")
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	cache.Get("b")
+
+	assertStats(t, cache.Stats(), Stats{Hits: 1, Misses: 1, Evictions: 1, Adds: 3})
+}
+
+func TestLFUCachePeek(t *testing.T) {
+	cache := CreateLFUCache(20)
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// Peeking "a" shouldn't increase its frequency, so it's still evicted first
+	for i := 0; i < 5; i++ {
+		cache.Peek("a")
+	}
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	if _, present := cache.Peek("a"); present {
+		t.Error("a should have been evicted")
+	}
+	if _, present := cache.Peek("b"); !present {
+		t.Error("b should be present")
+	}
+}
+
+func TestLFUCacheContains(t *testing.T) {
+	cache := CreateLFUCache(20)
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	if !cache.Contains("a") || cache.Contains("missing") {
+		t.Error("Contains should only report a and b as present")
+	}
+
+	// Contains shouldn't save "a" from being evicted
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	if cache.Contains("a") {
+		t.Error("a should have been evicted")
+	}
+}
@@ -0,0 +1,152 @@
+package memcache
+
+import (
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// NewLoadingCache creates and returns a LoadingCache that calls loader to fill in items missing from underlying
+//
+// Get on a missing key calls loader, adds the item it returns and returns it. Concurrent Gets for the same missing key
+// share one call to loader (as long as underlying's GetOrAdd does, all the caches in this package do). If loader returns
+// an error then nothing is added, Get treats the key as missing and GetOrLoad returns the error. The next Get tries again
+func NewLoadingCache(underlying Cache, loader func(key string) (CacheItem, error)) (LoadingCache) {
+	return &loadingCache { Cache: underlying, loader: loader }
+}
+
+// NewLoadingCacheCachingErrors creates and returns a LoadingCache like NewLoadingCache, but errors returned by loader are
+// remembered for errorTTL. Until then Gets for the key return the same error without calling loader again
+func NewLoadingCacheCachingErrors(underlying Cache, loader func(key string) (CacheItem, error), errorTTL time.Duration) (LoadingCache) {
+	return &loadingCache { Cache: underlying, loader: loader, errorTTL: errorTTL, errors: make(map[string]loadError) }
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Interface: LoadingCache
+// ------------------------------------------------------------------------------------------------------------------------
+
+// LoadingCache is a Cache that loads missing items itself
+type LoadingCache interface {
+	Cache
+
+	// GetOrLoad retrieves an item like Get, loading it if its missing. If the item couldn't be loaded then loader's error
+	// is returned
+	GetOrLoad(key string) (CacheItem, error)
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: loadingCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// loadingCache wraps a Cache and loads items on a miss. Everything but Get goes straight to the underlying Cache
+type loadingCache struct {
+	Cache
+
+	// loader is called with the key of a missing item
+	loader func(key string) (CacheItem, error)
+
+	// errorTTL is how long loader errors are remembered, 0 if they aren't
+	errorTTL time.Duration
+
+	// errors is the map of key to the last error loader returned for it, nil if errors aren't remembered
+	errors map[string]loadError
+
+	// mutex is used to synchronize errors as it can be accessed by multiple goroutines
+	mutex sync.Mutex
+
+	// clock tells the cache the time errors expire at, nil for the real time
+	clock clock
+}
+
+// now returns the current time from the cache's clock, the real time if it hasn't been given one. The mutex must be
+// held
+func (this *loadingCache) now() time.Time {
+	if this.clock == nil {
+		return time.Now()
+	}
+	return this.clock.Now()
+}
+
+// loadError is an error returned by loader, and when it should be forgotten
+type loadError struct {
+
+	// err is the error loader returned
+	err error
+
+	// expires is when the error stops being returned
+	expires time.Time
+}
+
+// load calls loader for the key, or returns its remembered error if it has one that hasn't expired
+func (this *loadingCache) load(key string) (CacheItem, error) {
+	if this.errors == nil {
+		return this.loader(key)
+	}
+
+	this.mutex.Lock()
+	cached, present := this.errors[key]
+	if present && this.now().Before(cached.expires) {
+		this.mutex.Unlock()
+		return nil, cached.err
+	}
+	delete(this.errors, key)
+	this.mutex.Unlock()
+
+	item, err := this.loader(key)
+	if err != nil {
+		this.mutex.Lock()
+		this.errors[key] = loadError { err: err, expires: this.now().Add(this.errorTTL) }
+		this.mutex.Unlock()
+	}
+	return item, err
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// LoadingCache Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// Get retrieves an item from the underlying cache, loading and adding it if its missing
+//
+// If the item couldn't be loaded then nil, false is returned
+func (this *loadingCache) Get(key string) (CacheItem, bool) {
+	item, err := this.GetOrLoad(key)
+	if err != nil {
+		return nil, false
+	}
+	return item, true
+}
+
+// GetOrLoad retrieves an item from the underlying cache, loading and adding it if its missing
+func (this *loadingCache) GetOrLoad(key string) (CacheItem, error) {
+	return this.Cache.GetOrAdd(key, func() (CacheItem, error) {
+		return this.load(key)
+	})
+}
+
+// GetOrErr retrieves an item like GetOrLoad, missing items are loaded so the error is the loader's rather than ErrNotFound
+func (this *loadingCache) GetOrErr(key string) (CacheItem, error) {
+	return this.GetOrLoad(key)
+}
+
+// Remove removes an item from the underlying cache, and forgets any error loading it
+func (this *loadingCache) Remove(key string) {
+	this.Cache.Remove(key)
+	if this.errors != nil {
+		this.mutex.Lock()
+		delete(this.errors, key)
+		this.mutex.Unlock()
+	}
+}
+
+// Clear removes all items from the underlying cache, and forgets any errors loading them
+func (this *loadingCache) Clear() {
+	this.Cache.Clear()
+	if this.errors != nil {
+		this.mutex.Lock()
+		this.errors = make(map[string]loadError)
+		this.mutex.Unlock()
+	}
+}
\ No newline at end of file
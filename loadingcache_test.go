@@ -0,0 +1,122 @@
+package memcache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadingCache(t *testing.T) {
+	loads := 0
+	cache := NewLoadingCache(CreateLRUCache(MaxSize), func(key string) (CacheItem, error) {
+		loads++
+		return NewString("loaded " + key), nil
+	})
+
+	// Miss should load and add the item
+	if item, present := cache.Get("a"); !present || item != NewString("loaded a") {
+		t.Error("Expected the loaded item, got", item, present)
+	}
+	if !cache.Contains("a") || loads != 1 {
+		t.Error("Expected a to be added after one load, got", cache.Keys(), loads)
+	}
+
+	// Hit shouldn't load again
+	if item, present := cache.Get("a"); !present || item != NewString("loaded a") || loads != 1 {
+		t.Error("Expected a hit without loading, got", item, present, loads)
+	}
+
+	// Items added directly are returned as they are
+	cache.Add("b", NewString("added"))
+	if item, err := cache.GetOrLoad("b"); err != nil || item != NewString("added") || loads != 1 {
+		t.Error("Expected the added item without loading, got", item, err, loads)
+	}
+}
+
+func TestLoadingCacheError(t *testing.T) {
+	loadErr := errors.New("load failed")
+	loads := 0
+	cache := NewLoadingCache(CreateLRUCache(MaxSize), func(key string) (CacheItem, error) {
+		loads++
+		return nil, loadErr
+	})
+
+	// Error should come back and nothing be added
+	if item, err := cache.GetOrLoad("a"); item != nil || err != loadErr {
+		t.Error("Expected the loader's error, got", item, err)
+	}
+	if item, present := cache.Get("a"); item != nil || present {
+		t.Error("Expected Get to miss, got", item, present)
+	}
+
+	// GetOrErr loads too, so its the loader's error rather than ErrNotFound
+	if item, err := cache.GetOrErr("a"); item != nil || err != loadErr {
+		t.Error("Expected GetOrErr to return the loader's error, got", item, err)
+	}
+
+	// Errors aren't cached, so each Get tries again
+	if cache.Contains("a") || loads != 3 {
+		t.Error("Expected nothing added and 3 loads, got", cache.Keys(), loads)
+	}
+}
+
+func TestLoadingCacheCachingErrors(t *testing.T) {
+	loadErr := errors.New("load failed")
+	loads := 0
+	cache := NewLoadingCacheCachingErrors(CreateLRUCache(MaxSize), func(key string) (CacheItem, error) {
+		loads++
+		return nil, loadErr
+	}, TestTTL)
+	clock := NewFakeClock()
+	SetClock(cache, clock)
+
+	// Error is remembered, loader isn't called again
+	cache.GetOrLoad("a")
+	if _, err := cache.GetOrLoad("a"); err != loadErr || loads != 1 {
+		t.Error("Expected the remembered error after one load, got", err, loads)
+	}
+
+	// An Add replaces it
+	cache.Add("a", NewString("added"))
+	if item, err := cache.GetOrLoad("a"); err != nil || item != NewString("added") {
+		t.Error("Expected the added item, got", item, err)
+	}
+
+	// Once the ttl has passed the loader is tried again
+	cache.GetOrLoad("b")
+	clock.Advance(TestTTL + time.Millisecond)
+	cache.GetOrLoad("b")
+	if loads != 3 {
+		t.Error("Expected the error to expire and b to load again, got", loads, "loads")
+	}
+}
+
+func TestLoadingCacheConcurrent(t *testing.T) {
+	var loads atomic.Int32
+	cache := NewLoadingCache(CreateLRUCache(MaxSize), func(key string) (CacheItem, error) {
+		loads.Add(1)
+
+		// Give the other goroutines time to pile up behind us
+		time.Sleep(10 * time.Millisecond)
+		return NewString("loaded"), nil
+	})
+
+	// Lots of goroutines all missing the same key at once
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if item, present := cache.Get("a"); !present || item != NewString("loaded") {
+				t.Error("Expected loaded item, got", item, present)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Error("Expected loader to be called once, got", loads.Load())
+	}
+}
\ No newline at end of file
@@ -1,15 +1,81 @@
 package memcache
 
 import (
+	"container/heap"
+	"context"
 	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
+	// maxPendingPromotions is how many Gets a read optimized cache buffers before a Get tries to apply them itself
+	maxPendingPromotions = 64
+
+	// memoryCheckInterval is how often a memory guarded cache reads the heap size, ReadMemStats stops the world
+	memoryCheckInterval = 100 * time.Millisecond
+
+	// rampStartPercent is the percentage of its final max size a ramped cache starts at
+	rampStartPercent = 10
+
 	// ErrorExceedsMaxSize is the error returned by Add if the item is too big for the cache 
 	ErrorExceedsMaxSize = "Exceeds max size, can't store"
+
+	// ErrorInvalidMaxSize is the error returned by CreateLRUCacheChecked if the max size is 0 or less
+	ErrorInvalidMaxSize = "Max size must be greater than 0"
+
+	// ErrorExceedsMaxItemSize is the error returned by Add if the item is bigger than the cache's max item size
+	ErrorExceedsMaxItemSize = "Exceeds max item size, can't store"
+
+	// ErrorNegativeSize is the error returned by Add if the item's size is less than 0
+	ErrorNegativeSize = "Size is negative, can't store"
+
+	// ErrorNotInt is the error returned by Increment and Decrement if the item under the key isn't an IntCacheItem
+	ErrorNotInt = "Item isn't an IntCacheItem, can't increment"
+
+	// ErrorNilItem is the error returned by Add if the item is nil
+	ErrorNilItem = "Item is nil, can't store"
+
+	// ErrorCacheFull is the error returned by Add if the cache rejects items that don't fit and the item doesn't
+	ErrorCacheFull = "Cache is full, can't store"
+
+	// ErrorCacheClosed is the error returned by a write-behind cache's Add once its been closed
+	ErrorCacheClosed = "Cache has been closed, can't store"
 )
 
+// ErrExceedsMaxSize is the error returned by Add if the item is too big for the cache, compare with errors.Is. Its
+// message is ErrorExceedsMaxSize, which is kept for callers that check the string
+var ErrExceedsMaxSize = errors.New(ErrorExceedsMaxSize)
+
+// ErrExceedsMaxItemSize is the error returned by Add if the item is bigger than the cache's max item size, even though
+// it would fit in the cache. Its message is ErrorExceedsMaxItemSize
+var ErrExceedsMaxItemSize = errors.New(ErrorExceedsMaxItemSize)
+
+// ErrNegativeSize is the error returned by Add if the item's Size (or the cost its added with) is less than 0. Storing
+// it would knock the size of the cache out. Its message is ErrorNegativeSize
+var ErrNegativeSize = errors.New(ErrorNegativeSize)
+
+// ErrNotInt is the error returned by Increment and Decrement if the item under the key isn't an IntCacheItem. Its
+// message is ErrorNotInt
+var ErrNotInt = errors.New(ErrorNotInt)
+
+// ErrNilItem is the error returned by Add if the item is nil, it has no Size and would panic once it was used. Its
+// message is ErrorNilItem
+var ErrNilItem = errors.New(ErrorNilItem)
+
+// ErrCacheFull is the error returned by Add for a cache created with OverflowReject, if the item would only fit by
+// evicting others. Its message is ErrorCacheFull
+var ErrCacheFull = errors.New(ErrorCacheFull)
+
+// ErrCacheClosed is the error returned by a write-behind cache's Add once its been closed. Its message is
+// ErrorCacheClosed
+var ErrCacheClosed = errors.New(ErrorCacheClosed)
+
 // ------------------------------------------------------------------------------------------------------------------------
 // Creation functions
 // ------------------------------------------------------------------------------------------------------------------------
@@ -17,8 +83,163 @@ const (
 // CreateLRUCache creates and returns a 'Last Recently Used' implementation of Cache
 //
 // LRU keeps items added and accessed most recently in preference to older items. Older meaning, last added / accessed
+//
+// maxsize isn't checked. If its 0 or less then anything with a size is rejected by Add with ErrExceedsMaxSize, use
+// CreateLRUCacheChecked to get an error up front instead
 func CreateLRUCache(maxsize int) (Cache) {
-	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.Mutex { } }
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { } }
+}
+
+// CreateLRUCacheChecked creates and returns an LRU Cache like CreateLRUCache, but returns an error if maxsize is 0 or
+// less as the cache wouldn't be able to store anything
+func CreateLRUCacheChecked(maxsize int) (Cache, error) {
+	if maxsize <= 0 {
+		return nil, errors.New(ErrorInvalidMaxSize)
+	}
+	return CreateLRUCache(maxsize), nil
+}
+
+// CreateLRUCacheWithCallback creates and returns an LRU Cache that calls onEvict whenever an item is evicted
+//
+// onEvict is told whether the item was evicted to make room or because it expired. If includeRemove is true then onEvict
+// is also called for items removed with Remove. onEvict is called after the cache has been unlocked, so its safe for it
+// to use the cache
+func CreateLRUCacheWithCallback(maxsize int, onEvict EvictCallback, includeRemove bool) (Cache) {
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { }, 
+		onEvict: onEvict, notifyRemove: includeRemove }
+}
+
+// CreateLRUCacheReadOptimized creates and returns an LRU Cache where Gets can run at the same time as each other
+//
+// A normal LRU Cache has to lock the whole cache on Get, as the accessed item is moved to the head of the queue. Here
+// Get only takes a read lock and puts the item in a buffer of pending moves, which are applied the next time something
+// takes the full lock (Add, Remove etc). If lots of Gets happen without a write the buffer fills up, the Get that finds it
+// full applies the buffered moves itself if it can take the full lock without waiting. If it can't then its move is
+// dropped, so the LRU order is an approximation under heavy concurrent load
+func CreateLRUCacheReadOptimized(maxsize int) (Cache) {
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { }, 
+		readOptimized: true }
+}
+
+// CreateLRUCacheByCount creates and returns an LRU Cache that holds at most maxItems items, whatever their size
+//
+// Sizes are still tracked (and returned by Size) but don't cause evictions, Cap returns math.MaxInt as there's no size
+// limit. Once there are maxItems items, adding another removes the tail item
+func CreateLRUCacheByCount(maxItems int) (Cache) {
+	return CreateLRUCacheBounded(0, maxItems)
+}
+
+// CreateLRUCacheBounded creates and returns an LRU Cache limited by both the total size of its items and their count
+//
+// Tail items are removed while either limit would be exceeded. A limit of 0 means there's no limit for that one, Cap
+// returns math.MaxInt if there's no size limit
+func CreateLRUCacheBounded(maxBytes, maxItems int) (Cache) {
+	if maxBytes == 0 {
+		maxBytes = math.MaxInt
+	}
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxBytes, mutex: sync.RWMutex { }, 
+		maxItems: maxItems }
+}
+
+// CreateLRUCacheWithMaxItemSize creates and returns an LRU Cache that rejects any item bigger than maxItemSize
+//
+// Stops a single big item flushing everything else out of the cache. Add returns ErrExceedsMaxItemSize for items bigger
+// than maxItemSize but no bigger than maxsize, and ErrExceedsMaxSize as usual for anything bigger than that. A
+// maxItemSize of 0 means there's no limit on single items
+func CreateLRUCacheWithMaxItemSize(maxsize, maxItemSize int) (Cache) {
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { }, 
+		maxItemSize: maxItemSize }
+}
+
+// CreateLRUCacheWithWatermarks creates and returns an LRU Cache that evicts in batches, instead of one item per Add
+//
+// Once an Add would take the cache over maxsize, tail items are removed until it would be at or below lowWaterPercent of
+// maxsize. Later Adds then have room without evicting anything, so under churn evictions happen less often but more
+// items go each time. A lowWaterPercent of 0 or less, or 100 or more, evicts just enough to fit like CreateLRUCache
+func CreateLRUCacheWithWatermarks(maxsize, lowWaterPercent int) (Cache) {
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { }, 
+		lowWaterPercent: lowWaterPercent }
+}
+
+// CreateLRUCacheWithEvents creates and returns an LRU Cache that sends a CacheEvent for every Add, Get, eviction and
+// removal on the channel returned by Events
+//
+// Up to bufferSize events are held for the consumer, once its full the oldest are dropped so a slow consumer (or none)
+// never holds up the cache. The returned Cache implements ObservableCache, Close stops the events and closes the channel
+func CreateLRUCacheWithEvents(maxsize, bufferSize int) (Cache) {
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { }, 
+		events: newEventStream(bufferSize) }
+}
+
+// CreateLRUCacheWithSizeFunc creates and returns an LRU Cache that uses sizeFunc to work out the size of items, instead
+// of calling their Size method
+//
+// Useful for items whose Size can't be changed to give their real size, e.g. types from another package. sizeFunc is
+// called whenever the cache would otherwise call Size, on Add and UpdateSize. The size its given is remembered so the
+// same size is taken off when the item leaves the cache
+func CreateLRUCacheWithSizeFunc(maxsize int, sizeFunc SizeFunc) (Cache) {
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { }, 
+		sizeFunc: sizeFunc }
+}
+
+// CreateLRUCacheWithTTL creates and returns an LRU Cache where items expire once they've been in the cache for ttl
+//
+// Expired items aren't removed until they're next accessed, so they still count towards the cache size until then. Adding
+// an item again restarts its ttl
+func CreateLRUCacheWithTTL(maxsize int, ttl time.Duration) (Cache) {
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { }, ttl: ttl }
+}
+
+// CreateLRUCacheWithJanitor creates and returns an LRU Cache with a ttl, and a janitor that removes expired items
+//
+// Every sweepInterval the janitor goes through the cache and removes anything that's expired, so expired items don't take
+// up memory until they're next accessed. The returned Cache implements io.Closer, Close must be called to stop the janitor
+func CreateLRUCacheWithJanitor(maxsize int, ttl, sweepInterval time.Duration) (Cache) {
+	cache := &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { }, ttl: ttl, 
+		stop: make(chan struct{}) }
+	go cache.janitor(sweepInterval)
+	return cache
+}
+
+// CreateLRUCacheWithMemoryGuard creates and returns an LRU Cache that shrinks when the process heap gets too big
+//
+// Before an Add the heap size is checked (at most every memoryCheckInterval, as reading it is expensive). If its over
+// heapLimitBytes then a quarter of the cache is evicted from the tail, on top of anything evicted to make room. The
+// cache keeps shrinking on each check until the heap comes back under the limit
+func CreateLRUCacheWithMemoryGuard(maxsize int, heapLimitBytes uint64) (Cache) {
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { }, 
+		memGuard: &memoryGuard { limit: heapLimitBytes, readMemStats: runtime.ReadMemStats } }
+}
+
+// CreateLRUCacheWithOverflow creates and returns an LRU Cache that does what overflow says when its full
+//
+// With OverflowEvict its the same as CreateLRUCache. With OverflowReject nothing is evicted to make room, an Add that
+// wouldn't fit in the space left returns ErrCacheFull and the cache is left as it was. Replacing an item only needs room
+// for the difference in size, so items can still be updated in a full cache if they don't grow by more than is left
+func CreateLRUCacheWithOverflow(maxsize int, overflow OverflowPolicy) (Cache) {
+	return &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: maxsize, mutex: sync.RWMutex { }, 
+		overflow: overflow }
+}
+
+// CreateLRUCacheWithRamp creates and returns an LRU Cache whose max size grows from rampStartPercent of finalMax up to
+// finalMax over ramp
+//
+// Useful when lots of instances start cold at once, e.g. on deploy, so their memory use grows steadily instead of all of
+// them filling up straight away. The max size goes up in a straight line from when the cache is created, its worked out
+// on each Add so items bigger than the current max size are rejected like they would be by a smaller cache. Once ramp
+// has passed it behaves exactly like CreateLRUCache(finalMax). Calling Resize stops the ramp at the new size
+func CreateLRUCacheWithRamp(finalMax int, ramp time.Duration) (Cache) {
+	// Worked out like lowWaterMark so it can't overflow, and at least 1 so there's room for something straight away
+	initial := finalMax / 100 * rampStartPercent + finalMax % 100 * rampStartPercent / 100
+	if initial < 1 && finalMax > 0 {
+		initial = 1
+	}
+	capacity := &capacityRamp { initial: initial, final: finalMax, duration: ramp }
+	cache := &lruCache { keyValMap: make(map[string]*lruCacheItem), maxSize: capacity.initial, mutex: sync.RWMutex { }, 
+		ramp: capacity }
+	capacity.now = cache.now
+	capacity.start = capacity.now()
+	return cache
 }
 
 // ------------------------------------------------------------------------------------------------------------------------
@@ -39,13 +260,65 @@ type lruCacheItem struct {
 
 	// next is the next item in the linked-list, nil if we're the tail
 	next *lruCacheItem
+
+	// added is when the item was added to the cache, used to see if its expired
+	added time.Time
+
+	// size is the size of cacheItem when it was added (or last updated), so the same size is taken off when its removed
+	size int
+
+	// ttl is how long the item stays in the cache after added, 0 if it never expires. Its the cache's ttl unless it was
+	// added with AddWithTTL
+	ttl time.Duration
+
+	// heapIndex is the item's position in the cache's expiries heap, only kept up to date if the cache has a ttl
+	heapIndex int
+
+	// generation is the cache's generation when the item was added, its stale once InvalidateAll moves the cache on
+	generation uint64
+}
+
+// expiresAt returns when the item expires so it can be kept in an expiryHeap
+func (this *lruCacheItem) expiresAt() time.Time {
+	return expiry(this.added, this.ttl)
+}
+
+// setHeapIndex is called by the expiryHeap when the item moves
+func (this *lruCacheItem) setHeapIndex(index int) {
+	this.heapIndex = index
+}
+
+// expired returns true if the item has been in the cache for longer than its ttl at the time passed in. A ttl of 0 means
+// it never expires
+func (this *lruCacheItem) expired(now time.Time) bool {
+	return this.ttl > 0 && now.Sub(this.added) > this.ttl
+}
+
+// now returns the current time from the cache's clock, the real time if it hasn't been given one
+func (this *lruCache) now() time.Time {
+	if this.clock == nil {
+		return time.Now()
+	}
+	return this.clock.Now()
+}
+
+// stale returns true if the item should be treated as missing, because its expired or InvalidateAll has been called
+// since it was added
+func (this *lruCache) stale(item *lruCacheItem) bool {
+	return item.generation != this.generation || item.expired(this.now())
 }
 
 // Remove removes this item from the lruCache and handles all clearup
 //
 // It pairs sibling nodes and points head/tail elsewhere if it was either. It also removes itself from the hash and alters 
 // the cache size
+// Its safe to call more than once, if the item isn't the one in the hash any more then nothing happens
 func (this *lruCacheItem) Remove(cache *lruCache) {
+	// Already removed (or replaced by another item under the key), doing it again would take the size off twice
+	if cache.keyValMap[this.key] != this {
+		return
+	}
+
 	// Join up left and right nodes (or point them at nil if heads and tails)
 	if this.prev != nil {
 		this.prev.next = this.next
@@ -63,7 +336,7 @@ func (this *lruCacheItem) Remove(cache *lruCache) {
 	}
 
 	// Remove size
-	cache.curSize -= this.cacheItem.Size()
+	cache.curSize -= this.size
 
 	// Remove from map
 	delete(cache.keyValMap, this.key)
@@ -74,8 +347,12 @@ func (this *lruCacheItem) Remove(cache *lruCache) {
 // It sets itself as the head and links the previous head and itself together. It also adds itself to the hash and alters
 // the cache size
 func (this *lruCacheItem) Add(cache *lruCache) {
+	// We might have been in the linked-list before, the head has nothing before it
+	this.prev = nil
+
 	// If we're the only element then set head and tail
 	if cache.head == nil {
+		this.next = nil
 		cache.head = this
 		cache.tail = this
 
@@ -87,12 +364,77 @@ func (this *lruCacheItem) Add(cache *lruCache) {
 	}
 
 	// Add size to cache
-	cache.curSize += this.cacheItem.Size()
+	cache.curSize += this.size
 
 	// Add to map
 	cache.keyValMap[this.key] = this
 }
 
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: memoryGuard (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// memoryGuard keeps track of when the heap was last checked for a memory guarded cache
+type memoryGuard struct {
+
+	// limit is the heap size in bytes the cache starts shrinking at
+	limit uint64
+
+	// lastCheck is when the heap was last read, zero if it hasn't been yet
+	lastCheck time.Time
+
+	// readMemStats reads the heap size, runtime.ReadMemStats unless a test replaces it
+	readMemStats func(stats *runtime.MemStats)
+}
+
+// overLimit returns true if the heap is over the limit at the time passed in. If it was checked less than
+// memoryCheckInterval before then false is returned without checking
+func (this *memoryGuard) overLimit(now time.Time) bool {
+	if now.Sub(this.lastCheck) < memoryCheckInterval {
+		return false
+	}
+	this.lastCheck = now
+
+	var stats runtime.MemStats
+	this.readMemStats(&stats)
+	return stats.HeapAlloc > this.limit
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: capacityRamp (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// capacityRamp works out the max size of a cache created with CreateLRUCacheWithRamp
+type capacityRamp struct {
+
+	// initial is the max size the cache starts at
+	initial int
+
+	// final is the max size the cache ends up at
+	final int
+
+	// start is when the ramp started
+	start time.Time
+
+	// duration is how long it takes to get from initial to final
+	duration time.Duration
+
+	// now returns the current time from the cache's clock
+	now func() time.Time
+}
+
+// capacity returns what the max size should be now, and true once its reached final
+func (this *capacityRamp) capacity() (int, bool) {
+	elapsed := this.now().Sub(this.start)
+	if elapsed >= this.duration {
+		return this.final, true
+	}
+	if elapsed <= 0 {
+		return this.initial, false
+	}
+	return this.initial + int(float64(this.final - this.initial) * float64(elapsed) / float64(this.duration)), false
+}
+
 // ------------------------------------------------------------------------------------------------------------------------
 // Struct: lruCache (not exported)
 // ------------------------------------------------------------------------------------------------------------------------
@@ -115,84 +457,1419 @@ type lruCache struct {
 	// maxSize holds the maximum size of the cache
 	maxSize int
 
-	// curSize holds the current size of the cache
+	// curSize holds the current size of the cache, including reserved
 	curSize int
 
+	// reserved is the total size held by Reserve for items that haven't been added yet
+	reserved int
+
+	// reservations holds the reservations that haven't been released or used by an Add yet, oldest first
+	reservations []*reservation
+
+	// maxItems holds the maximum number of items in the cache, 0 if there's no limit
+	maxItems int
+
+	// maxItemSize holds the maximum size of a single item, 0 if there's no limit other than maxSize
+	maxItemSize int
+
+	// frozen stops items being evicted to make room, set by Freeze
+	frozen bool
+
+	// sizeFunc works out the size of items instead of calling their Size method, nil to use Size
+	sizeFunc SizeFunc
+
+	// lowWaterPercent is the percentage of maxSize evicted down to once the cache is full, 0 to only evict enough to fit
+	lowWaterPercent int
+
 	// mutex is used to synchronize cache as it can be accessed by multiple goroutines
-	mutex sync.Mutex
+	mutex sync.RWMutex
+
+	// insertionOrder stops Get moving items to the head, so items are removed in the order they were added (FIFO)
+	insertionOrder bool
+
+	// ttl is how long items stay in the cache before they expire, 0 if they never expire
+	ttl time.Duration
+
+	// expiries holds the items that expire, soonest first, so the janitor can find expired items without walking the
+	// linked-list
+	expiries expiryHeap[*lruCacheItem]
+
+	// stop is closed to stop the janitor goroutine, nil if there isn't one
+	stop chan struct{}
+
+	// generation is incremented by InvalidateAll, items added under an older generation are treated as missing
+	generation uint64
+
+	// sweptGeneration is the generation the janitor last removed stale items for
+	sweptGeneration uint64
+
+	// closeOnce makes sure stop is only closed once
+	closeOnce sync.Once
+
+	// stats holds the hit, miss, eviction and add counts
+	stats cacheStats
+
+	// readOptimized makes Get take a read lock, moves to the head are buffered in pending instead
+	readOptimized bool
+
+	// pending holds items accessed by Get that need moving to the head, only used if readOptimized
+	pending [maxPendingPromotions]atomic.Pointer[lruCacheItem]
+
+	// pendingCount is the number of slots in pending that have been claimed, can go past maxPendingPromotions when full
+	pendingCount atomic.Int64
+
+	// onEvict is called with items evicted from the cache, nil if there's no callback
+	onEvict EvictCallback
+
+	// notifyRemove means onEvict is also called for items removed with Remove
+	notifyRemove bool
+
+	// events sends CacheEvents for the channel returned by Events, nil if the cache wasn't created with events
+	events *eventStream
+
+	// evicted holds items waiting to be passed to onEvict once the lock is released
+	evicted []evictedItem
+
+	// flights makes sure GetOrAdd only computes a missing item once
+	flights flightGroup
+
+	// negatives is the map of keys added with AddNegative to when they stop being known as absent, nil until one's added
+	negatives map[string]time.Time
+
+	// memGuard shrinks the cache when the heap is too big, nil if the cache doesn't have one
+	memGuard *memoryGuard
+
+	// ramp grows maxSize up to its final size, nil if the cache doesn't have one or its finished
+	ramp *capacityRamp
+
+	// pinned holds the keys of items that can't be evicted to make room, nil until one's pinned
+	pinned map[string]bool
+
+	// overflow is what Add does when an item doesn't fit
+	overflow OverflowPolicy
+
+	// clock tells the cache the time, nil for the real time
+	clock clock
 }
 
-// ------------------------------------------------------------------------------------------------------------------------
-// Cache Implementation
-// ------------------------------------------------------------------------------------------------------------------------
+// reservation is space held in the cache by Reserve
+type reservation struct {
 
-// Add adds a CacheItem to the cache, it can be retrieved using Get and passing in the same key
+	// bytes is how much space is held
+	bytes int
+}
+
+// evictedItem is an item waiting to be passed to onEvict, along with why it was removed
+type evictedItem struct {
+
+	// item is the item that was removed
+	item *lruCacheItem
+
+	// reason is why it was removed
+	reason EvictReason
+}
+
+// evict removes an item from the cache, it's passed to onEvict once the lock is released
 //
-// If the item already exists its moved from its current place in the linked-list to the head
-// If the item doesn't curretly exist then its added to the head. This item will add to the current size of the cache. If
-// the current size > max size then tail items are removed until it falls under max size
-func (this *lruCache) Add(k string, v CacheItem) error {
+// Items removed with Remove (ReasonManual) are only passed on if notifyRemove is set, they're always sent as events.
+// Only items removed to make room (ReasonCapacity) count as evictions in Stats, and items that have expired
+// (ReasonExpired) as expirations
+func (this *lruCache) evict(item *lruCacheItem, reason EvictReason) {
+	item.Remove(this)
+	this.untrack(item)
+	delete(this.pinned, item.key)
+	switch reason {
+	case ReasonCapacity:
+		this.stats.evictions.Add(1)
+	case ReasonExpired:
+		this.stats.expirations.Add(1)
+	}
+	this.events.removed(item.key, reason)
+	if this.onEvict != nil && (reason != ReasonManual || this.notifyRemove) {
+		this.evicted = append(this.evicted, evictedItem { item: item, reason: reason })
+	}
+}
 
-	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
-	this.mutex.Lock()
-	defer this.mutex.Unlock()
+// track adds a new item to the expiries heap, nothing happens if the item never expires
+func (this *lruCache) track(item *lruCacheItem) {
+	if item.ttl > 0 {
+		heap.Push(&this.expiries, item)
+	}
+}
 
-	// If we already contain item then remove from linked-list (value may be different)
-	if item, present := this.keyValMap[k]; present {
-		// Removes from position in linked-list
-		item.Remove(this)
-		
-		// Values are the same so we can just move to the start of the array
-		if v == item.cacheItem {
-			item.Add(this)
-			return nil
+// retrack moves an item in the expiries heap after its added time or ttl has been changed
+func (this *lruCache) retrack(item *lruCacheItem) {
+	switch {
+	case !this.tracked(item):
+		this.track(item)
+	case item.ttl > 0:
+		heap.Fix(&this.expiries, item.heapIndex)
+	default:
+		this.untrack(item)
+	}
+}
+
+// untrack takes an item out of the expiries heap, its safe to call if the item isn't in there
+func (this *lruCache) untrack(item *lruCacheItem) {
+	if this.tracked(item) {
+		heap.Remove(&this.expiries, item.heapIndex)
+		item.heapIndex = -1
+	}
+}
+
+// tracked returns true if the item is in the expiries heap
+func (this *lruCache) tracked(item *lruCacheItem) bool {
+	return item.heapIndex >= 0 && item.heapIndex < len(this.expiries) && this.expiries[item.heapIndex] == item
+}
+
+// checkSize returns the error Add should return for an item of the size passed in, nil if it can be stored
+func (this *lruCache) checkSize(size int) error {
+	if err := sizeError(size, this.maxSize); err != nil {
+		return err
+	}
+	if this.maxItemSize > 0 && size > this.maxItemSize {
+		return ErrExceedsMaxItemSize
+	}
+	return nil
+}
+
+// evictFor removes tail items until an item of the size passed in will fit, and there's room for one more item
+//
+// Nothing is removed while the cache is frozen
+func (this *lruCache) evictFor(size int) {
+	if this.frozen {
+		return
+	}
+	// Evict a batch in one go, leaving room for the next few Adds
+	if lowWater := this.lowWaterMark(); lowWater < this.maxSize && this.curSize + size > this.maxSize {
+		this.evictDownTo(max(lowWater - size, 0))
+	}
+	for this.curSize + size > this.maxSize || (this.maxItems > 0 && len(this.keyValMap) >= this.maxItems) {
+		victim := this.victim()
+		if victim == nil {
+			this.emptied()
+			break
 		}
+		this.evict(victim, ReasonCapacity)
 	}
+}
 
-	// Can't store if it already exceeds max size
-	if v.Size() > this.maxSize {
-		return errors.New(ErrorExceedsMaxSize)
+// victim returns the item closest to the tail that isn't pinned, nil if there isn't one
+func (this *lruCache) victim() *lruCacheItem {
+	item := this.tail
+	for item != nil && this.pinned[item.key] {
+		item = item.prev
 	}
+	return item
+}
 
-	// Remove tail items until we're under max size
-	for this.curSize + v.Size() > this.maxSize {
-		this.tail.Remove(this)
+// emptied is called when there's nothing left to evict. If the cache is empty but we think we still have a size then
+// its drifted so recompute it (empty == just the reservations), if everything left is pinned then the cache stays over
+// max size
+func (this *lruCache) emptied() {
+	if this.tail == nil {
+		this.curSize = this.reserved
 	}
+}
 
-	// Create item
-	lruItem := &lruCacheItem { cacheItem: v, key: k }
-	lruItem.Add(this)
+// release gives back the space held by a reservation, nothing happens if its already been released
+func (this *lruCache) release(reserved *reservation) {
+	for i, r := range this.reservations {
+		if r == reserved {
+			this.reservations = append(this.reservations[:i], this.reservations[i + 1:]...)
+			this.reserved -= r.bytes
+			this.curSize -= r.bytes
+			return
+		}
+	}
+}
+
+// claimReservation releases the oldest reservation big enough for an item of the size passed in, so the item can use
+// its space
+func (this *lruCache) claimReservation(size int) {
+	if r := this.reservationFor(size); r != nil {
+		this.release(r)
+	}
+}
+
+// reservationFor returns the reservation claimReservation would release for an item of the size passed in, nil if
+// there isn't one big enough
+func (this *lruCache) reservationFor(size int) *reservation {
+	for _, r := range this.reservations {
+		if r.bytes >= size {
+			return r
+		}
+	}
 	return nil
 }
 
-// Get retrieves an item from the cache if its present. Also, because its been accessed its moved to the head of the queue
+// sizeOf returns the size of an item, from sizeFunc if the cache has one. nil items are 0 so store can reject them
+func (this *lruCache) sizeOf(item CacheItem) int {
+	if item == nil {
+		return 0
+	}
+	if this.sizeFunc != nil {
+		return this.sizeFunc(item)
+	}
+	return item.Size()
+}
+
+// rampUp moves maxSize on if the cache is ramping up, once its reached the final size the ramp is dropped
+func (this *lruCache) rampUp() {
+	if this.ramp == nil {
+		return
+	}
+	capacity, done := this.ramp.capacity()
+	this.maxSize = capacity
+	if done {
+		this.ramp = nil
+	}
+}
+
+// lowWaterMark returns the size evictFor evicts down to once the cache is full, maxSize if it doesn't evict in batches
+func (this *lruCache) lowWaterMark() int {
+	if this.lowWaterPercent <= 0 || this.lowWaterPercent >= 100 {
+		return this.maxSize
+	}
+	return this.maxSize / 100 * this.lowWaterPercent + this.maxSize % 100 * this.lowWaterPercent / 100
+}
+
+// evictDownTo removes tail items until the current size is no more than size
+func (this *lruCache) evictDownTo(size int) {
+	for this.curSize > size {
+		victim := this.victim()
+		if victim == nil {
+			this.emptied()
+			break
+		}
+		this.evict(victim, ReasonCapacity)
+	}
+}
+
+// checkInvariants returns an error describing the first way the hash, linked-list or heap don't agree, nil if they do
 //
-// If item is present then the item, true is returned. Otherwise, nil, false
-func (this *lruCache) Get(key string) (CacheItem, bool) {
+// Its for tests to call after a sequence of operations, it walks everything so its too slow to use anywhere else
+func (this *lruCache) checkInvariants() error {
+	// Read lock as we only read the hash / linked-list, other reads can happen at the same time. Unlock when func returns
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
 
-	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
-	this.mutex.Lock()
-	defer this.mutex.Unlock()
+	if (this.head == nil) != (this.tail == nil) {
+		return fmt.Errorf("head is %v but tail is %v", this.head, this.tail)
+	}
+	if this.head != nil && (this.head.prev != nil || this.tail.next != nil) {
+		return errors.New("head has a prev or tail has a next")
+	}
 
-	// See if the cache contains the item
-	if item, containsKey := this.keyValMap[key]; containsKey {
+	// Walk forwards, remembering what we've seen so we don't go round forever if theres a loop
+	forward := map[*lruCacheItem]bool{}
+	size := 0
+	var last *lruCacheItem
+	for item := this.head; item != nil; item = item.next {
+		if forward[item] {
+			return fmt.Errorf("linked-list loops back round to %s", item.key)
+		}
+		if item.prev != last {
+			return fmt.Errorf("%s's prev doesn't point at the item before it", item.key)
+		}
+		if this.keyValMap[item.key] != item {
+			return fmt.Errorf("%s is in the linked-list but not the hash", item.key)
+		}
+		forward[item] = true
+		size += item.size
+		last = item
+	}
+	if last != this.tail {
+		return fmt.Errorf("walking forwards ends at %v rather than the tail", last)
+	}
+	if len(forward) != len(this.keyValMap) {
+		return fmt.Errorf("linked-list has %d items but the hash has %d", len(forward), len(this.keyValMap))
+	}
+
+	// Walking backwards should find the same items
+	backward := 0
+	for item := this.tail; item != nil; item = item.prev {
+		if backward++; backward > len(forward) || !forward[item] {
+			return errors.New("walking backwards finds items that walking forwards doesn't")
+		}
+	}
+	if backward != len(forward) {
+		return fmt.Errorf("walking backwards finds %d items but forwards finds %d", backward, len(forward))
+	}
+
+	if size + this.reserved != this.curSize {
+		return fmt.Errorf("items add up to a size of %d with %d reserved but the cache thinks its %d", size, this.reserved, 
+			this.curSize)
+	}
+
+	for i, item := range this.expiries {
+		if item.heapIndex != i || this.keyValMap[item.key] != item {
+			return fmt.Errorf("%s is at %d in the heap but has index %d, or isn't in the hash", item.key, i, item.heapIndex)
+		}
+	}
+	return nil
+}
+
+// unlock releases the full lock and then calls onEvict for any items evicted while it was held
+//
+// onEvict is called without the lock so it can use the cache without deadlocking
+func (this *lruCache) unlock() {
+	evicted := this.evicted
+	this.evicted = nil
+	this.mutex.Unlock()
+
+	for _, removed := range evicted {
+		this.onEvict(removed.item.key, removed.item.cacheItem, removed.reason)
+	}
+}
+
+// promote records an item accessed while only holding the read lock, so it can be moved to the head later
+//
+// Each Get claims a slot in pending with an atomic add so Gets don't have to wait on each other. Returns false if its
+// full and the move wasn't buffered
+func (this *lruCache) promote(item *lruCacheItem) bool {
+	if this.pendingCount.Load() >= maxPendingPromotions {
+		return false
+	}
+	if slot := this.pendingCount.Add(1) - 1; slot < maxPendingPromotions {
+		this.pending[slot].Store(item)
+		return true
+	}
+	return false
+}
+
+// drainPromotions applies the buffered moves and then moves item to the head, for a Get that found pending full
+//
+// It only takes the full lock if it can without waiting, so Get never blocks on a writer. If it can't then the move is
+// dropped, whoever holds the lock will apply the buffered ones
+func (this *lruCache) drainPromotions(item *lruCacheItem) {
+	if !this.mutex.TryLock() {
+		return
+	}
+	this.applyPromotions()
+	if this.keyValMap[item.key] == item {
 		item.Remove(this)
 		item.Add(this)
-		
-		return item.cacheItem, containsKey
 	}
-	return nil, false
+	this.unlock()
 }
 
-// Remove removes an item from the cache
-func (this *lruCache) Remove(key string) {
-	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
-	this.mutex.Lock()
-	defer this.mutex.Unlock()
+// applyPromotions moves items buffered by promote to the head, must be called with the full lock held
+//
+// Holding the full lock means no Get is half way through claiming / filling a slot. Items might have been removed since
+// they were buffered, so they're only moved if they're still the item in the hash
+func (this *lruCache) applyPromotions() {
+	count := this.pendingCount.Load()
+	if count > maxPendingPromotions {
+		count = maxPendingPromotions
+	}
 
-		// Check if item is present in cache
+	for i := int64(0); i < count; i++ {
+		item := this.pending[i].Swap(nil)
+		if item != nil && this.keyValMap[item.key] == item {
+			item.Remove(this)
+			item.Add(this)
+		}
+	}
+	this.pendingCount.Store(0)
+}
+
+// janitor removes expired items every interval until stop is closed
+func (this *lruCache) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			this.removeExpired()
+		case <-this.stop:
+			return
+		}
+	}
+}
+
+// removeExpired removes any expired items, taking them off the top of the expiries heap
+func (this *lruCache) removeExpired() {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	// Items get moved around the linked-list when they're accessed, the heap keeps them soonest to expire first so we can
+	// stop at the first one that hasn't expired
+	now := this.now()
+	for len(this.expiries) > 0 && this.expiries[0].expired(now) {
+		this.evict(this.expiries[0], ReasonExpired)
+	}
+
+	// Items left over from before InvalidateAll aren't in any order, so the linked-list is walked once per generation
+	if this.sweptGeneration != this.generation {
+		for item := this.tail; item != nil; {
+			prev := item.prev
+			if item.generation != this.generation {
+				this.evict(item, ReasonExpired)
+			}
+			item = prev
+		}
+		this.sweptGeneration = this.generation
+	}
+
+	for key, expires := range this.negatives {
+		if !now.Before(expires) {
+			delete(this.negatives, key)
+		}
+	}
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Cache Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// Add adds a CacheItem to the cache, it can be retrieved using Get and passing in the same key
+//
+// If the item already exists its moved from its current place in the linked-list to the head, and its size is re-read in
+// case its changed. If a different item exists under the key then its replaced
+// If the item doesn't curretly exist then its added to the head. This item will add to the current size of the cache. If
+// the current size > max size then tail items are removed until it falls under max size
+// If the item is bigger than max size then an error is returned. Any different item under the key is left as it was,
+// but if its the same item (its grown since it was added) then its removed
+func (this *lruCache) Add(k string, v CacheItem) error {
+	_, _, err := this.AddReturning(k, v)
+	return err
+}
+
+// AddReturning adds a CacheItem to the cache like Add, and returns the item it replaced
+//
+// If an item was already present under the key then it's returned with existed set to true, even if its the same item.
+// If the item can't be added then nil, false is returned along with the error
+func (this *lruCache) AddReturning(k string, v CacheItem) (CacheItem, bool, error) {
+	return this.addDefault(k, v, this.sizeOf(v))
+}
+
+// AddWithCost adds a CacheItem to the cache like Add, but with cost used as its size instead of v.Size()
+func (this *lruCache) AddWithCost(k string, v CacheItem, cost int) error {
+	_, _, err := this.addDefault(k, v, cost)
+	return err
+}
+
+// AddIfAbsent adds a CacheItem to the cache like Add, but only if nothing is stored under the key
+//
+// Returns true if the item was added. If the key's already present then false is returned and the existing item is left
+// where it is. An expired item counts as absent, its removed and replaced
+func (this *lruCache) AddIfAbsent(k string, v CacheItem) (bool, error) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	if item, present := this.keyValMap[k]; present {
+		if !this.stale(item) {
+			return false, nil
+		}
+		this.evict(item, ReasonExpired)
+	}
+	_, _, err := this.store(k, v, this.sizeOf(v), this.ttl)
+	return err == nil, err
+}
+
+// Replace replaces the item stored under the key like Add, but only if there is one
+//
+// Returns true if the item was replaced, its moved to the head and the size of the cache changes to the new item's. If
+// the key's missing (or its item has expired) then false is returned and nothing is added
+func (this *lruCache) Replace(k string, v CacheItem) (bool, error) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	item, present := this.keyValMap[k]
+	if !present {
+		return false, nil
+	}
+	if this.stale(item) {
+		this.evict(item, ReasonExpired)
+		return false, nil
+	}
+	_, _, err := this.store(k, v, this.sizeOf(v), this.ttl)
+	return err == nil, err
+}
+
+// Increment adds delta to the IntCacheItem stored under the key and returns the new value
+//
+// If the key's missing (or its item has expired) then an IntCacheItem of delta is added. The item is moved to the head
+// like a Get but its ttl carries on from when it was added, so a counter with a ttl counts over a fixed window. Returns
+// ErrNotInt if the item under the key is something else
+func (this *lruCache) Increment(k string, delta int64) (int64, error) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	item, present := this.keyValMap[k]
+	if present && this.stale(item) {
+		this.evict(item, ReasonExpired)
+		present = false
+	}
+	if !present {
+		n := IntCacheItem(delta)
+		if _, _, err := this.store(k, n, this.sizeOf(n), this.ttl); err != nil {
+			return 0, err
+		}
+		return delta, nil
+	}
+
+	n, isInt := item.cacheItem.(IntCacheItem)
+	if !isInt {
+		return 0, ErrNotInt
+	}
+	n += IntCacheItem(delta)
+	item.cacheItem = n
+	if !this.insertionOrder {
+		item.Remove(this)
+		item.Add(this)
+	}
+	return int64(n), nil
+}
+
+// Decrement takes delta off the IntCacheItem stored under the key and returns the new value, see Increment
+func (this *lruCache) Decrement(k string, delta int64) (int64, error) {
+	return this.Increment(k, -delta)
+}
+
+// Update passes the item stored under the key to fn and stores what it returns, all while holding the lock
+//
+// fn is given the item and true, or nil and false if the key's missing (or its item has expired). If it returns keep as
+// true then its item is stored like Add, with its size re-read and the ttl restarted, otherwise the key is removed. As
+// nothing else can use the cache until fn returns it should be quick, and it mustn't call the cache itself or it'll
+// deadlock. Returns the error Add would if the new item can't be stored
+func (this *lruCache) Update(k string, fn func(existing CacheItem, found bool) (CacheItem, bool)) error {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	item, present := this.keyValMap[k]
+	if present && this.stale(item) {
+		this.evict(item, ReasonExpired)
+		present = false
+	}
+	var existing CacheItem
+	if present {
+		existing = item.cacheItem
+	}
+
+	updated, keep := fn(existing, present)
+	if !keep {
+		if present {
+			this.evict(item, ReasonManual)
+		}
+		return nil
+	}
+	_, _, err := this.store(k, updated, this.sizeOf(updated), this.ttl)
+	return err
+}
+
+// Freeze stops items being evicted to make room until Unfreeze is called
+//
+// Adds still store items, so the cache can go over its max size (and max items) by as much as is added while its
+// frozen. The only limit is memory, a memory guarded cache still shrinks if the heap gets too big. Items that are bigger
+// than the max size on their own are still rejected, and expired items are still removed
+func (this *lruCache) Freeze() {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+
+	this.frozen = true
+}
+
+// Reserve makes room for an item of the size passed in before its been created, and holds the space until its added
+//
+// Tail items are evicted until bytes fits under the max size, and the space counts towards Size so other Adds don't take
+// it. The next Add of an item no bigger than the reservation uses the space up, otherwise release gives it back. Calling
+// release after the Add (or more than once) does nothing, so it can be deferred. If bytes is more than the max size then
+// nothing is evicted and a release that does nothing is returned with ok false. Caches created with OverflowReject
+// don't evict to make room either, they return ok false if bytes is more than the space left
+func (this *lruCache) Reserve(bytes int) (func(), bool) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+	this.rampUp()
+
+	if err := sizeError(bytes, this.maxSize); err != nil {
+		return func() { }, false
+	}
+	if this.overflow == OverflowReject && bytes > this.maxSize - this.curSize {
+		return func() { }, false
+	}
+	if !this.frozen {
+		this.evictDownTo(this.maxSize - bytes)
+	}
+
+	reserved := &reservation { bytes: bytes }
+	this.reservations = append(this.reservations, reserved)
+	this.reserved += bytes
+	this.curSize += bytes
+	return func() {
+		// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+		this.mutex.Lock()
+		defer this.unlock()
+
+		this.release(reserved)
+	}, true
+}
+
+// Pin stops the item under the key being evicted to make room, until Unpin is called. Nothing happens if its missing
+//
+// Eviction walks past pinned items to the next one along, so pinning lots of items near the tail makes evicting slower.
+// If pinned items add up to more than the max size then the cache goes over it, as there's nothing else to evict. The
+// pin stays if the item's replaced with Add, and goes if its removed or expires
+func (this *lruCache) Pin(key string) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+
+	if item, present := this.keyValMap[key]; present && !this.stale(item) {
+		if this.pinned == nil {
+			this.pinned = make(map[string]bool)
+		}
+		this.pinned[key] = true
+	}
+}
+
+// Unpin lets the item under the key be evicted again. If the cache is over its max size then its brought back under by
+// the next Add, rather than straight away
+func (this *lruCache) Unpin(key string) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+
+	delete(this.pinned, key)
+}
+
+// Unfreeze lets items be evicted again, removing tail items straight away until the cache is back within its limits
+func (this *lruCache) Unfreeze() {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	this.frozen = false
+	this.evictDownTo(this.maxSize)
+	for this.maxItems > 0 && len(this.keyValMap) > this.maxItems && this.victim() != nil {
+		this.evict(this.victim(), ReasonCapacity)
+	}
+}
+
+// SetDefaultTTL changes the ttl given to items added from now on, items already in the cache keep the ttl they were
+// added with. A ttl of 0 means items added from now on never expire
+func (this *lruCache) SetDefaultTTL(ttl time.Duration) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.ttl = ttl
+}
+
+// AddWithTTL adds a CacheItem to the cache like Add, but it expires after ttl instead of the cache's ttl
+//
+// A ttl of 0 means the item never expires, even if the cache's items do. Adding the item again with Add goes back to the
+// cache's ttl, Touch restarts the item's own ttl. Expired items are removed when they're next accessed, or by the janitor
+// if the cache has one
+func (this *lruCache) AddWithTTL(k string, v CacheItem, ttl time.Duration) error {
+	_, _, err := this.add(k, v, this.sizeOf(v), ttl)
+	return err
+}
+
+// add implements AddWithTTL, and Add, AddReturning and AddWithCost through addDefault. size is what the item counts as
+// towards the size of the cache and ttl is how long its kept for
+func (this *lruCache) add(k string, v CacheItem, size int, ttl time.Duration) (CacheItem, bool, error) {
+
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	return this.store(k, v, size, ttl)
+}
+
+// addDefault is add with the cache's ttl, its read once the lock is held as SetDefaultTTL can change it
+func (this *lruCache) addDefault(k string, v CacheItem, size int) (CacheItem, bool, error) {
+
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	return this.store(k, v, size, this.ttl)
+}
+
+// store does the work for add, it must be called with the full lock held
+func (this *lruCache) store(k string, v CacheItem, size int, ttl time.Duration) (CacheItem, bool, error) {
+	// Nothing would be able to use a nil item, leave whatever's under the key as it is
+	if v == nil {
+		return nil, false, ErrNilItem
+	}
+
+	// The key's known to exist now, even if the item turns out to be too big
+	delete(this.negatives, k)
+	this.rampUp()
+
+	item, present := this.keyValMap[k]
+
+	// Can't store if it already exceeds max size
+	if err := this.checkSize(size); err != nil {
+		if present && v == item.cacheItem {
+			this.evict(item, ReasonCapacity)
+		}
+		return nil, false, err
+	}
+
+	// Only the space left can be used if the cache doesn't evict to make room, less whatever's being replaced. Space held
+	// for the item by a reservation is its to use
+	if this.overflow == OverflowReject {
+		room := this.maxSize - this.curSize
+		if present {
+			room += item.size
+		}
+		if r := this.reservationFor(size); r != nil {
+			room += r.bytes
+		}
+		if size > room || (!present && this.maxItems > 0 && len(this.keyValMap) >= this.maxItems) {
+			return nil, false, ErrCacheFull
+		}
+	}
+
+	// If we already contain item then remove from linked-list (value may be different). Its size is taken off so the
+	// eviction below makes room for the new size
+	if present {
+		item.Remove(this)
+	}
+
+	// Give memory back if the process is running out
+	if this.memGuard != nil && this.memGuard.overLimit(this.now()) {
+		this.evictDownTo(this.curSize * 3 / 4)
+	}
+
+	// Remove tail items until we're under max size, the item can have the space reserved for it
+	this.claimReservation(size)
+	this.evictFor(size)
+
+	// Values are the same so we can just move to the start of the array, with its new size
+	if present && v == item.cacheItem {
+		item.added = this.now()
+		item.size = size
+		item.ttl = ttl
+		item.generation = this.generation
+		item.Add(this)
+		this.retrack(item)
+		this.stats.adds.Add(1)
+		this.events.add(k)
+		return v, true, nil
+	}
+
+	// Create item
+	lruItem := &lruCacheItem { cacheItem: v, key: k, added: this.now(), size: size, ttl: ttl, generation: this.generation }
+	lruItem.Add(this)
+	this.track(lruItem)
+	if present {
+		this.untrack(item)
+	}
+	this.stats.adds.Add(1)
+	this.events.add(k)
+	if present {
+		return item.cacheItem, true, nil
+	}
+	return nil, false, nil
+}
+
+// Get retrieves an item from the cache if its present. Also, because its been accessed its moved to the head of the queue
+//
+// If item is present then the item, true is returned. Otherwise, nil, false. If the item has expired its removed and
+// nil, false is returned
+func (this *lruCache) Get(key string) (CacheItem, bool) {
+	item, _, present := this.get(key)
+	return item, present
+}
+
+// GetOrErr retrieves an item like Get, returning ErrNotFound if it isn't present or has expired
+func (this *lruCache) GetOrErr(key string) (CacheItem, error) {
+	return getOrErr(this.Get(key))
+}
+
+// GetWithRank retrieves an item like Get, along with how close it was to being evicted
+//
+// rankFromTail is the item's position counting from the tail before this Get moved it, 0 means it was next to be evicted.
+// Its found by walking from the tail so it costs O(rankFromTail), cheap for the items close to eviction that callers want
+// to refresh but slow for items near the head of a big cache. Read optimized caches apply any pending moves first
+func (this *lruCache) GetWithRank(key string) (CacheItem, int, bool) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	item, present := this.keyValMap[key]
+	if present && this.stale(item) {
+		this.evict(item, ReasonExpired)
+		present = false
+	}
+	if !present {
+		this.stats.get(false)
+		this.events.get(key, false)
+		return nil, 0, false
+	}
+
+	rank := 0
+	for walk := this.tail; walk != item; walk = walk.prev {
+		rank++
+	}
+	if !this.insertionOrder {
+		item.Remove(this)
+		item.Add(this)
+	}
+	this.stats.get(true)
+	this.events.get(key, true)
+	return item.cacheItem, rank, true
+}
+
+// GetWithAge retrieves an item like Get, along with how long its been in the cache
+//
+// The age is the time since the item was last added, or touched as that restarts its ttl. It counts as an access so the
+// item is moved to the head of the queue like Get
+func (this *lruCache) GetWithAge(key string) (CacheItem, time.Duration, bool) {
+	item, added, present := this.get(key)
+	if !present {
+		return nil, 0, false
+	}
+	return item, this.now().Sub(added), true
+}
+
+// get implements Get and GetWithAge, it returns when the item was added as well as the item itself
+func (this *lruCache) get(key string) (CacheItem, time.Time, bool) {
+	if this.readOptimized {
+		return this.getReadOptimized(key)
+	}
+
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+
+	// See if the cache contains the item
+	if item, containsKey := this.keyValMap[key]; containsKey {
+		// Expired items are removed and treated as missing
+		if this.stale(item) {
+			this.evict(item, ReasonExpired)
+			this.stats.get(false)
+			this.events.get(key, false)
+			return nil, time.Time{}, false
+		}
+
+		// FIFO caches leave the item where it is
+		if !this.insertionOrder {
+			item.Remove(this)
+			item.Add(this)
+		}
+		
+		this.stats.get(true)
+		this.events.get(key, true)
+		return item.cacheItem, item.added, containsKey
+	}
+	this.stats.get(false)
+	this.events.get(key, false)
+	return nil, time.Time{}, false
+}
+
+// getReadOptimized is Get for read optimized caches, it only takes the read lock and buffers the move to the head
+//
+// If the item has expired then we need the full lock to remove it, so the read lock is swapped for it
+func (this *lruCache) getReadOptimized(key string) (CacheItem, time.Time, bool) {
+	this.mutex.RLock()
+	item, containsKey := this.keyValMap[key]
+	if containsKey && !this.stale(item) {
+		buffered := this.promote(item)
+		cacheItem, added := item.cacheItem, item.added
+		this.mutex.RUnlock()
+
+		if !buffered {
+			this.drainPromotions(item)
+		}
+
+		this.stats.get(true)
+		this.events.get(key, true)
+		return cacheItem, added, true
+	}
+	this.mutex.RUnlock()
+
+	// Expired, check its still the same item now we have the full lock before removing it
+	if containsKey {
+		this.mutex.Lock()
+		if this.keyValMap[key] == item && this.stale(item) {
+			this.evict(item, ReasonExpired)
+		}
+		this.unlock()
+	}
+
+	this.stats.get(false)
+	this.events.get(key, false)
+	return nil, time.Time{}, false
+}
+
+// Remove removes an item from the cache
+func (this *lruCache) Remove(key string) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+		// Check if item is present in cache
 	lruCacheItem, present := this.keyValMap[key]
 	if present {
-		lruCacheItem.Remove(this)
+		this.evict(lruCacheItem, ReasonManual)
+	}
+	delete(this.negatives, key)
+}
+
+// NextEviction returns the key of the item closest to the tail that isn't pinned, the one that would be evicted next to
+// make room, without removing it
+//
+// It might be an item that's expired but hasn't been removed yet, those are evicted like any other. If the cache is
+// empty (or everything's pinned) or its frozen, so nothing would be evicted, then "", false is returned. Read optimized
+// caches apply any pending moves first
+func (this *lruCache) NextEviction() (string, bool) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	victim := this.victim()
+	if victim == nil || this.frozen {
+		return "", false
+	}
+	return victim.key, true
+}
+
+// Evict removes the tail item and returns it, so the least recently used item for LRU caches and the oldest for FIFO
+//
+// Pinned items are skipped over, expired items at the tail are removed and skipped over. It counts as removing the item
+// rather than evicting it, so it doesn't appear in Stats and onEvict is only called if the cache was created to include
+// removes
+func (this *lruCache) Evict() (string, CacheItem, bool) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	for item := this.victim(); item != nil; item = this.victim() {
+		if this.stale(item) {
+			this.evict(item, ReasonExpired)
+			continue
+		}
+		this.evict(item, ReasonManual)
+		return item.key, item.cacheItem, true
+	}
+	return "", nil, false
+}
+
+// Len returns the number of items currently stored in the cache
+//
+// Items that have been evicted to make room for others aren't included
+func (this *lruCache) Len() int {
+	// Read lock as we only read the hash / linked-list, other reads can happen at the same time. Unlock when func returns
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	return len(this.keyValMap)
+}
+
+// Size returns the total size of all the items currently stored in the cache, plus any space held by Reserve
+func (this *lruCache) Size() int {
+	// Read lock as we only read the hash / linked-list, other reads can happen at the same time. Unlock when func returns
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	return this.curSize
+}
+
+// Cap returns the maximum total size the cache will hold before it starts removing items
+//
+// If the cache is ramping up then its the max size the next Add will use
+func (this *lruCache) Cap() int {
+	// Read lock as we only read the hash / linked-list, other reads can happen at the same time. Unlock when func returns
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	if this.ramp != nil {
+		capacity, _ := this.ramp.capacity()
+		return capacity
+	}
+	return this.maxSize
+}
+
+// Clear removes all items from the cache
+//
+// The hash is replaced rather than emptied and head/tail are dropped, so the linked-list is left for the garbage collector
+func (this *lruCache) Clear() {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.applyPromotions()
+	this.keyValMap = make(map[string]*lruCacheItem)
+	this.head = nil
+	this.tail = nil
+	this.curSize = this.reserved
+	this.negatives = nil
+	this.expiries = nil
+	this.pinned = nil
+}
+
+// InvalidateAll makes every item currently in the cache stale, so they're all treated as missing
+//
+// It doesn't walk the cache so it takes the same time however many items there are. Stale items still take up space
+// until they're next accessed, or the janitor removes them if the cache has one. Negative entries are dropped too
+func (this *lruCache) InvalidateAll() {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.generation++
+	this.negatives = nil
+}
+
+// Snapshot returns a copy of the cache as it is now, with the same items in the same order and the same limits
+//
+// Gets on the snapshot all see the cache as it was when it was taken, whatever's added or removed since. The items are
+// copied while holding the read lock so its O(n) once, after that the snapshot doesn't hold up the cache at all. The
+// items themselves aren't copied, if they're changed in place then the snapshot sees the change. Its a separate cache,
+// adding to it doesn't change this one. It doesn't have the callback, events or janitor this cache might have
+func (this *lruCache) Snapshot() Cache {
+	// Read lock as we only read the hash / linked-list, other reads can happen at the same time. Unlock when func returns
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	snapshot := &lruCache { keyValMap: make(map[string]*lruCacheItem, len(this.keyValMap)), maxSize: this.maxSize, 
+		maxItems: this.maxItems, maxItemSize: this.maxItemSize, sizeFunc: this.sizeFunc, 
+		lowWaterPercent: this.lowWaterPercent, insertionOrder: this.insertionOrder, ttl: this.ttl, 
+		readOptimized: this.readOptimized, ramp: this.ramp, overflow: this.overflow, clock: this.clock }
+
+	// Tail first so each copy can go on the head
+	for item := this.tail; item != nil; item = item.prev {
+		if this.stale(item) {
+			continue
+		}
+		copied := &lruCacheItem { cacheItem: item.cacheItem, key: item.key, added: item.added, size: item.size, ttl: item.ttl }
+		copied.Add(snapshot)
+		snapshot.track(copied)
+		if this.pinned[item.key] {
+			if snapshot.pinned == nil {
+				snapshot.pinned = make(map[string]bool)
+			}
+			snapshot.pinned[item.key] = true
+		}
+	}
+	return snapshot
+}
+
+// Keys returns the keys of all the items currently stored in the cache
+//
+// Keys are returned head first, so most recently used first and next to be removed last
+func (this *lruCache) Keys() []string {
+	// Read lock as we only read the hash / linked-list, other reads can happen at the same time. Unlock when func returns
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	keys := make([]string, 0, len(this.keyValMap))
+	for item := this.head; item != nil; item = item.next {
+		keys = append(keys, item.key)
+	}
+	return keys
+}
+
+// String returns the size and max size of the cache, and each key with its size head first, e.g. for logging when a
+// test fails
+//
+// It looks like "size 30/100, 3 items [c:10 b:10 a:10]". Nothing's counted as an access or moved, so moves still
+// pending in a read optimized cache aren't shown
+func (this *lruCache) String() string {
+	// Read lock as we only read the hash / linked-list, other reads can happen at the same time. Unlock when func returns
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	var dump strings.Builder
+	fmt.Fprintf(&dump, "size %d/%d, %d items [", this.curSize, this.maxSize, len(this.keyValMap))
+	for item := this.head; item != nil; item = item.next {
+		if item != this.head {
+			dump.WriteString(" ")
+		}
+		fmt.Fprintf(&dump, "%s:%d", item.key, item.size)
+	}
+	dump.WriteString("]")
+	return dump.String()
+}
+
+// GetAll returns a snapshot of every unexpired item in the cache, items aren't moved in the queue
+func (this *lruCache) GetAll() map[string]CacheItem {
+	// Read lock as we only read the hash / linked-list, other reads can happen at the same time. Unlock when func returns
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	all := make(map[string]CacheItem, len(this.keyValMap))
+	for key, item := range this.keyValMap {
+		if !this.stale(item) {
+			all[key] = item.cacheItem
+		}
+	}
+	return all
+}
+
+// Close stops the janitor if the cache has one, and stops sending events closing the Events channel. It's safe to call
+// more than once
+func (this *lruCache) Close() error {
+	this.closeOnce.Do(func() {
+		if this.stop != nil {
+			close(this.stop)
+		}
+		this.events.close()
+	})
+	return nil
+}
+
+// Events returns the channel CacheEvents are sent on as things happen in the cache, nil if the cache wasn't created with
+// CreateLRUCacheWithEvents
+//
+// Its closed by Close. Sending never blocks the cache, if the buffer is full then the oldest event is dropped
+func (this *lruCache) Events() <-chan CacheEvent {
+	return this.events.channel()
+}
+
+// Stats returns counts of the hits, misses, evictions, expirations and adds since the cache was created
+func (this *lruCache) Stats() Stats {
+	return this.stats.snapshot()
+}
+
+// Peek retrieves an item from the cache if its present. Unlike Get, the item isn't moved in the queue
+//
+// If item is present then the item, true is returned. Otherwise, nil, false. Expired items are left for Get or the janitor
+// to remove, but nil, false is still returned
+func (this *lruCache) Peek(key string) (CacheItem, bool) {
+	// Read lock as we only read the hash / linked-list, other reads can happen at the same time. Unlock when func returns
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	if item, containsKey := this.keyValMap[key]; containsKey && !this.stale(item) {
+		return item.cacheItem, true
+	}
+	return nil, false
+}
+
+// Contains returns true if the item is present in the cache. Like Peek, the item isn't moved in the queue
+//
+// Expired items are treated as not present
+func (this *lruCache) Contains(key string) bool {
+	// Read lock as we only read the hash / linked-list, other reads can happen at the same time. Unlock when func returns
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	item, containsKey := this.keyValMap[key]
+	return containsKey && !this.stale(item)
+}
+
+// GetOrAdd retrieves an item from the cache if its present, otherwise it calls compute and adds the item it returns
+//
+// The cache isn't locked while compute runs, but concurrent callers for the same key wait for it rather than calling
+// compute themselves. If compute returns an error then nothing is added and the error is returned
+func (this *lruCache) GetOrAdd(key string, compute func() (CacheItem, error)) (CacheItem, error) {
+	return getOrAdd(this, &this.flights, key, compute)
+}
+
+// GetOrAddContext works like GetOrAdd, but gives up if ctx is done before it has the item
+//
+// ctx is passed on to compute. If ctx is done by the time compute returns then nothing is added and ctx's error is
+// returned, so a cancelled compute never leaves a partial item behind
+func (this *lruCache) GetOrAddContext(ctx context.Context, key string, compute func(ctx context.Context) (CacheItem, error)) (CacheItem, error) {
+	return getOrAddContext(ctx, this, &this.flights, key, compute)
+}
+
+// GetStaleWhileRevalidate retrieves an item, serving it stale and refreshing it in the background once its older than
+// ttl. Its only waited for if its older than ttl + staleWindow or missing
+//
+// Refreshes share the flights used by GetOrAdd, so only one runs at a time for a key
+func (this *lruCache) GetStaleWhileRevalidate(key string, ttl, staleWindow time.Duration, refresh func() (CacheItem, error)) (CacheItem, bool) {
+	return getStaleWhileRevalidate(this, &this.flights, key, ttl, staleWindow, refresh)
+}
+
+// UpdateSize re-reads the Size of an item that's changed since it was added, and updates the size of the cache to match
+//
+// The item is moved to the head and tail items are removed until the cache is back under max size. If the item is now
+// bigger than max size (or max item size) then its evicted and an error is returned. Nothing happens if the item isn't
+// present. Caches created with OverflowReject don't evict to make room, if the item's grown by more than the space left
+// then ErrCacheFull is returned and it keeps its old size
+func (this *lruCache) UpdateSize(key string) error {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	item, present := this.keyValMap[key]
+	if !present {
+		return nil
+	}
+
+	// Can't store if it now exceeds max size
+	size := this.sizeOf(item.cacheItem)
+	if err := this.checkSize(size); err != nil {
+		this.evict(item, ReasonCapacity)
+		return err
+	}
+	if this.overflow == OverflowReject && size - item.size > this.maxSize - this.curSize {
+		return ErrCacheFull
+	}
+
+	// Take it out with its old size, make room and put it back with the new one
+	item.Remove(this)
+	this.evictFor(size)
+	item.size = size
+	item.Add(this)
+	return nil
+}
+
+// Resize changes the maximum total size of the cache
+//
+// If the cache is now over the new size then tail items are removed until it isn't. Shrinking below the size of an item
+// means it'll be removed, even if it's the only item
+func (this *lruCache) Resize(newMax int) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	this.maxSize = newMax
+	this.ramp = nil
+	if !this.frozen {
+		this.evictDownTo(newMax)
+	}
+}
+
+// Trim removes tail items until the cache's size is no more than targetBytes, without changing its max size
+//
+// The most recently used items are kept. Trim(0) empties the cache, including items with a size of 0, apart from any
+// pinned items. Trimmed items count as evictions and are passed to onEvict, even if the cache is frozen
+func (this *lruCache) Trim(targetBytes int) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	for this.curSize > targetBytes || targetBytes <= 0 {
+		victim := this.victim()
+		if victim == nil {
+			break
+		}
+		this.evict(victim, ReasonCapacity)
+	}
+}
+
+// RemoveFunc removes every item that pred returns true for, they count as removed with Remove for onEvict
+//
+// pred is called with the cache locked so it mustn't call back into the cache
+func (this *lruCache) RemoveFunc(pred func(key string, item CacheItem) bool) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	// Grab next before we remove so we can carry on walking
+	for item := this.head; item != nil; {
+		next := item.next
+		if pred(item.key, item.cacheItem) {
+			this.evict(item, ReasonManual)
+		}
+		item = next
+	}
+}
+
+// ForEach calls fn for every item in the cache head first, until fn returns false. Items aren't moved in the queue
+//
+// Expired items are skipped. fn is called with the cache locked so it mustn't call back into the cache
+func (this *lruCache) ForEach(fn func(key string, item CacheItem) bool) {
+	// Read lock as we only read the hash / linked-list, other reads can happen at the same time. Unlock when func returns
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	for item := this.head; item != nil; item = item.next {
+		if this.stale(item) {
+			continue
+		}
+		if !fn(item.key, item.cacheItem) {
+			return
+		}
+	}
+}
+
+// AddNegative records that key is known to be absent for ttl, removing any item stored under it
+//
+// Negative entries are kept outside the linked-list so they don't take up room or push items out. Expired ones are
+// dropped when they're next looked up, or by the janitor if the cache has one
+func (this *lruCache) AddNegative(key string, ttl time.Duration) {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	if item, present := this.keyValMap[key]; present {
+		this.evict(item, ReasonManual)
+	}
+	if this.negatives == nil {
+		this.negatives = make(map[string]time.Time)
+	}
+	this.negatives[key] = this.now().Add(ttl)
+}
+
+// GetWithNegative retrieves an item like Get, but also says whether the key has been cached as absent
+//
+// A negative hit doesn't count as a hit or a miss in Stats, otherwise the lookup is just a Get
+func (this *lruCache) GetWithNegative(key string) (CacheItem, bool, bool) {
+	// Lock method so negatives can be accessed safely from multiple go-routines. Expired entries are deleted
+	this.mutex.Lock()
+	expires, negative := this.negatives[key]
+	if negative && this.now().Before(expires) {
+		this.mutex.Unlock()
+		return nil, false, true
+	}
+	if negative {
+		delete(this.negatives, key)
+	}
+	this.mutex.Unlock()
+
+	item, found := this.Get(key)
+	return item, found, false
+}
+
+// Touch moves an item to the head of the queue and restarts its ttl, without retrieving it or counting as a hit
+//
+// FIFO caches leave the item where it is, only the ttl is restarted. Returns false if the key is missing or the item has
+// expired, expired items are removed rather than brought back
+func (this *lruCache) Touch(key string) bool {
+	// Lock method so hash ad linked-list can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.unlock()
+	this.applyPromotions()
+
+	item, present := this.keyValMap[key]
+	if !present {
+		return false
+	}
+	if this.stale(item) {
+		this.evict(item, ReasonExpired)
+		return false
+	}
+
+	item.added = this.now()
+	this.retrack(item)
+	if !this.insertionOrder {
+		item.Remove(this)
+		item.Add(this)
 	}
+	return true
 }
\ No newline at end of file
@@ -0,0 +1,97 @@
+package memcache
+
+import (
+	"testing"
+	"time"
+)
+
+// evictRecord is a single call to an EvictCallback
+type evictRecord struct {
+	key string
+	item CacheItem
+	reason EvictReason
+}
+
+func TestLRUCacheWithCallback(t *testing.T) {
+	var evicted []evictRecord
+	var cache Cache
+	cache = CreateLRUCacheWithCallback(20, func(key string, item CacheItem, reason EvictReason) {
+		// Callback is run without the lock held so it can use the cache
+		cache.Len()
+		evicted = append(evicted, evictRecord{key, item, reason})
+	}, false)
+
+	a := &DummyCacheItem{DummySize: 10}
+	cache.Add("a", a)
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// Nothing evicted yet
+	if len(evicted) != 0 {
+		t.Error("Expected no evictions, got", evicted)
+	}
+
+	// "a" is the tail so should be evicted, and passed to the callback
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	if len(evicted) != 1 || evicted[0] != (evictRecord{"a", a, ReasonCapacity}) {
+		t.Error("Expected a to be evicted for capacity, got", evicted)
+	}
+
+	// Remove shouldn't call the callback as we haven't included it
+	cache.Remove("b")
+	if len(evicted) != 1 {
+		t.Error("Expected Remove not to call the callback, got", evicted)
+	}
+}
+
+func TestLRUCacheWithCallbackIncludeRemove(t *testing.T) {
+	var evicted []evictRecord
+	cache := CreateLRUCacheWithCallback(20, func(key string, item CacheItem, reason EvictReason) {
+		evicted = append(evicted, evictRecord{key, item, reason})
+	}, true)
+
+	a := &DummyCacheItem{DummySize: 10}
+	cache.Add("a", a)
+	cache.Remove("a")
+	if len(evicted) != 1 || evicted[0] != (evictRecord{"a", a, ReasonManual}) {
+		t.Error("Expected a to be passed to the callback as a manual removal, got", evicted)
+	}
+
+	// Removing something that isn't there shouldn't call it
+	cache.Remove("a")
+	if len(evicted) != 1 {
+		t.Error("Expected no more calls, got", evicted)
+	}
+}
+
+func TestLRUCacheWithCallbackExpired(t *testing.T) {
+	var evicted []evictRecord
+	cache := CreateLRUCacheWithCallback(MaxSize, func(key string, item CacheItem, reason EvictReason) {
+		evicted = append(evicted, evictRecord{key, item, reason})
+	}, false).(*lruCache)
+	cache.ttl = TestTTL
+
+	a := &DummyCacheItem{DummySize: 10}
+	b := &DummyCacheItem{DummySize: 10}
+	cache.Add("a", a)
+	cache.Add("b", b)
+	time.Sleep(TestTTL * 2)
+
+	// Expired on Get
+	if _, present := cache.Get("a"); present {
+		t.Error("a should have expired")
+	}
+	if len(evicted) != 1 || evicted[0] != (evictRecord{"a", a, ReasonExpired}) {
+		t.Error("Expected a to be passed to the callback as expired, got", evicted)
+	}
+
+	// Expired by the janitor
+	cache.removeExpired()
+	if len(evicted) != 2 || evicted[1] != (evictRecord{"b", b, ReasonExpired}) {
+		t.Error("Expected b to be passed to the callback as expired, got", evicted)
+	}
+
+	// Expirations aren't capacity evictions
+	if cache.Stats().Evictions != 0 {
+		t.Error("Expected no evictions in stats, got", cache.Stats().Evictions)
+	}
+}
@@ -0,0 +1,67 @@
+package memcache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCounterCache(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(CounterCache)
+
+	// Missing counters start at delta
+	if n, err := cache.Increment("hits", 5); n != 5 || err != nil {
+		t.Error("Expected 5, got", n, err)
+	}
+	if n, err := cache.Decrement("hits", 2); n != 3 || err != nil {
+		t.Error("Expected 3, got", n, err)
+	}
+	if n, err := cache.Decrement("misses", 1); n != -1 || err != nil {
+		t.Error("Expected -1, got", n, err)
+	}
+	if item, _ := cache.Get("hits"); item != NewInt(3) || cache.Size() != 16 {
+		t.Error("Expected hits to be stored as 3, got", item, "with size", cache.Size())
+	}
+
+	// Anything other than an IntCacheItem can't be incremented, and is left alone
+	cache.Add("name", NewString("text"))
+	if n, err := cache.Increment("name", 1); n != 0 || !errors.Is(err, ErrNotInt) {
+		t.Error("Expected ErrNotInt, got", n, err)
+	}
+	if item, _ := cache.Get("name"); item != NewString("text") {
+		t.Error("Expected name to be unchanged, got", item)
+	}
+}
+
+func TestCounterCacheConcurrent(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(CounterCache)
+
+	// No increments should be lost
+	const goroutines = 50
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Increment("count", 1)
+		}()
+	}
+	wg.Wait()
+	if item, _ := cache.Get("count"); item != NewInt(goroutines) {
+		t.Error("Expected", goroutines, "got", item)
+	}
+}
+
+func TestCounterCacheTTL(t *testing.T) {
+	cache := CreateLRUCacheWithTTL(MaxSize, TestTTL).(CounterCache)
+
+	// Incrementing shouldn't restart the ttl, so the count is over a fixed window
+	cache.Increment("requests", 1)
+	time.Sleep(TestTTL / 2)
+	cache.Increment("requests", 1)
+	time.Sleep(TestTTL * 3 / 4)
+	if n, _ := cache.Increment("requests", 1); n != 1 {
+		t.Error("Expected the counter to have expired and start again, got", n)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,68 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestLRUCacheEvents(t *testing.T) {
+	cache := CreateLRUCacheWithEvents(20, 10).(ObservableCache)
+	defer cache.Close()
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Get("a")
+	cache.Get("missing")
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	cache.Remove("a")
+
+	expected := []CacheEvent {
+		{ Type: EventAdd, Key: "a" },
+		{ Type: EventAdd, Key: "b" },
+		{ Type: EventGet, Key: "a", Hit: true },
+		{ Type: EventGet, Key: "missing" },
+		{ Type: EventEvict, Key: "b" },
+		{ Type: EventAdd, Key: "c" },
+		{ Type: EventRemove, Key: "a" },
+	}
+	for _, want := range expected {
+		if got := <-cache.Events(); got != want {
+			t.Error("Expected", want, "got", got)
+		}
+	}
+	select {
+	case event := <-cache.Events():
+		t.Error("Expected no more events, got", event)
+	default:
+	}
+}
+
+func TestLRUCacheEventsSlowConsumer(t *testing.T) {
+	cache := CreateLRUCacheWithEvents(MaxSize, 3).(ObservableCache)
+
+	// Nobody's reading, the cache mustn't block and only the newest events should be kept
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 1})
+	}
+	for _, key := range []string{"7", "8", "9"} {
+		if event := <-cache.Events(); event.Key != key {
+			t.Error("Expected the event for", key, "got", event)
+		}
+	}
+
+	// Closing stops the events and closes the channel
+	cache.Close()
+	cache.Add("closed", &DummyCacheItem{DummySize: 1})
+	if _, open := <-cache.Events(); open {
+		t.Error("Expected the channel to be closed")
+	}
+	cache.Close()
+}
+
+func TestLRUCacheWithoutEvents(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(ObservableCache)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	if cache.Events() != nil {
+		t.Error("Expected no events channel for a cache created without events")
+	}
+}
\ No newline at end of file
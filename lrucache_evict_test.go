@@ -0,0 +1,150 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEvictableCache(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(EvictableCache)
+	items := map[string]CacheItem{}
+	for _, key := range []string{"a", "b", "c"} {
+		items[key] = &DummyCacheItem{DummySize: 10}
+		cache.Add(key, items[key])
+	}
+
+	// Accessing "a" moves it to the head, so it should come out last
+	cache.Get("a")
+	for _, expected := range []string{"b", "c", "a"} {
+		key, item, ok := cache.Evict()
+		if !ok || key != expected || item != items[expected] {
+			t.Error("Expected", expected, "to be evicted, got", key, item, ok)
+		}
+	}
+
+	// Size and head / tail should be back to empty, and the cache still usable
+	lru := cache.(*lruCache)
+	if cache.Len() != 0 || cache.Size() != 0 || lru.head != nil || lru.tail != nil {
+		t.Error("Expected empty cache, got", cache.Len(), "items of size", cache.Size())
+	}
+	if key, item, ok := cache.Evict(); ok || key != "" || item != nil {
+		t.Error("Expected nothing to evict, got", key, item, ok)
+	}
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"d"})
+
+	// Removing rather than evicting to make room, so Stats shouldn't count it
+	assertStats(t, cache.Stats(), Stats{Hits: 1, Adds: 4})
+}
+
+func TestEvictableCacheFIFO(t *testing.T) {
+	cache := CreateFIFOCache(MaxSize).(EvictableCache)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Add(key, &DummyCacheItem{DummySize: 10})
+	}
+
+	// Accessing shouldn't change the order items come out in
+	cache.Get("a")
+	for _, expected := range []string{"a", "b", "c"} {
+		if key, _, _ := cache.Evict(); key != expected {
+			t.Error("Expected", expected, "to be evicted, got", key)
+		}
+	}
+}
+
+func TestEvictableCacheExpired(t *testing.T) {
+	cache := CreateLRUCacheWithTTL(MaxSize, TestTTL).(EvictableCache)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	time.Sleep(TestTTL * 2)
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// Expired tail should be skipped over and removed
+	if key, _, ok := cache.Evict(); !ok || key != "b" {
+		t.Error("Expected b to be evicted, got", key, ok)
+	}
+	if cache.Len() != 0 {
+		t.Error("Expected empty cache, got", cache.Keys())
+	}
+}
+func TestNextEviction(t *testing.T) {
+	caches := map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"fifo": CreateFIFOCache(MaxSize),
+		"readOptimized": CreateLRUCacheReadOptimized(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"arc": CreateARCCache(MaxSize),
+		"2q": CreateTwoQueueCache(MaxSize),
+		"clock": CreateClockCache(MaxSize),
+		"random": CreateRandomCache(MaxSize),
+		"sampledLFU": CreateSampledLFUCache(MaxSize, 3),
+		"tinyLFU": CreateWindowTinyLFUCache(MaxSize),
+		"lruK": CreateLRUKCache(MaxSize, 2),
+		"priority": CreatePriorityCache(MaxSize),
+	}
+
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			predictable := cache.(PredictableCache)
+			if _, ok := predictable.NextEviction(); ok {
+				t.Error("Expected nothing to be evicted from an empty cache")
+			}
+
+			for i := 0; i < 10; i++ {
+				cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+			}
+
+			// Each Add over max size should evict exactly the key predicted, whatever's been accessed in between
+			for i := 10; i < 50; i++ {
+				cache.Get(strconv.Itoa(i % 7))
+				cache.Get(strconv.Itoa(i - 1))
+				key, ok := predictable.NextEviction()
+				if !ok || !cache.Contains(key) {
+					t.Fatal("Expected a key in the cache, got", key, ok)
+				}
+				cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+				if cache.Contains(key) || cache.Len() != 10 {
+					t.Fatal("Expected", key, "to have been evicted, got", cache.Keys())
+				}
+			}
+		})
+	}
+}
+
+func TestNextEvictionDoesntChangeEvictions(t *testing.T) {
+	creators := map[string]func() Cache {
+		"clock": func() Cache { return CreateClockCache(MaxSize) },
+		"tinyLFU": func() Cache { return CreateWindowTinyLFUCache(1000) },
+	}
+
+	for name, create := range creators {
+		t.Run(name, func(t *testing.T) {
+			// Two caches get the same adds and gets, only one is asked what it'll evict along the way
+			asked, left := create(), create()
+			for i := 0; i < 300; i++ {
+				for _, cache := range []Cache { asked, left } {
+					cache.Get(strconv.Itoa(i % 7))
+					cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+				}
+				for j := 0; j < 3; j++ {
+					asked.(PredictableCache).NextEviction()
+				}
+			}
+			assertKeys(t, asked.Keys(), left.Keys())
+		})
+	}
+}
+
+func TestNextEvictionFrozen(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	if key, ok := cache.(PredictableCache).NextEviction(); !ok || key != "a" {
+		t.Error("Expected a to be next, got", key, ok)
+	}
+
+	// Nothing's evicted while frozen
+	cache.(FreezableCache).Freeze()
+	if key, ok := cache.(PredictableCache).NextEviction(); ok {
+		t.Error("Expected nothing to be evicted while frozen, got", key)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,61 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestFreezableCache(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(FreezableCache)
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Frozen, so nothing should be evicted to make room
+	cache.Freeze()
+	for i := 10; i < 15; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Len() != 15 || cache.Size() != 150 {
+		t.Error("Expected the cache to grow to 15 items of size 150, got", cache.Len(), "items of size", cache.Size())
+	}
+	if cache.Stats().Evictions != 0 {
+		t.Error("Expected no evictions while frozen, got", cache.Stats())
+	}
+
+	// Items that wouldn't fit on their own are still rejected
+	if err := cache.Add("huge", &DummyCacheItem{DummySize: MaxSize + 1}); err == nil {
+		t.Error("Expected an item bigger than the max size to be rejected")
+	}
+
+	// Unfreezing should evict the tail items until its back under the max size
+	cache.Unfreeze()
+	if cache.Len() != 10 || cache.Size() > MaxSize {
+		t.Error("Expected 10 items within the max size, got", cache.Len(), "items of size", cache.Size())
+	}
+	if cache.Contains("4") || !cache.Contains("5") {
+		t.Error("Expected the oldest items to be evicted, got", cache.Keys())
+	}
+
+	// And evict as normal again afterwards
+	cache.Add("new", &DummyCacheItem{DummySize: 10})
+	if cache.Len() != 10 || cache.Contains("5") {
+		t.Error("Expected 5 to be evicted, got", cache.Keys())
+	}
+}
+
+func TestFreezableCacheByCount(t *testing.T) {
+	cache := CreateLRUCacheByCount(3).(FreezableCache)
+	cache.Freeze()
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Resizing while frozen waits for Unfreeze too
+	cache.Resize(20)
+	if cache.Len() != 5 {
+		t.Error("Expected 5 items while frozen, got", cache.Keys())
+	}
+	cache.Unfreeze()
+	assertKeys(t, cache.Keys(), []string{"4", "3"})
+}
\ No newline at end of file
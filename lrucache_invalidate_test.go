@@ -0,0 +1,61 @@
+package memcache
+
+import (
+	"io"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestInvalidateAll(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(InvalidatableCache)
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	cache.InvalidateAll()
+	for i := 0; i < 5; i++ {
+		if _, present := cache.Get(strconv.Itoa(i)); present {
+			t.Error("Expected", i, "to miss after InvalidateAll")
+		}
+	}
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected the missed items to be removed, got", cache.Len(), "items of size", cache.Size())
+	}
+
+	// Items added afterwards belong to the new generation
+	cache.Add("new", &DummyCacheItem{DummySize: 10})
+	if _, present := cache.Get("new"); !present {
+		t.Error("Expected an item added after InvalidateAll to be present")
+	}
+
+	// Re-adding the same item should bring it back too
+	item := &DummyCacheItem{DummySize: 10}
+	cache.Add("same", item)
+	cache.InvalidateAll()
+	if cache.Contains("same") {
+		t.Error("Expected same to be invalidated")
+	}
+	cache.Add("same", item)
+	if !cache.Contains("same") || cache.Size() != 20 {
+		t.Error("Expected same to be present again alongside new, got size", cache.Size())
+	}
+}
+
+func TestInvalidateAllJanitor(t *testing.T) {
+	cache := CreateLRUCacheWithJanitor(MaxSize, time.Hour, TestTTL/10)
+	defer cache.(io.Closer).Close()
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Nothing accesses the items, the janitor should reclaim them
+	cache.(InvalidatableCache).InvalidateAll()
+	time.Sleep(TestTTL / 2)
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected the janitor to remove the invalidated items, got", cache.Keys())
+	}
+	if err := CheckInvariants(cache); err != nil {
+		t.Error(err)
+	}
+}
\ No newline at end of file
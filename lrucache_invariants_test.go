@@ -0,0 +1,93 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLRUCacheInvariants(t *testing.T) {
+	cache := CreateLRUCacheWithTTL(50, TestTTL)
+	item := &DummyCacheItem{DummySize: 10}
+	steps := []struct {
+		name string
+		op func()
+	}{
+		{"add", func() {
+			for i := 0; i < 5; i++ {
+				cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+			}
+		}},
+		{"add evicting", func() { cache.Add("a", item) }},
+		{"get head", func() { cache.Get("a") }},
+		{"get tail", func() { cache.Get("1") }},
+		{"re-add", func() { cache.Add("a", item) }},
+		{"replace", func() { cache.Add("a", &DummyCacheItem{DummySize: 5}) }},
+		{"update size", func() {
+			cache.Add("b", item)
+			item.DummySize = 20
+			cache.UpdateSize("b")
+		}},
+		{"too big", func() { cache.Add("big", &DummyCacheItem{DummySize: 51}) }},
+		{"remove head", func() { cache.Remove(cache.Keys()[0]) }},
+		{"remove tail", func() { cache.Remove(cache.Keys()[cache.Len() - 1]) }},
+		{"remove twice", func() {
+			cache.Remove("a")
+			cache.Remove("a")
+		}},
+		{"touch", func() { cache.Touch(cache.Keys()[cache.Len() - 1]) }},
+		{"ttl", func() { cache.(ExpiringCache).AddWithTTL("forever", &DummyCacheItem{DummySize: 1}, 0) }},
+		{"evict", func() { cache.(EvictableCache).Evict() }},
+		{"resize", func() { cache.Resize(20) }},
+		{"drain last", func() {
+			for cache.Len() > 0 {
+				cache.(EvictableCache).Evict()
+			}
+		}},
+		{"add after empty", func() { cache.Add("c", &DummyCacheItem{DummySize: 10}) }},
+		{"expire", func() {
+			time.Sleep(TestTTL * 2)
+			cache.Get("c")
+		}},
+		{"clear", func() {
+			cache.Add("d", &DummyCacheItem{DummySize: 10})
+			cache.Clear()
+		}},
+	}
+
+	for _, step := range steps {
+		step.op()
+		if err := CheckInvariants(cache); err != nil {
+			t.Fatal("After", step.name, "-", err)
+		}
+	}
+}
+
+func TestLRUCacheCheckInvariantsCatchesCorruption(t *testing.T) {
+	for name, corrupt := range map[string]func(cache *lruCache) {
+		"nil tail": func(cache *lruCache) { cache.tail = nil },
+		"tail not at end": func(cache *lruCache) { cache.tail = cache.tail.prev },
+		"size taken off twice": func(cache *lruCache) { cache.curSize -= cache.tail.size },
+		"stale prev": func(cache *lruCache) { cache.head.next.prev = nil },
+		"loop": func(cache *lruCache) { cache.tail.next = cache.head },
+		"missing from the hash": func(cache *lruCache) { delete(cache.keyValMap, cache.head.key) },
+		"unlinked but still hashed": func(cache *lruCache) {
+			middle := cache.head.next
+			middle.prev.next = middle.next
+			middle.next.prev = middle.prev
+		},
+	} {
+		cache := CreateLRUCache(MaxSize).(*lruCache)
+		for _, key := range []string{"a", "b", "c"} {
+			cache.Add(key, &DummyCacheItem{DummySize: 10})
+		}
+		if err := CheckInvariants(cache); err != nil {
+			t.Fatal(name, "unexpected error before corrupting:", err)
+		}
+
+		corrupt(cache)
+		if err := CheckInvariants(cache); err == nil {
+			t.Error(name, "should have been caught")
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,62 @@
+package memcache
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// guardedCache creates a memory guarded cache whose heap size is read from heapAlloc, and counts how often its read
+func guardedCache(limit uint64, heapAlloc *uint64, reads *int) (*lruCache) {
+	cache := CreateLRUCacheWithMemoryGuard(MaxSize, limit).(*lruCache)
+	cache.memGuard.readMemStats = func(stats *runtime.MemStats) {
+		*reads++
+		stats.HeapAlloc = *heapAlloc
+	}
+	return cache
+}
+
+func TestLRUCacheWithMemoryGuard(t *testing.T) {
+	heapAlloc, reads := uint64(500), 0
+	cache := guardedCache(1000, &heapAlloc, &reads)
+
+	// Under the limit, nothing extra is evicted
+	for i := 0; i < 8; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Len() != 8 {
+		t.Error("Expected 8 items under the heap limit, got", cache.Len())
+	}
+
+	// Over the limit the next Add should shrink the cache by a quarter before adding
+	heapAlloc = 2000
+	cache.memGuard.lastCheck = time.Time{}
+	cache.Add("8", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"8", "7", "6", "5", "4", "3", "2"})
+	if stats := cache.Stats(); stats.Evictions != 2 {
+		t.Error("Expected 2 evictions, got", stats.Evictions)
+	}
+}
+
+func TestLRUCacheWithMemoryGuardThrottled(t *testing.T) {
+	heapAlloc, reads := uint64(2000), 0
+	cache := guardedCache(1000, &heapAlloc, &reads)
+	clock := NewFakeClock()
+	SetClock(cache, clock)
+
+	// Lots of adds in a row should only read the heap once
+	for i := 0; i < 50; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 1})
+	}
+	if reads != 1 {
+		t.Error("Expected the heap to be read once, got", reads)
+	}
+
+	// Once the interval has passed its read again
+	clock.Advance(memoryCheckInterval)
+	cache.Add("another", &DummyCacheItem{DummySize: 1})
+	if reads != 2 {
+		t.Error("Expected the heap to be read again, got", reads)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,90 @@
+package memcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNegativeCache(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(NegativeCache)
+
+	// Nothing cached either way
+	if item, found, negative := cache.GetWithNegative("a"); item != nil || found || negative {
+		t.Error("Expected nothing for a, got", item, found, negative)
+	}
+
+	// Negative hit, and it shouldn't take up any room
+	cache.AddNegative("a", TestTTL)
+	if item, found, negative := cache.GetWithNegative("a"); item != nil || found || !negative {
+		t.Error("Expected a negative hit for a, got", item, found, negative)
+	}
+	if cache.Len() != 0 || cache.Size() != 0 || cache.Contains("a") {
+		t.Error("Negative entries shouldn't count as items, got", cache.Len(), "items of size", cache.Size())
+	}
+
+	// Positive items are found as normal
+	item := &DummyCacheItem{DummySize: 10}
+	cache.Add("b", item)
+	if got, found, negative := cache.GetWithNegative("b"); got != item || !found || negative {
+		t.Error("Expected b to be found, got", got, found, negative)
+	}
+}
+
+func TestNegativeCacheExpiry(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(NegativeCache)
+	cache.AddNegative("a", TestTTL)
+
+	// Once the ttl has passed the key isn't known to be absent any more
+	time.Sleep(TestTTL + 10 * time.Millisecond)
+	if item, found, negative := cache.GetWithNegative("a"); item != nil || found || negative {
+		t.Error("Expected the negative entry to have expired, got", item, found, negative)
+	}
+}
+
+func TestNegativeCacheOverride(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(NegativeCache)
+
+	// A positive Add should replace the negative entry
+	cache.AddNegative("a", TestTTL)
+	item := &DummyCacheItem{DummySize: 10}
+	cache.Add("a", item)
+	if got, found, negative := cache.GetWithNegative("a"); got != item || !found || negative {
+		t.Error("Expected a to be found after being added, got", got, found, negative)
+	}
+
+	// And a negative Add should replace the item
+	cache.AddNegative("a", TestTTL)
+	if got, found, negative := cache.GetWithNegative("a"); got != nil || found || !negative {
+		t.Error("Expected a negative hit after AddNegative, got", got, found, negative)
+	}
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected the item to be removed, got", cache.Len(), "items of size", cache.Size())
+	}
+
+	// Remove and Clear forget negative entries too
+	cache.Remove("a")
+	if _, _, negative := cache.GetWithNegative("a"); negative {
+		t.Error("Expected Remove to forget the negative entry")
+	}
+	cache.AddNegative("a", TestTTL)
+	cache.Clear()
+	if _, _, negative := cache.GetWithNegative("a"); negative {
+		t.Error("Expected Clear to forget the negative entry")
+	}
+}
+
+func TestNegativeCacheJanitor(t *testing.T) {
+	cache := CreateLRUCacheWithJanitor(MaxSize, TestTTL, TestTTL / 4).(*lruCache)
+	defer cache.Close()
+
+	// Janitor should sweep out expired negative entries without them being looked up
+	cache.AddNegative("a", TestTTL)
+	time.Sleep(TestTTL * 2)
+
+	cache.mutex.RLock()
+	remaining := len(cache.negatives)
+	cache.mutex.RUnlock()
+	if remaining != 0 {
+		t.Error("Expected the janitor to remove the negative entry, got", remaining)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,102 @@
+package memcache
+
+import (
+	"testing"
+)
+
+func TestLRUCacheOverflowEvict(t *testing.T) {
+	cache := CreateLRUCacheWithOverflow(30, OverflowEvict)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Full, so the tail makes room like any LRU cache
+	if err := cache.Add("d", &DummyCacheItem{DummySize: 10}); err != nil {
+		t.Error("Expected d to be added, got", err)
+	}
+	assertKeys(t, cache.Keys(), []string{"d", "c", "b"})
+}
+
+func TestLRUCacheOverflowReject(t *testing.T) {
+	cache := CreateLRUCacheWithOverflow(30, OverflowReject)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	if err := cache.Add("c", &DummyCacheItem{DummySize: 15}); err != ErrCacheFull {
+		t.Error("Expected ErrCacheFull, got", err)
+	}
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Full, nothing's evicted and the cache is left as it was
+	if err := cache.Add("d", &DummyCacheItem{DummySize: 10}); err != ErrCacheFull {
+		t.Error("Expected ErrCacheFull, got", err)
+	}
+	assertKeys(t, cache.Keys(), []string{"c", "b", "a"})
+	assertStats(t, cache.Stats(), Stats{Adds: 3})
+
+	// Replacing an item that fits still works, as long as it doesn't grow past the space left
+	if err := cache.Add("a", &DummyCacheItem{DummySize: 10}); err != nil {
+		t.Error("Expected a to be replaced, got", err)
+	}
+	if err := cache.Add("b", &DummyCacheItem{DummySize: 20}); err != ErrCacheFull {
+		t.Error("Expected ErrCacheFull, got", err)
+	}
+	assertKeys(t, cache.Keys(), []string{"a", "c", "b"})
+
+	// Once there's room it fills up again
+	cache.Remove("c")
+	if err := cache.Add("b", &DummyCacheItem{DummySize: 20}); err != nil {
+		t.Error("Expected b to be replaced, got", err)
+	}
+	if cache.Size() != 30 {
+		t.Error("Expected a size of 30, got", cache.Size())
+	}
+	if err := CheckInvariants(cache); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLRUCacheOverflowRejectReserve(t *testing.T) {
+	cache := CreateLRUCacheWithOverflow(10, OverflowReject)
+
+	// The reserved space is the item's to use, even though the cache looks full
+	release, ok := cache.(ReservableCache).Reserve(8)
+	defer release()
+	if !ok {
+		t.Error("Expected the reservation to succeed")
+	}
+	if err := cache.Add("a", &DummyCacheItem{DummySize: 8}); err != nil {
+		t.Error("Expected a to use the reservation, got", err)
+	}
+
+	// Without room, reserving doesn't evict
+	if _, ok := cache.(ReservableCache).Reserve(5); ok {
+		t.Error("Expected the reservation to be rejected")
+	}
+	assertKeys(t, cache.Keys(), []string{"a"})
+	if err := CheckInvariants(cache); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLRUCacheOverflowRejectUpdateSize(t *testing.T) {
+	cache := CreateLRUCacheWithOverflow(20, OverflowReject)
+	a := &DummyCacheItem{DummySize: 5}
+	cache.Add("a", a)
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// Growing into the space left is fine
+	a.DummySize = 10
+	if err := cache.UpdateSize("a"); err != nil || cache.Size() != 20 {
+		t.Error("Expected a to grow to fill the cache, got", err, "size", cache.Size())
+	}
+
+	// Growing past it is rejected, nothing's evicted and a keeps its old size
+	a.DummySize = 15
+	if err := cache.UpdateSize("a"); err != ErrCacheFull {
+		t.Error("Expected ErrCacheFull, got", err)
+	}
+	assertKeys(t, cache.Keys(), []string{"a", "b"})
+	if cache.Size() != 20 {
+		t.Error("Expected size to stay at 20, got", cache.Size())
+	}
+}
\ No newline at end of file
@@ -0,0 +1,67 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestLRUCachePin(t *testing.T) {
+	cache := CreateLRUCache(30)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// a is the tail, once pinned its neighbours go instead
+	cache.(PinnableCache).Pin("a")
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+		if key, _ := cache.(PredictableCache).NextEviction(); key == "a" {
+			t.Error("a shouldn't be the next eviction while its pinned")
+		}
+	}
+	assertKeys(t, cache.Keys(), []string{"4", "3", "a"})
+	if cache.Size() != 30 {
+		t.Error("Expected size of 30, got", cache.Size())
+	}
+
+	// Unpinned it goes like any other
+	cache.(PinnableCache).Unpin("a")
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"d", "4", "3"})
+	if err := CheckInvariants(cache); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLRUCachePinOverCapacity(t *testing.T) {
+	cache := CreateLRUCache(20)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.(PinnableCache).Pin("a")
+	cache.(PinnableCache).Pin("b")
+	cache.(PinnableCache).Pin("missing")
+
+	// Nothing can be evicted so the cache goes over its max size
+	if err := cache.Add("c", &DummyCacheItem{DummySize: 10}); err != nil {
+		t.Error("Expected Add to succeed, got", err)
+	}
+	if cache.Len() != 3 || cache.Size() != 30 {
+		t.Error("Expected 3 items of size 30, got", cache.Len(), "items of size", cache.Size())
+	}
+	cache.(TrimmableCache).Trim(0)
+	assertKeys(t, cache.Keys(), []string{"b", "a"})
+
+	// Once unpinned the next Add brings it back under
+	cache.(PinnableCache).Unpin("a")
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"d", "b"})
+
+	// Removing an item drops its pin, adding it again doesn't bring it back
+	cache.Remove("b")
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("e", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"e", "b"})
+	if err := CheckInvariants(cache); err != nil {
+		t.Error(err)
+	}
+}
\ No newline at end of file
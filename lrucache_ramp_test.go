@@ -0,0 +1,82 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLRUCacheWithRamp(t *testing.T) {
+	cache := CreateLRUCacheWithRamp(1000, time.Minute)
+	clock := &FakeClock{now: cache.(*lruCache).ramp.start}
+	SetClock(cache, clock)
+
+	// Starts off small, items are evicted once its full
+	fill := func() {
+		for i := 0; i < 100; i++ {
+			cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+		}
+	}
+	fill()
+	if cache.Cap() != 100 || cache.Size() != 100 {
+		t.Error("Expected the cache to start at 100, got cap", cache.Cap(), "size", cache.Size())
+	}
+
+	// Half way through it's half way between
+	clock.Advance(30 * time.Second)
+	if cache.Cap() != 550 {
+		t.Error("Expected cap of 550, got", cache.Cap())
+	}
+	fill()
+	if cache.Size() != 550 {
+		t.Error("Expected size of 550, got", cache.Size())
+	}
+
+	// Too big for now, but not for later
+	if err := cache.Add("big", &DummyCacheItem{DummySize: 800}); err != ErrExceedsMaxSize {
+		t.Error("Expected ErrExceedsMaxSize, got", err)
+	}
+
+	// Once the ramp's over its a normal cache
+	clock.Advance(time.Hour)
+	fill()
+	if cache.Cap() != 1000 || cache.Size() != 1000 || cache.(*lruCache).ramp != nil {
+		t.Error("Expected the cache to have reached 1000, got cap", cache.Cap(), "size", cache.Size())
+	}
+	if err := cache.Add("big", &DummyCacheItem{DummySize: 800}); err != nil {
+		t.Error("Expected big item to fit, got", err)
+	}
+	if err := CheckInvariants(cache); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLRUCacheWithRampResize(t *testing.T) {
+	cache := CreateLRUCacheWithRamp(1000, time.Minute)
+	clock := &FakeClock{now: cache.(*lruCache).ramp.start}
+	SetClock(cache, clock)
+
+	// Resizing stops the ramp where it is
+	cache.Resize(200)
+	clock.Advance(time.Hour)
+	for i := 0; i < 100; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Cap() != 200 || cache.Size() != 200 {
+		t.Error("Expected the cache to stay at 200, got cap", cache.Cap(), "size", cache.Size())
+	}
+}
+
+func TestLRUCacheWithRampSmall(t *testing.T) {
+	// Under 100 the start size shouldn't be truncated to 0, and the remainder counts above 100
+	for finalMax, expected := range map[int]int{99: 9, 5: 1, 1099: 109} {
+		cache := CreateLRUCacheWithRamp(finalMax, time.Hour)
+		if cache.Cap() != expected {
+			t.Error("Expected", finalMax, "to start at", expected, "got", cache.Cap())
+		}
+	}
+	cache := CreateLRUCacheWithRamp(5, time.Hour)
+	if err := cache.Add("a", &DummyCacheItem{DummySize: 1}); err != nil {
+		t.Error("Expected add to succeed, got", err)
+	}
+}
\ No newline at end of file
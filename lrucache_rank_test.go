@@ -0,0 +1,53 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestLRUCacheGetWithRank(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(RankedCache)
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// 0 is at the tail, 4 at the head
+	if _, rank, ok := cache.GetWithRank("0"); !ok || rank != 0 {
+		t.Error("Expected 0 to be next to be evicted, got rank", rank, ok)
+	}
+
+	// That Get moved 0 to the head, so everything else moves down one
+	if _, rank, _ := cache.GetWithRank("0"); rank != 4 {
+		t.Error("Expected 0 to be at the head after being got, got rank", rank)
+	}
+	if _, rank, _ := cache.GetWithRank("1"); rank != 0 {
+		t.Error("Expected 1 to be next to be evicted, got rank", rank)
+	}
+
+	// A normal Get promotes too
+	cache.Get("2")
+	if _, rank, _ := cache.GetWithRank("3"); rank != 0 {
+		t.Error("Expected 3 to be next to be evicted, got rank", rank)
+	}
+
+	if _, _, ok := cache.GetWithRank("missing"); ok {
+		t.Error("Expected a missing key not to be found")
+	}
+	if stats := cache.Stats(); stats.Hits != 5 || stats.Misses != 1 {
+		t.Error("Expected 5 hits and a miss, got", stats)
+	}
+}
+
+func TestFIFOCacheGetWithRank(t *testing.T) {
+	cache := CreateFIFOCache(MaxSize).(RankedCache)
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Gets don't move anything in a FIFO cache
+	for i := 0; i < 2; i++ {
+		if _, rank, _ := cache.GetWithRank("1"); rank != 1 {
+			t.Error("Expected 1 to stay second from the tail, got rank", rank)
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,76 @@
+package memcache
+
+import (
+	"testing"
+)
+
+func TestLRUCacheReserve(t *testing.T) {
+	cache := CreateLRUCache(30)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Room is made straight away, the space counts towards the size
+	release, ok := cache.(ReservableCache).Reserve(15)
+	if !ok {
+		t.Error("Expected the reservation to fit")
+	}
+	assertKeys(t, cache.Keys(), []string{"c"})
+	if cache.Size() != 25 {
+		t.Error("Expected a size of 25, got", cache.Size())
+	}
+
+	// Released the space can be used by anything
+	release()
+	release()
+	if cache.Size() != 10 {
+		t.Error("Expected a size of 10, got", cache.Size())
+	}
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	cache.Add("e", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"e", "d", "c"})
+	if err := CheckInvariants(cache); err != nil {
+		t.Error(err)
+	}
+
+	// Too big to ever fit, nothing's evicted
+	if release, ok := cache.(ReservableCache).Reserve(31); ok || cache.Len() != 3 {
+		t.Error("Expected the reservation to fail, got", cache.Keys())
+	} else {
+		release()
+	}
+}
+
+func TestLRUCacheReserveAdd(t *testing.T) {
+	cache := CreateLRUCache(30)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// The item that was reserved for uses the space, nothing else is evicted
+	release, _ := cache.(ReservableCache).Reserve(20)
+	cache.Add("big", &DummyCacheItem{DummySize: 20})
+	assertKeys(t, cache.Keys(), []string{"big", "b"})
+	if cache.Size() != 30 {
+		t.Error("Expected a size of 30, got", cache.Size())
+	}
+
+	// Its already been used so releasing doesn't give anything back
+	release()
+	if cache.Size() != 30 {
+		t.Error("Expected a size of 30, got", cache.Size())
+	}
+
+	// Clearing keeps reservations
+	release, _ = cache.(ReservableCache).Reserve(10)
+	cache.Clear()
+	if cache.Size() != 10 {
+		t.Error("Expected the reservation to survive Clear, got", cache.Size())
+	}
+	release()
+	if cache.Size() != 0 {
+		t.Error("Expected an empty cache, got", cache.Size())
+	}
+	if err := CheckInvariants(cache); err != nil {
+		t.Error(err)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,45 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestLRUCacheWithSizeFunc(t *testing.T) {
+	double := func(item CacheItem) int {
+		return item.Size() * 2
+	}
+	cache := CreateLRUCacheWithSizeFunc(MaxSize, double)
+
+	// Each item counts as 20, so only 5 fit
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Len() != 5 || cache.Size() != MaxSize {
+		t.Error("Expected 5 items of size", MaxSize, "got", cache.Len(), "items of size", cache.Size())
+	}
+	assertKeys(t, cache.Keys(), []string{"9", "8", "7", "6", "5"})
+
+	// Get doesn't change the size, Remove takes the same size off that was added
+	cache.Get("5")
+	cache.Remove("9")
+	if cache.Size() != 80 {
+		t.Error("Expected size 80 after removing an item, got", cache.Size())
+	}
+
+	// UpdateSize goes through sizeFunc too
+	item := &DummyCacheItem{DummySize: 10}
+	cache.Add("grows", item)
+	item.DummySize = 30
+	if err := cache.UpdateSize("grows"); err != nil {
+		t.Error("Unexpected error", err)
+	}
+	if cache.Size() != MaxSize || !cache.Contains("grows") {
+		t.Error("Expected grows to count as 60 and evict to make room, got", cache.Keys(), "of size", cache.Size())
+	}
+
+	// Items are rejected by the size sizeFunc gives them
+	if err := cache.Add("big", &DummyCacheItem{DummySize: MaxSize / 2 + 1}); err != ErrExceedsMaxSize {
+		t.Error("Expected ErrExceedsMaxSize, got", err)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,55 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLRUCacheSnapshot(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(SnapshotCache)
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	cache.Get("0")
+	snapshot := cache.Snapshot()
+
+	// Change the cache after taking the snapshot
+	replaced := &DummyCacheItem{DummySize: 20}
+	cache.Add("1", replaced)
+	cache.Remove("2")
+	cache.Add("new", &DummyCacheItem{DummySize: 10})
+	cache.Clear()
+
+	assertKeys(t, snapshot.Keys(), []string{"0", "4", "3", "2", "1"})
+	if snapshot.Size() != 50 || snapshot.Cap() != MaxSize {
+		t.Error("Expected the snapshot to have size 50 and max size", MaxSize, "got", snapshot.Size(), snapshot.Cap())
+	}
+	if item, _ := snapshot.Get("1"); item == replaced {
+		t.Error("Expected the snapshot to have the original 1")
+	}
+	if _, present := snapshot.Get("new"); present {
+		t.Error("Expected new not to be in the snapshot")
+	}
+
+	// Changing the snapshot doesn't change the cache
+	snapshot.Add("only", &DummyCacheItem{DummySize: 10})
+	if cache.Contains("only") || cache.Len() != 0 {
+		t.Error("Expected the cache to be unaffected by the snapshot, got", cache.Keys())
+	}
+}
+
+func TestLRUCacheSnapshotTTL(t *testing.T) {
+	cache := CreateLRUCacheWithTTL(MaxSize, TestTTL).(SnapshotCache)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	snapshot := cache.Snapshot()
+
+	// Items keep their expiry in the snapshot
+	time.Sleep(TestTTL * 2)
+	if _, present := snapshot.Get("a"); present {
+		t.Error("Expected a to expire in the snapshot too")
+	}
+	if err := CheckInvariants(snapshot); err != nil {
+		t.Error(err)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,46 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestLRUCacheTrim(t *testing.T) {
+	var evicted []string
+	cache := CreateLRUCacheWithCallback(MaxSize, func(key string, item CacheItem, reason EvictReason) {
+		if reason != ReasonCapacity {
+			t.Error("Expected", key, "to be trimmed for capacity, got reason", reason)
+		}
+		evicted = append(evicted, key)
+	}, false).(TrimmableCache)
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Use the oldest so they're kept over the ones in the middle
+	cache.Get("0")
+	cache.Get("1")
+
+	cache.Trim(MaxSize / 2)
+	if cache.Size() != MaxSize / 2 || cache.Cap() != MaxSize {
+		t.Error("Expected size", MaxSize / 2, "with the max size unchanged, got", cache.Size(), cache.Cap())
+	}
+	assertKeys(t, cache.Keys(), []string{"1", "0", "9", "8", "7"})
+	assertKeys(t, evicted, []string{"2", "3", "4", "5", "6"})
+
+	// Trimming to more than the size does nothing, and the cache can fill up again afterwards
+	cache.Trim(MaxSize)
+	for i := 10; i < 15; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Size() != MaxSize || len(evicted) != 5 {
+		t.Error("Expected the cache to fill back up without evicting, got size", cache.Size(), "and", evicted)
+	}
+
+	// Trim(0) empties it, even items with no size
+	cache.Add("empty", &DummyCacheItem{DummySize: 0})
+	cache.Trim(0)
+	if cache.Len() != 0 || cache.Size() != 0 || len(evicted) != 16 {
+		t.Error("Expected Trim(0) to evict everything, got", cache.Keys(), "and", evicted)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,350 @@
+package memcache
+
+import (
+	"io"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	TestTTL = 100 * time.Millisecond
+)
+
+func TestLRUCacheWithTTL(t *testing.T) {
+	cache := CreateLRUCacheWithTTL(MaxSize, TestTTL)
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	if _, present := cache.Get("a"); !present {
+		t.Error("a should be present before its ttl")
+	}
+
+	// Expired items still count towards the size until they're accessed
+	time.Sleep(TestTTL * 2)
+	if cache.Len() != 1 || cache.Size() != 10 {
+		t.Error("Expected expired item to still be stored, got", cache.Len(), "items of size", cache.Size())
+	}
+
+	// Peeking shouldn't return it, but shouldn't remove it either
+	if _, present := cache.Peek("a"); present {
+		t.Error("a should have expired")
+	}
+	if cache.Len() != 1 {
+		t.Error("Expected Peek to leave the expired item, got", cache.Len(), "items")
+	}
+
+	// Accessing it should remove it
+	if _, present := cache.Get("a"); present {
+		t.Error("a should have expired")
+	}
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected empty cache after expired Get, got", cache.Len(), "items of size", cache.Size())
+	}
+
+	// Getting an expired item is a miss, and it counts as expiring
+	assertStats(t, cache.Stats(), Stats{Hits: 1, Misses: 1, Expirations: 1, Adds: 1})
+}
+
+func TestLRUCacheWithTTLReAdd(t *testing.T) {
+	cache := CreateLRUCacheWithTTL(MaxSize, TestTTL)
+
+	item := &DummyCacheItem{DummySize: 10}
+	cache.Add("a", item)
+	time.Sleep(TestTTL / 2)
+
+	// Adding again should restart the ttl
+	cache.Add("a", item)
+	time.Sleep(TestTTL * 3 / 4)
+	if _, present := cache.Get("a"); !present {
+		t.Error("a should be present as it was added again")
+	}
+}
+
+func TestLRUCacheWithTTLTouch(t *testing.T) {
+	cache := CreateLRUCacheWithTTL(MaxSize, TestTTL)
+
+	item := &DummyCacheItem{DummySize: 10}
+	cache.Add("a", item)
+	time.Sleep(TestTTL * 3 / 4)
+
+	// Touching near expiry should restart the ttl, so its still there after the original would've run out
+	if !cache.Touch("a") {
+		t.Error("Touch should return true for a present item")
+	}
+	time.Sleep(TestTTL / 2)
+	if got, present := cache.Get("a"); !present || got != item {
+		t.Error("a should be present as it was touched")
+	}
+
+	// Once its expired Touch shouldn't bring it back
+	time.Sleep(TestTTL + 10 * time.Millisecond)
+	if cache.Touch("a") {
+		t.Error("Touch should return false for an expired item")
+	}
+	if cache.Len() != 0 {
+		t.Error("Expired item should have been removed, got", cache.Keys())
+	}
+	if cache.Touch("missing") {
+		t.Error("Touch should return false for a missing item")
+	}
+
+	// Touch isn't a Get so doesn't count as a hit or miss, the item still expired
+	assertStats(t, cache.Stats(), Stats{Hits: 1, Expirations: 1, Adds: 1})
+}
+
+func TestLRUCacheWithoutTTL(t *testing.T) {
+	cache := CreateLRUCacheWithTTL(MaxSize, 0)
+
+	// A ttl of 0 means items never expire
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	time.Sleep(TestTTL)
+	if _, present := cache.Get("a"); !present {
+		t.Error("a should never expire")
+	}
+}
+
+func TestLRUCacheWithJanitor(t *testing.T) {
+	cache := CreateLRUCacheWithJanitor(MaxSize, TestTTL, TestTTL / 4)
+	defer cache.(io.Closer).Close()
+
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Janitor should have removed everything without any Gets
+	time.Sleep(TestTTL * 2)
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected janitor to empty the cache, got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestLRUCacheExpirations(t *testing.T) {
+	readOptimized := CreateLRUCacheReadOptimized(MaxSize)
+	readOptimized.(ExpiringCache).SetDefaultTTL(TestTTL)
+	caches := map[string]Cache {
+		"lru": CreateLRUCacheWithTTL(MaxSize, TestTTL),
+		"readOptimized": readOptimized,
+	}
+
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			cache.Add("a", &DummyCacheItem{DummySize: 10})
+			time.Sleep(TestTTL + 10 * time.Millisecond)
+
+			// Found in the hash but expired, so its a miss rather than a hit
+			if _, present := cache.Get("a"); present {
+				t.Error("a should have expired")
+			}
+			assertStats(t, cache.Stats(), Stats{Misses: 1, Expirations: 1, Adds: 1})
+		})
+	}
+
+	// Items the janitor removes expire without any Gets
+	cache := CreateLRUCacheWithJanitor(MaxSize, TestTTL, TestTTL / 4)
+	defer cache.(io.Closer).Close()
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	time.Sleep(TestTTL * 2)
+	assertStats(t, cache.Stats(), Stats{Expirations: 2, Adds: 2})
+}
+
+func TestLRUCacheWithJanitorClose(t *testing.T) {
+	cache := CreateLRUCacheWithJanitor(MaxSize, TestTTL, TestTTL / 4)
+
+	// Close should be safe to call more than once
+	closer := cache.(io.Closer)
+	if err := closer.Close(); err != nil {
+		t.Error("Unexpected error closing:", err)
+	}
+	if err := closer.Close(); err != nil {
+		t.Error("Unexpected error closing again:", err)
+	}
+
+	// Janitor has stopped so expired items are left until accessed
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	time.Sleep(TestTTL * 2)
+	if cache.Len() != 1 {
+		t.Error("Expected expired item to remain after janitor was closed, got", cache.Len(), "items")
+	}
+
+	// Closing a cache without a janitor should also be fine
+	if err := CreateLRUCache(MaxSize).(io.Closer).Close(); err != nil {
+		t.Error("Unexpected error closing cache without janitor:", err)
+	}
+}
+
+
+func TestLRUCacheRemoveExpiredStaggered(t *testing.T) {
+	cache := CreateLRUCacheWithTTL(MaxSize, TestTTL).(*lruCache)
+
+	// First half is added now, second half once the first is half way to expiring
+	items := make([]*DummyCacheItem, 50)
+	for i := range items {
+		items[i] = &DummyCacheItem{DummySize: 1}
+		cache.Add(strconv.Itoa(i), items[i])
+	}
+	time.Sleep(TestTTL / 2)
+	for i := 50; i < 100; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 1})
+	}
+
+	// From the first half, restart the ttl of 0-9 with Touch and 10-19 by re-adding them, remove 20-29 and replace 30-39
+	// with new values
+	for i := 0; i < 10; i++ {
+		cache.Touch(strconv.Itoa(i))
+		cache.Add(strconv.Itoa(i + 10), items[i + 10])
+		cache.Remove(strconv.Itoa(i + 20))
+		cache.Add(strconv.Itoa(i + 30), &DummyCacheItem{DummySize: 1})
+	}
+	assertExpiries(t, cache)
+
+	// Only 40-49 should have expired, 20-29 were already removed
+	time.Sleep(TestTTL * 3 / 4)
+	cache.removeExpired()
+	if cache.Len() != 80 || cache.Size() != 80 {
+		t.Error("Expected 80 items after removing expired, got", cache.Len(), "items of size", cache.Size())
+	}
+	for i := 0; i < 100; i++ {
+		_, present := cache.Peek(strconv.Itoa(i))
+		if gone := (i >= 20 && i < 30) || (i >= 40 && i < 50); present == gone {
+			t.Error("Expected", i, "present to be", !gone)
+		}
+	}
+	assertExpiries(t, cache)
+
+	// Everything else expires next
+	time.Sleep(TestTTL)
+	cache.removeExpired()
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected empty cache, got", cache.Len(), "items of size", cache.Size())
+	}
+	assertExpiries(t, cache)
+}
+
+// assertExpiries checks the expiries heap holds exactly the items in the hash that expire, each knowing its index
+func assertExpiries(t *testing.T, cache *lruCache) {
+	t.Helper()
+	expiring := 0
+	for _, item := range cache.keyValMap {
+		if item.ttl > 0 {
+			expiring++
+		}
+	}
+	if len(cache.expiries) != expiring {
+		t.Error("Expected", expiring, "items in the heap, got", len(cache.expiries))
+	}
+	for i, item := range cache.expiries {
+		if item.heapIndex != i || cache.keyValMap[item.key] != item {
+			t.Error("Heap item", item.key, "at", i, "has index", item.heapIndex)
+		}
+	}
+}
+
+func TestLRUCacheAddWithTTL(t *testing.T) {
+	cache := CreateLRUCacheWithTTL(MaxSize, TestTTL).(ExpiringCache)
+	cache.Add("default", &DummyCacheItem{DummySize: 10})
+	cache.AddWithTTL("long", &DummyCacheItem{DummySize: 10}, TestTTL * 4)
+	cache.AddWithTTL("forever", &DummyCacheItem{DummySize: 10}, 0)
+
+	// Only the item using the cache's ttl should have expired
+	time.Sleep(TestTTL * 2)
+	if _, present := cache.Get("default"); present {
+		t.Error("default should have expired")
+	}
+	for _, key := range []string{"long", "forever"} {
+		if _, present := cache.Get(key); !present {
+			t.Error(key, "should still be present")
+		}
+	}
+
+	// Long lived items still expire eventually, and a cache without a ttl can have items that do
+	time.Sleep(TestTTL * 3)
+	if _, present := cache.Get("long"); present {
+		t.Error("long should have expired")
+	}
+	noTTL := CreateLRUCache(MaxSize).(ExpiringCache)
+	noTTL.AddWithTTL("short", &DummyCacheItem{DummySize: 10}, TestTTL)
+	time.Sleep(TestTTL * 2)
+	if _, present := noTTL.Get("short"); present {
+		t.Error("short should have expired")
+	}
+}
+
+func TestLRUCacheAddWithTTLJanitor(t *testing.T) {
+	cache := CreateLRUCacheWithJanitor(MaxSize, TestTTL, TestTTL / 4)
+	defer cache.(io.Closer).Close()
+
+	cache.(ExpiringCache).AddWithTTL("long", &DummyCacheItem{DummySize: 10}, TestTTL * 10)
+	cache.(ExpiringCache).AddWithTTL("forever", &DummyCacheItem{DummySize: 10}, 0)
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Janitor should only have removed the items using the cache's ttl
+	time.Sleep(TestTTL * 2)
+	lru := cache.(*lruCache)
+	lru.mutex.Lock()
+	assertExpiries(t, lru)
+	lru.mutex.Unlock()
+	assertKeys(t, cache.Keys(), []string{"forever", "long"})
+}
+
+func TestLRUCacheSetDefaultTTL(t *testing.T) {
+	testSetDefaultTTL(t, CreateLRUCache(MaxSize).(ExpiringCache))
+}
+
+// testSetDefaultTTL checks items expire using the ttl that was in effect when they were added
+func testSetDefaultTTL(t *testing.T, cache ExpiringCache) {
+	cache.SetDefaultTTL(TestTTL * 10)
+	cache.Add("long", &DummyCacheItem{DummySize: 10})
+	cache.SetDefaultTTL(TestTTL)
+	cache.Add("short", &DummyCacheItem{DummySize: 10})
+	cache.SetDefaultTTL(0)
+	cache.Add("forever", &DummyCacheItem{DummySize: 10})
+
+	time.Sleep(TestTTL * 2)
+	if _, present := cache.Get("short"); present {
+		t.Error("short should have expired")
+	}
+	for _, key := range []string{"long", "forever"} {
+		if _, present := cache.Get(key); !present {
+			t.Error(key, "should still be present")
+		}
+	}
+
+	// Re-adding picks up the ttl in effect now
+	cache.SetDefaultTTL(TestTTL)
+	cache.Add("forever", &DummyCacheItem{DummySize: 10})
+	time.Sleep(TestTTL * 2)
+	if _, present := cache.Get("forever"); present {
+		t.Error("forever should have expired once re-added with a ttl")
+	}
+}
+
+func TestFakeClockExpiry(t *testing.T) {
+	caches := map[string]Cache {
+		"lru": CreateLRUCacheWithTTL(MaxSize, time.Hour),
+		"timed": CreateTimedCache(time.Hour),
+	}
+
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			clock := NewFakeClock()
+			SetClock(cache, clock)
+			cache.Add("a", &DummyCacheItem{DummySize: 10})
+
+			// The clock hasn't moved so there's no age yet, however long the test takes
+			clock.Advance(time.Hour - time.Second)
+			if _, age, present := cache.GetWithAge("a"); !present || age != time.Hour - time.Second {
+				t.Error("Expected a to be present with an age of just under an hour, got", age, present)
+			}
+
+			// Just past the ttl it expires, without having to sleep
+			clock.Advance(2 * time.Second)
+			if _, present := cache.Get("a"); present {
+				t.Error("a should have expired")
+			}
+			assertStats(t, cache.Stats(), Stats{Hits: 1, Misses: 1, Expirations: 1, Adds: 1})
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,72 @@
+package memcache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLRUCacheUpdate(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(UpdatableCache)
+
+	// Missing keys aren't found, returning an item adds it
+	err := cache.Update("a", func(existing CacheItem, found bool) (CacheItem, bool) {
+		if found || existing != nil {
+			t.Error("Expected a to be missing, got", existing, found)
+		}
+		return &DummyCacheItem{DummySize: 10}, true
+	})
+	if err != nil || cache.Size() != 10 {
+		t.Error("Expected a to be added, got", err, cache.Size())
+	}
+
+	// Growing the item in place should be picked up
+	cache.Update("a", func(existing CacheItem, found bool) (CacheItem, bool) {
+		existing.(*DummyCacheItem).DummySize = 30
+		return existing, true
+	})
+	if cache.Size() != 30 || cache.Len() != 1 {
+		t.Error("Expected a size of 30, got", cache.Size())
+	}
+
+	// Too big leaves the cache as it was
+	err = cache.Update("b", func(existing CacheItem, found bool) (CacheItem, bool) {
+		return &DummyCacheItem{DummySize: MaxSize + 1}, true
+	})
+	if err != ErrExceedsMaxSize || cache.Contains("b") {
+		t.Error("Expected ErrExceedsMaxSize, got", err)
+	}
+
+	// Not keeping it removes it
+	cache.Update("a", func(existing CacheItem, found bool) (CacheItem, bool) {
+		return nil, false
+	})
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected a to be removed, got", cache.Keys())
+	}
+}
+
+func TestLRUCacheUpdateConcurrent(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(UpdatableCache)
+
+	// Each goroutine merges its adds into the same total, if any were lost the total would be short
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cache.Update("total", func(existing CacheItem, found bool) (CacheItem, bool) {
+					if !found {
+						return IntCacheItem(1), true
+					}
+					return existing.(IntCacheItem) + 1, true
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if total, _ := cache.Get("total"); total != IntCacheItem(5000) {
+		t.Error("Expected a total of 5000, got", total)
+	}
+}
\ No newline at end of file
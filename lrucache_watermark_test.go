@@ -0,0 +1,80 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestLRUCacheWithWatermarks(t *testing.T) {
+	cache := CreateLRUCacheWithWatermarks(MaxSize, 50)
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Size() != MaxSize || cache.Stats().Evictions != 0 {
+		t.Error("Expected the cache to fill without evicting, got size", cache.Size(), cache.Stats())
+	}
+
+	// Going over should evict down to the low-water mark, including room for the new item
+	cache.Add("10", &DummyCacheItem{DummySize: 10})
+	if cache.Size() > MaxSize / 2 {
+		t.Error("Expected the size to be at most", MaxSize / 2, "after evicting, got", cache.Size())
+	}
+	assertKeys(t, cache.Keys(), []string{"10", "9", "8", "7", "6"})
+	if cache.Stats().Evictions != 6 {
+		t.Error("Expected 6 evictions, got", cache.Stats())
+	}
+
+	// There's room for the next few without evicting again
+	for i := 11; i < 16; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Size() != MaxSize || cache.Stats().Evictions != 6 {
+		t.Error("Expected no more evictions until the cache was full again, got size", cache.Size(), cache.Stats())
+	}
+
+	// Items bigger than the low-water mark still fit
+	cache.Add("big", &DummyCacheItem{DummySize: 80})
+	if cache.Size() != 80 || !cache.Contains("big") {
+		t.Error("Expected only big to be left, got", cache.Keys())
+	}
+}
+
+func TestLRUCacheWithWatermarksDisabled(t *testing.T) {
+	for _, percent := range []int{0, 100} {
+		cache := CreateLRUCacheWithWatermarks(MaxSize, percent)
+		for i := 0; i < 11; i++ {
+			cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+		}
+		if cache.Size() != MaxSize || cache.Stats().Evictions != 1 {
+			t.Error("Expected a", percent, "percent low-water mark to evict just enough to fit, got", cache.Stats())
+		}
+	}
+}
+
+func BenchmarkLRUCacheAddChurn(b *testing.B) {
+	benchmarkAddChurn(b, CreateLRUCache(MaxSize * 10))
+}
+
+func BenchmarkLRUCacheWithWatermarksAddChurn(b *testing.B) {
+	benchmarkAddChurn(b, CreateLRUCacheWithWatermarks(MaxSize * 10, 90))
+}
+
+// benchmarkAddChurn adds a new key every time so the cache is always evicting, and reports how many Adds evicted
+func benchmarkAddChurn(b *testing.B, cache Cache) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	item := &DummyCacheItem{DummySize: 10}
+
+	evictingAdds := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		evictions := cache.Stats().Evictions
+		cache.Add(keys[i % len(keys)], item)
+		if cache.Stats().Evictions != evictions {
+			evictingAdds++
+		}
+	}
+	b.ReportMetric(float64(evictingAdds) / float64(b.N), "evicting-adds/op")
+}
\ No newline at end of file
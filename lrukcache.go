@@ -0,0 +1,230 @@
+package memcache
+
+import (
+	"sort"
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateLRUKCache creates and returns an 'LRU-K' implementation of Cache
+//
+// The last k times each key was added or accessed are remembered, and the item evicted is the one whose k-th most recent
+// use is furthest in the past (its backward k-distance is biggest). Items used fewer than k times count as infinitely
+// far back so they go first, least recently used first. With k = 2 a key has to be used twice to be protected, so one-off
+// scans don't push out items with bursts of use. The history of evicted keys is kept too (for as many keys as are in the
+// cache), so a key that comes back quickly carries on where it left off. A k below 1 is treated as 1, which is plain LRU
+//
+// Finding a victim looks at every item, so eviction is O(n)
+func CreateLRUKCache(maxsize, k int) (Cache) {
+	return CreateLRUKCacheWithCRP(maxsize, k, 0)
+}
+
+// CreateLRUKCacheWithCRP creates and returns an LRU-K Cache with a correlated reference period
+//
+// Uses of a key within correlatedPeriod of its last use are treated as the same use, e.g. several reads while handling
+// one request, so they don't make it look popular. An item used within correlatedPeriod isn't evicted unless every item
+// has been. A correlatedPeriod of 0 treats every use separately
+func CreateLRUKCacheWithCRP(maxsize, k int, correlatedPeriod time.Duration) (Cache) {
+	if k < 1 {
+		k = 1
+	}
+	policy := &lruKPolicy { k: k, correlatedPeriod: correlatedPeriod, histories: make(map[string]*lruKHistory), 
+		ghosts: make(map[string]*policyEntry) }
+	cache := createPolicyCache(maxsize, policy)
+	policy.now = cache.now
+	return cache
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: lruKHistory (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// lruKHistory is what an lruKPolicy remembers about a key, HIST and LAST in the LRU-K paper
+type lruKHistory struct {
+
+	// refs holds the times of the last k uncorrelated uses, most recent first. Zero if there haven't been that many
+	refs []time.Time
+
+	// last is the time of the most recent use, correlated or not
+	last time.Time
+}
+
+// kth returns the time of the k-th most recent use, zero (infinitely far back) if there haven't been k
+func (this *lruKHistory) kth() time.Time {
+	return this.refs[len(this.refs) - 1]
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: lruKPolicy (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// lruKPolicy is the evictionPolicy for the LRU-K cache
+//
+// histories has an entry for every key in the cache and every ghost. ghosts are keys that have left the cache but whose
+// history is still remembered, oldest at the tail of ghostList so they're forgotten first
+type lruKPolicy struct {
+
+	// k is how many uses are remembered for each key
+	k int
+
+	// correlatedPeriod is how soon after a use another counts as the same one
+	correlatedPeriod time.Duration
+
+	// now returns the current time from the cache's clock
+	now func() time.Time
+
+	// entries holds every entry in the cache
+	entries entryList
+
+	// histories is the map of key(string) to its history
+	histories map[string]*lruKHistory
+
+	// ghostList holds a placeholder entry for each ghost, most recently removed at the head
+	ghostList entryList
+
+	// ghosts is the map of key(string) to ghost entry, for keys in ghostList
+	ghosts map[string]*policyEntry
+}
+
+// reference records a use of the key, following the LRU-K paper
+//
+// A use within correlatedPeriod of the last one just moves last on. Otherwise the older uses are shifted down a place,
+// moved on by the length of the correlated period that's just ended so it counts as a single use
+func (this *lruKPolicy) reference(key string) {
+	now := this.now()
+	history, present := this.histories[key]
+	if !present {
+		history = &lruKHistory { refs: make([]time.Time, this.k) }
+		history.refs[0] = now
+		history.last = now
+		this.histories[key] = history
+		return
+	}
+
+	if this.correlatedPeriod > 0 && now.Sub(history.last) <= this.correlatedPeriod {
+		history.last = now
+		return
+	}
+	correlated := history.last.Sub(history.refs[0])
+	for i := len(history.refs) - 1; i > 0; i-- {
+		if !history.refs[i - 1].IsZero() {
+			history.refs[i] = history.refs[i - 1].Add(correlated)
+		}
+	}
+	history.refs[0] = now
+	history.last = now
+}
+
+// evictsBefore returns true if a should be evicted ahead of b, its k-th use is further back or (if they're the same,
+// e.g. neither has been used k times) it was used less recently
+func (this *lruKPolicy) evictsBefore(a, b *lruKHistory) bool {
+	if !a.kth().Equal(b.kth()) {
+		return a.kth().Before(b.kth())
+	}
+	return a.last.Before(b.last)
+}
+
+// forget drops the ghost for the key if there is one, its history goes with it unless keepHistory is set
+func (this *lruKPolicy) forget(key string, keepHistory bool) {
+	ghost, present := this.ghosts[key]
+	if !present {
+		return
+	}
+	this.ghostList.remove(ghost)
+	delete(this.ghosts, key)
+	if !keepHistory {
+		delete(this.histories, key)
+	}
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// evictionPolicy Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// admitting takes the key off the ghost list if its there, keeping its history as its coming back
+func (this *lruKPolicy) admitting(key string) {
+	this.forget(key, true)
+}
+
+// added records the add as a use of the key
+func (this *lruKPolicy) added(entry *policyEntry) {
+	this.forget(entry.key, true)
+	this.entries.pushFront(entry)
+	this.reference(entry.key)
+}
+
+// accessed records the access as a use of the key
+func (this *lruKPolicy) accessed(entry *policyEntry) {
+	this.reference(entry.key)
+}
+
+// removed takes the entry out and keeps its history as a ghost, forgetting the oldest ghosts so there are no more of
+// them than entries
+func (this *lruKPolicy) removed(entry *policyEntry, evicted bool) {
+	this.entries.remove(entry)
+
+	ghost := &policyEntry { key: entry.key }
+	this.ghostList.pushFront(ghost)
+	this.ghosts[ghost.key] = ghost
+	for this.ghostList.len > max(this.entries.len, 1) {
+		this.forget(this.ghostList.tail.key, false)
+	}
+}
+
+// victim returns the entry with the biggest backward k-distance, out of the ones not used within correlatedPeriod
+//
+// If every entry has been used within correlatedPeriod then its picked from all of them
+func (this *lruKPolicy) victim() *policyEntry {
+	now := this.now()
+	var victim, fallback *policyEntry
+	for entry := this.entries.head; entry != nil; entry = entry.next {
+		history := this.histories[entry.key]
+		if fallback == nil || this.evictsBefore(history, this.histories[fallback.key]) {
+			fallback = entry
+		}
+		if this.correlatedPeriod > 0 && now.Sub(history.last) <= this.correlatedPeriod {
+			continue
+		}
+		if victim == nil || this.evictsBefore(history, this.histories[victim.key]) {
+			victim = entry
+		}
+	}
+	if victim == nil {
+		return fallback
+	}
+	return victim
+}
+
+// peekVictim returns victim, which only reads the histories so calling it doesn't change anything
+func (this *lruKPolicy) peekVictim() *policyEntry {
+	return this.victim()
+}
+
+// each iterates over the entries in the order they'd be kept, sorting them by backward k-distance first. The sort ignores
+// correlatedPeriod so the last entry might not be the next victim if its been used within it
+func (this *lruKPolicy) each(fn func(entry *policyEntry) bool) {
+	entries := make([]*policyEntry, 0, this.entries.len)
+	for entry := this.entries.head; entry != nil; entry = entry.next {
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return this.evictsBefore(this.histories[entries[j].key], this.histories[entries[i].key])
+	})
+
+	for _, entry := range entries {
+		if !fn(entry) {
+			return
+		}
+	}
+}
+
+// reset drops all entries, ghosts and histories
+func (this *lruKPolicy) reset() {
+	this.entries = entryList { }
+	this.ghostList = entryList { }
+	this.histories = make(map[string]*lruKHistory)
+	this.ghosts = make(map[string]*policyEntry)
+}
\ No newline at end of file
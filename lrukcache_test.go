@@ -0,0 +1,110 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLRUKCache(t *testing.T) {
+	cache := CreateLRUKCache(MaxSize, 2)
+
+	// Check cache is created correctly
+	if cache == nil {
+		t.Error("Cache is nil")
+	}
+
+	// Way more than will fit, should stay at max size
+	for i := 0; i < 50; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Len() != 10 || cache.Size() != MaxSize {
+		t.Error("Expected 10 items of size", MaxSize, "got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestLRUKCacheSecondUse(t *testing.T) {
+	cache := CreateLRUKCache(30, 2)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// a has been used twice, b and c only once so they're infinitely far back and go first, oldest first
+	cache.Get("a")
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	if !cache.Contains("a") || cache.Contains("b") {
+		t.Error("Expected b to be evicted over a, got", cache.Keys())
+	}
+
+	// A scan of one-off keys shouldn't push a out
+	for i := 0; i < 20; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if !cache.Contains("a") {
+		t.Error("Expected a to survive the scan, got", cache.Keys())
+	}
+	assertKeys(t, cache.Keys(), []string{"a", "19", "18"})
+}
+
+func TestLRUKCacheBackwardDistance(t *testing.T) {
+	cache := CreateLRUKCache(30, 2)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// All used twice, a's second to last use is the furthest back even though it was used most recently
+	cache.Get("b")
+	cache.Get("c")
+	cache.Get("a")
+	assertKeys(t, cache.Keys(), []string{"c", "b", "a"})
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	if cache.Contains("a") {
+		t.Error("Expected a to be evicted, got", cache.Keys())
+	}
+}
+
+func TestLRUKCacheHistory(t *testing.T) {
+	cache := CreateLRUKCache(20, 2)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// a was evicted but its history is remembered, so coming back counts as its second use
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	if !cache.Contains("a") || cache.Contains("c") {
+		t.Error("Expected c to be evicted over a, got", cache.Keys())
+	}
+}
+
+func TestLRUKCacheCorrelatedPeriod(t *testing.T) {
+	cache := CreateLRUKCacheWithCRP(30, 2, time.Hour)
+	policy := cache.(*policyCache).policy.(*lruKPolicy)
+	now := time.Now()
+	policy.now = func() time.Time { return now }
+
+	// The Get is straight after the Add so they're the same use, a hasn't really been used twice
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	now = now.Add(time.Minute)
+	cache.Get("a")
+	now = now.Add(2 * time.Hour)
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	if policy.histories["a"].kth() != (time.Time{}) {
+		t.Error("Expected a to have a single use, got", policy.histories["a"].refs)
+	}
+
+	// b and c are within their correlated period so they can't be evicted yet, a goes even though b and c are older
+	now = now.Add(time.Minute)
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	if cache.Contains("a") {
+		t.Error("Expected a to be evicted, got", cache.Keys())
+	}
+
+	// Uses outside the period count separately
+	now = now.Add(2 * time.Hour)
+	cache.Get("b")
+	if policy.histories["b"].kth().IsZero() {
+		t.Error("Expected b to have been used twice")
+	}
+}
\ No newline at end of file
@@ -1,5 +1,15 @@
 package memcache
 
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrNotFound is returned by GetOrErr when the key isn't in the cache, compare with errors.Is
+var ErrNotFound = errors.New("Key not found in cache")
+
 // Cache is an interface that the different memory cache implementations will implement
 type Cache interface {
 
@@ -13,6 +23,127 @@ type Cache interface {
 
 	// Remove removes an item from the cache
 	Remove(key string)
+
+	// Len returns the number of items currently stored in the cache
+	Len() int
+
+	// Size returns the total size of all the items currently stored in the cache
+	Size() int
+
+	// Cap returns the maximum total size the cache will hold before it starts removing items
+	Cap() int
+
+	// Clear removes all items from the cache
+	Clear()
+
+	// Keys returns the keys of all the items currently stored in the cache
+	//
+	// Keys are returned in the order the cache would keep them, so the last key is the next to be removed
+	Keys() []string
+
+	// Stats returns counts of the hits, misses, evictions, expirations and adds since the cache was created
+	Stats() Stats
+
+	// Peek retrieves an item from the cache if its present, without counting as an access
+	//
+	// If item is present then the item, true is returned. Otherwise, nil, false
+	Peek(key string) (CacheItem, bool)
+
+	// Contains returns true if the item is present in the cache, without counting as an access
+	Contains(key string) bool
+
+	// GetOrAdd retrieves an item from the cache if its present, otherwise it calls compute and adds the item it returns
+	//
+	// If multiple goroutines call GetOrAdd for the same missing key at the same time then compute is only called once, and
+	// they all get its result. If compute returns an error then nothing is added and the error is returned
+	GetOrAdd(key string, compute func() (CacheItem, error)) (CacheItem, error)
+
+	// UpdateSize re-reads the Size of an item that's changed since it was added, and updates the size of the cache to match
+	//
+	// Items may be evicted to get back under the max size. If the item is now too big for the cache then its removed and an
+	// error is returned
+	UpdateSize(key string) error
+
+	// AddReturning adds a CacheItem to the cache like Add, and returns the item it replaced
+	//
+	// If an item was already present under the key then it's returned with existed set to true, even if its the same item
+	AddReturning(key string, val CacheItem) (prev CacheItem, existed bool, err error)
+
+	// Resize changes the maximum total size of the cache, items are removed straight away if it's now over the new size
+	Resize(newMax int)
+
+	// RemoveFunc removes every item that pred returns true for
+	//
+	// pred is called with the cache locked so it mustn't call back into the cache
+	RemoveFunc(pred func(key string, item CacheItem) bool)
+
+	// ForEach calls fn for every item in the cache, in the same order as Keys, until fn returns false
+	//
+	// It doesn't count as accessing the items. fn is called with the cache locked so it mustn't call back into the cache
+	ForEach(fn func(key string, item CacheItem) bool)
+
+	// GetOrAddContext works like GetOrAdd, but gives up if ctx is done before it has the item
+	//
+	// ctx is passed on to compute, which should return once ctx is done. If ctx is done by the time compute returns then
+	// nothing is added and ctx's error is returned. Callers waiting on another goroutine's compute stop waiting when their
+	// own ctx is done
+	GetOrAddContext(ctx context.Context, key string, compute func(ctx context.Context) (CacheItem, error)) (CacheItem, error)
+
+	// Touch marks an item as used without retrieving it, returning true if it was present
+	//
+	// Caches with a ttl restart the item's ttl. Touching a missing or expired key returns false and doesn't add anything
+	Touch(key string) bool
+
+	// AddWithCost adds a CacheItem like Add, but counts cost towards the size of the cache instead of the item's Size
+	//
+	// Lets items be weighted by something other than memory, e.g. how expensive they are to recompute. The same cost comes
+	// off when the item leaves the cache. Re-adding the item with Add or calling UpdateSize goes back to its Size
+	AddWithCost(key string, val CacheItem, cost int) error
+
+	// GetWithAge retrieves an item like Get, along with how long its been since it was added
+	//
+	// It counts as an access just like Get. If the item isn't present then nil, 0, false is returned
+	GetWithAge(key string) (CacheItem, time.Duration, bool)
+
+	// GetStaleWhileRevalidate retrieves an item, refreshing it once its been in the cache for longer than ttl
+	//
+	// If the item is younger than ttl then its returned as it is. If its older, but by no more than staleWindow, then its
+	// still returned straight away and refresh is called on another goroutine to replace it. Otherwise (or if its missing)
+	// refresh is called and waited for. Only one refresh runs at a time for a key. If refresh returns an error then a stale
+	// item is left as it is, and if there wasn't one to return then nil, false is returned
+	GetStaleWhileRevalidate(key string, ttl, staleWindow time.Duration, refresh func() (CacheItem, error)) (CacheItem, bool)
+
+	// GetOrErr retrieves an item like Get, but returns ErrNotFound instead of false if the item isn't present
+	//
+	// Useful where a miss should be passed up as an error. If the item is present then the item, nil is returned
+	GetOrErr(key string) (CacheItem, error)
+
+	// GetAll returns a snapshot of every item in the cache, keyed by their keys
+	//
+	// It doesn't count as accessing the items. The map is new so changing it doesn't change the cache, and later changes to
+	// the cache don't show up in it
+	GetAll() map[string]CacheItem
+
+	// AddIfAbsent adds a CacheItem like Add, but only if there isn't already an item stored under the key
+	//
+	// Returns true if the item was added. If the key's present then false is returned and the existing item is left as it
+	// is, it doesn't count as an access. Checking and adding happen together so only one of several concurrent calls for
+	// the same key can succeed
+	AddIfAbsent(key string, val CacheItem) (added bool, err error)
+
+	// Replace replaces the item stored under the key like Add, but only if there already is one
+	//
+	// Returns true if the item was replaced, and the size of the cache changes to match the new item. If the key isn't
+	// present then false is returned and nothing is added
+	Replace(key string, val CacheItem) (replaced bool, err error)
+}
+
+// getOrErr turns the result of a Get into the result of GetOrErr
+func getOrErr(item CacheItem, present bool) (CacheItem, error) {
+	if !present {
+		return nil, ErrNotFound
+	}
+	return item, nil
 }
 
 // CacheItem represents a single item in the cache
@@ -20,6 +151,258 @@ type CacheItem interface {
 
 	// Size returns the size in memory of the item
 	//
-	// This can be used by the cache to keep track of the total size
+	// This can be used by the cache to keep track of the total size. It mustn't be negative, caches won't store items
+	// with a negative size
 	Size() int
+}
+
+// ValidateItem returns the error Add would return for the item however big the cache, ErrNilItem if its nil or
+// ErrNegativeSize if its Size is less than 0. Otherwise it returns nil
+func ValidateItem(item CacheItem) error {
+	if item == nil {
+		return ErrNilItem
+	}
+	return sizeError(item.Size(), math.MaxInt)
+}
+
+// itemSize returns the item's Size, or 0 if its nil so the cache can return ErrNilItem instead of panicking
+func itemSize(item CacheItem) int {
+	if item == nil {
+		return 0
+	}
+	return item.Size()
+}
+
+// sizeError returns the error for an item of the size passed in, in a cache with the max size passed in. nil if it can
+// be stored
+func sizeError(size int, maxSize int) error {
+	if size < 0 {
+		return ErrNegativeSize
+	}
+	if size > maxSize {
+		return ErrExceedsMaxSize
+	}
+	return nil
+}
+
+// Stats holds counts of what's happened in a cache since it was created
+type Stats struct {
+
+	// Hits is the number of times Get found the item it was looking for
+	Hits uint64
+
+	// Misses is the number of times Get didn't find the item it was looking for, including when it found an item that had
+	// expired
+	Misses uint64
+
+	// Evictions is the number of items removed to make room for others
+	Evictions uint64
+
+	// Expirations is the number of items removed because they'd expired, whether Get found them or they were removed in
+	// the background
+	Expirations uint64
+
+	// Adds is the number of items successfully added
+	Adds uint64
+}
+
+// EvictReason is why an item left a cache
+type EvictReason int
+
+const (
+	// ReasonCapacity means the item was evicted to make room for others
+	ReasonCapacity EvictReason = iota
+
+	// ReasonExpired means the item had been in the cache for longer than its ttl
+	ReasonExpired
+
+	// ReasonManual means the item was removed with Remove
+	ReasonManual
+)
+
+// OverflowPolicy is what a cache does when an item's added that doesn't fit in the space left
+type OverflowPolicy int
+
+const (
+	// OverflowEvict means items are evicted to make room for the new one
+	OverflowEvict OverflowPolicy = iota
+
+	// OverflowReject means the new item isn't added and Add returns ErrCacheFull, so nothing already cached is lost
+	OverflowReject
+)
+
+// EvictCallback is called with the key, item and reason when an item is evicted from a cache
+type EvictCallback func(key string, item CacheItem, reason EvictReason)
+
+// SizeFunc returns the size of an item, for caches that are given one to use instead of the item's Size method. Like
+// Size it mustn't be negative
+type SizeFunc func(item CacheItem) int
+
+// ExpiringCache is a Cache where items can be given their own ttl, instead of the one the cache was created with. The
+// LRU, FIFO and Timed caches implement it
+type ExpiringCache interface {
+	Cache
+
+	// AddWithTTL adds a CacheItem like Add, but it expires once its been in the cache for ttl. A ttl of 0 means it never
+	// expires, even if the other items in the cache do
+	AddWithTTL(key string, val CacheItem, ttl time.Duration) error
+
+	// SetDefaultTTL changes the ttl given to items added from now on, without changing the items already in the cache. A
+	// ttl of 0 means items added from now on never expire
+	SetDefaultTTL(ttl time.Duration)
+}
+
+// CounterCache is a Cache that can hold counters as IntCacheItems, and add to them without racing other goroutines. The
+// LRU and FIFO caches implement it
+type CounterCache interface {
+	Cache
+
+	// Increment adds delta to the IntCacheItem stored under the key and returns the new value. If the key isn't present
+	// then the counter starts at delta. Returns ErrNotInt if a different kind of item is stored under the key
+	Increment(key string, delta int64) (int64, error)
+
+	// Decrement takes delta off the IntCacheItem stored under the key and returns the new value, like Increment
+	Decrement(key string, delta int64) (int64, error)
+}
+
+// PriorityCache is a Cache where items can be given a priority, lower priority items are evicted first. The Priority
+// cache implements it
+type PriorityCache interface {
+	Cache
+
+	// AddWithPriority adds an item like Add with the priority passed in, higher priorities are kept for longer. Within a
+	// priority the least recently used item is evicted first
+	AddWithPriority(key string, item CacheItem, priority int) error
+}
+
+// UpdatableCache is a Cache whose items can be read and replaced in one step without racing other goroutines, e.g. to
+// merge into an aggregate. The LRU and FIFO caches implement it
+type UpdatableCache interface {
+	Cache
+
+	// Update calls fn with the item stored under the key (found is false if there isn't one) while holding the cache's
+	// lock, then stores the item fn returns. If fn returns keep as false then the key is removed instead
+	Update(key string, fn func(existing CacheItem, found bool) (updated CacheItem, keep bool)) error
+}
+
+// FreezableCache is a Cache where eviction can be turned off for a while, e.g. to keep everything during a burst. The
+// LRU and FIFO caches implement it
+type FreezableCache interface {
+	Cache
+
+	// Freeze stops items being evicted to make room, the cache grows past its max size instead
+	Freeze()
+
+	// Unfreeze lets items be evicted again, and evicts them straight away until the cache is back under its max size
+	Unfreeze()
+}
+
+// EvictableCache is a Cache that items can be taken off the end of, in the order they'd be evicted. The LRU and FIFO
+// caches implement it
+type EvictableCache interface {
+	Cache
+
+	// Evict removes the item that would be evicted next and returns it, or "", nil, false if the cache is empty. Calling
+	// it until it returns false drains the cache in eviction order
+	Evict() (key string, item CacheItem, ok bool)
+}
+
+// PredictableCache is a Cache that can say which item it'll evict next, e.g. for testing code that depends on what gets
+// evicted. The LRU and FIFO caches implement it, as do the caches with another eviction policy (LFU, ARC, 2Q and so on)
+type PredictableCache interface {
+	Cache
+
+	// NextEviction returns the key of the item that would be evicted next to make room, without removing it. An Add that
+	// takes the cache over its max size straight afterwards evicts that item first. Returns "", false if nothing would be
+	NextEviction() (key string, ok bool)
+}
+
+// ReservableCache is a Cache that can make room for an item before its been put together, so the work isn't wasted if
+// it wouldn't fit. The LRU and FIFO caches implement it
+type ReservableCache interface {
+	Cache
+
+	// Reserve evicts items until there's room for bytes and holds the space until release is called, or an item that fits
+	// in it is added. Returns ok false if bytes is more than the max size
+	Reserve(bytes int) (release func(), ok bool)
+}
+
+// PinnableCache is a Cache whose items can be pinned so they're never evicted to make room, e.g. config that must always
+// be there. The LRU and FIFO caches implement it
+type PinnableCache interface {
+	Cache
+
+	// Pin stops the item under the key being evicted, eviction skips over it to the next item. Pinned items can still be
+	// removed, and they expire like any other
+	Pin(key string)
+
+	// Unpin lets the item under the key be evicted again
+	Unpin(key string)
+}
+
+// TrimmableCache is a Cache that can be shrunk once to free memory, without lowering its max size. The LRU and FIFO
+// caches implement it
+type TrimmableCache interface {
+	Cache
+
+	// Trim evicts items, in the order they'd normally be evicted, until the cache's size is no more than targetBytes.
+	// Trim(0) empties the cache
+	Trim(targetBytes int)
+}
+
+// NegativeCache is a Cache that can also remember keys that are known not to exist, so callers can skip looking them up
+// again. The LRU and FIFO caches implement it
+type NegativeCache interface {
+	Cache
+
+	// AddNegative records that key is known to be absent for ttl. Any item stored under the key is removed. Negative
+	// entries don't count towards the size or length of the cache, and adding an item under the key replaces it
+	AddNegative(key string, ttl time.Duration)
+
+	// GetWithNegative retrieves an item like Get, but also says whether the key has been cached as absent
+	//
+	// If the item is present then item, true, false is returned. If the key was added with AddNegative and its ttl hasn't
+	// passed then nil, false, true. Otherwise nil, false, false
+	GetWithNegative(key string) (item CacheItem, found bool, negativelyCached bool)
+}
+
+// InvalidatableCache is a Cache that can invalidate everything in it at once, without walking every item. The LRU and
+// FIFO caches implement it
+type InvalidatableCache interface {
+	Cache
+
+	// InvalidateAll makes every item currently in the cache miss on Get, as if it had been removed. The items are
+	// removed lazily, when they're next accessed or by the janitor
+	InvalidateAll()
+}
+
+// ObservableCache is a Cache that sends a CacheEvent as things happen to its items, e.g. for a live debug view. The LRU
+// and FIFO caches implement it, only caches created with CreateLRUCacheWithEvents send anything
+type ObservableCache interface {
+	Cache
+
+	// Events returns the channel events are sent on. Events are dropped rather than holding up the cache, oldest first
+	Events() <-chan CacheEvent
+
+	// Close stops sending events and closes the channel
+	Close() error
+}
+
+// RankedCache is a Cache that can say how close an item is to being evicted, e.g. to refresh items before they go. The
+// LRU and FIFO caches implement it
+type RankedCache interface {
+	Cache
+
+	// GetWithRank retrieves an item like Get, along with its distance from the end items are evicted from. A
+	// rankFromTail of 0 means it would have been the next to go
+	GetWithRank(key string) (item CacheItem, rankFromTail int, ok bool)
+}
+
+// SnapshotCache is a Cache that can take a consistent copy of itself, so several Gets can see the same state while it
+// keeps changing. The LRU and FIFO caches implement it
+type SnapshotCache interface {
+	Cache
+
+	// Snapshot returns a copy of the cache as it is now, later changes to either don't show up in the other
+	Snapshot() Cache
 }
\ No newline at end of file
@@ -1,9 +1,14 @@
 package memcache
 
 import (
+	"errors"
 	"testing"
 	"strconv"
+	"strings"
 	"fmt"
+	"sync"
+	"time"
+	"slices"
 )
 
 const (
@@ -30,7 +35,7 @@ func TestLRUCache(t *testing.T) {
 	// 	}
 	// }
 
-	fmt.Println("\nAfter adds...\n")
+	fmt.Println("\nAfter adds...")
 
 	// 9 should be last accessed so front of queue, 0 should be last
 	cache.Add("10", &DummyCacheItem{DummySize: 10})
@@ -56,10 +61,1256 @@ func TestLRUCache(t *testing.T) {
 	}
 }
 
+func TestLRUCacheLen(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+
+	// Empty cache should have nothing in it
+	if cache.Len() != 0 {
+		t.Error("Len should be 0 for an empty cache, got", cache.Len())
+	}
+
+	// Add 5 cacheitems of size 30, only the last 3 will fit
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 30})
+	}
+
+	// Count the keys that survived eviction, Len should match
+	surviving := 0
+	for i := 0; i < 5; i++ {
+		if _, present := cache.Get(strconv.Itoa(i)); present {
+			surviving++
+		}
+	}
+
+	if surviving != 3 {
+		t.Error("Expected 3 items to survive eviction, got", surviving)
+	}
+
+	if cache.Len() != surviving {
+		t.Error("Len should be", surviving, "got", cache.Len())
+	}
+
+	// Removing an item should reduce the count
+	cache.Remove("4")
+	if cache.Len() != surviving - 1 {
+		t.Error("Len should be", surviving - 1, "after Remove, got", cache.Len())
+	}
+}
+
+func TestLRUCacheSize(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+
+	if cache.Cap() != MaxSize {
+		t.Error("Cap should be", MaxSize, "got", cache.Cap())
+	}
+
+	// Add items of size 10, 20 & 30, size should be the sum
+	cache.Add("10", &DummyCacheItem{DummySize: 10})
+	cache.Add("20", &DummyCacheItem{DummySize: 20})
+	cache.Add("30", &DummyCacheItem{DummySize: 30})
+	if cache.Size() != 60 {
+		t.Error("Size should be 60, got", cache.Size())
+	}
+
+	// Adding another 50 should evict 10 to make room
+	cache.Add("50", &DummyCacheItem{DummySize: 50})
+	if cache.Size() != 100 {
+		t.Error("Size should be 100 after eviction, got", cache.Size())
+	}
+
+	// Removing should take the size off
+	cache.Remove("20")
+	if cache.Size() != 80 {
+		t.Error("Size should be 80 after Remove, got", cache.Size())
+	}
+}
+
+func TestLRUCacheAddMaxSizeItems(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+
+	// First item takes up the whole cache
+	if err := cache.Add("first", &DummyCacheItem{DummySize: MaxSize}); err != nil {
+		t.Error("Unexpected error adding first item:", err)
+	}
+
+	// Second item also takes up the whole cache so first should be evicted, without panicking
+	if err := cache.Add("second", &DummyCacheItem{DummySize: MaxSize}); err != nil {
+		t.Error("Unexpected error adding second item:", err)
+	}
+
+	if _, present := cache.Get("first"); present {
+		t.Error("first should have been evicted")
+	}
+	if _, present := cache.Get("second"); !present {
+		t.Error("second should be present")
+	}
+	if cache.Len() != 1 || cache.Size() != MaxSize {
+		t.Error("Expected 1 item of size", MaxSize, "got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestLRUCacheAddDriftedSize(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(*lruCache)
+
+	// Simulate the size drifting when there's nothing in the cache, the eviction loop would run out of items
+	cache.curSize = 1
+
+	if err := cache.Add("item", &DummyCacheItem{DummySize: MaxSize}); err != nil {
+		t.Error("Unexpected error adding item:", err)
+	}
+	if cache.Size() != MaxSize {
+		t.Error("Size should have been recomputed to", MaxSize, "got", cache.Size())
+	}
+}
+
+func TestLRUCacheClear(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+
+	// Fill the cache
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	cache.Clear()
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected empty cache after Clear, got", cache.Len(), "items of size", cache.Size())
+	}
+	if _, present := cache.Get("0"); present {
+		t.Error("0 should not be present after Clear")
+	}
+
+	// Cache should work as normal afterwards
+	cache.Add("a", &DummyCacheItem{DummySize: 60})
+	cache.Add("b", &DummyCacheItem{DummySize: 60})
+	if _, present := cache.Get("a"); present {
+		t.Error("a should have been evicted")
+	}
+	if _, present := cache.Get("b"); !present {
+		t.Error("b should be present")
+	}
+	if cache.Len() != 1 || cache.Size() != 60 {
+		t.Error("Expected 1 item of size 60, got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestLRUCacheKeys(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Most recently added should be first
+	assertKeys(t, cache.Keys(), []string{"4", "3", "2", "1", "0"})
+
+	// Accessing 0 should move it to the head
+	cache.Get("0")
+	assertKeys(t, cache.Keys(), []string{"0", "4", "3", "2", "1"})
+
+	// Removed keys shouldn't be returned
+	cache.Remove("3")
+	assertKeys(t, cache.Keys(), []string{"0", "4", "2", "1"})
+}
+
+func TestLRUCacheStats(t *testing.T) {
+	cache := CreateLRUCache(30)
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// 2 hits, 1 miss
+	cache.Get("a")
+	cache.Get("b")
+	cache.Get("missing")
+
+	// "c" is the tail so is evicted, then it misses
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	cache.Get("c")
+
+	// Too big to add so shouldn't count
+	cache.Add("e", &DummyCacheItem{DummySize: 40})
+
+	assertStats(t, cache.Stats(), Stats{Hits: 2, Misses: 2, Evictions: 1, Adds: 4})
+}
+
+func TestLRUCachePeek(t *testing.T) {
+	cache := CreateLRUCache(30)
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Peek the tail item repeatedly, it shouldn't move
+	for i := 0; i < 5; i++ {
+		if item, present := cache.Peek("a"); !present || item.Size() != 10 {
+			t.Error("a should be present")
+		}
+	}
+	assertKeys(t, cache.Keys(), []string{"c", "b", "a"})
+
+	// So it's still the next to be evicted
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	if _, present := cache.Peek("a"); present {
+		t.Error("a should have been evicted")
+	}
+
+	// Peeks aren't counted as hits or misses
+	assertStats(t, cache.Stats(), Stats{Evictions: 1, Adds: 4})
+}
+
+func TestLRUCacheContains(t *testing.T) {
+	cache := CreateLRUCache(30)
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	if !cache.Contains("a") {
+		t.Error("a should be present")
+	}
+	if cache.Contains("missing") {
+		t.Error("missing shouldn't be present")
+	}
+
+	// Checking the tail shouldn't save it, next Add should still evict it first
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	if cache.Contains("a") {
+		t.Error("a should have been evicted")
+	}
+	if !cache.Contains("b") {
+		t.Error("b should be present")
+	}
+
+	// Contains isn't counted as a hit or miss
+	assertStats(t, cache.Stats(), Stats{Evictions: 1, Adds: 4})
+}
+
+func TestLRUCacheRepeatedGet(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Moving the same item to the head more than once shouldn't leave it linked to its old neighbours
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("b")
+	assertKeys(t, cache.Keys(), []string{"b", "a", "c"})
+}
+
+func TestLRUCacheReadOptimized(t *testing.T) {
+	cache := CreateLRUCacheReadOptimized(30)
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Accessing "a" is buffered, but should be applied before the next Add evicts so "b" goes instead
+	if _, present := cache.Get("a"); !present {
+		t.Error("a should be present")
+	}
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+
+	if !cache.Contains("a") {
+		t.Error("a should be present as it was accessed")
+	}
+	if cache.Contains("b") {
+		t.Error("b should have been evicted")
+	}
+	assertKeys(t, cache.Keys(), []string{"d", "a", "c"})
+
+	// Buffered moves for removed items shouldn't bring them back
+	cache.Get("c")
+	cache.Remove("c")
+	cache.Add("e", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"e", "d", "a"})
+}
+
+func TestLRUCacheReadOptimizedFullBuffer(t *testing.T) {
+	cache := CreateLRUCacheReadOptimized(30)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Fill the buffer with moves for "b", the Get for "a" finds it full and should apply them then move "a" itself
+	for i := 0; i < maxPendingPromotions; i++ {
+		cache.Get("b")
+	}
+	cache.Get("a")
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"d", "a", "b"})
+
+	// Gets alone keep the order up to date, however many there are
+	for i := 0; i < maxPendingPromotions * 3; i++ {
+		cache.Get([]string{"b", "d"}[i % 2])
+	}
+	cache.Get("a")
+	cache.Get("b")
+	cache.Add("e", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"e", "b", "a"})
+}
+
+func TestLRUCacheReadOptimizedConcurrent(t *testing.T) {
+	cache := CreateLRUCacheReadOptimized(MaxSize)
+
+	// Hammer the cache with Gets and Adds from multiple goroutines, run with -race to check the locking
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := strconv.Itoa((g * i) % 20)
+				if i % 4 == 0 {
+					cache.Add(key, &DummyCacheItem{DummySize: 10})
+				} else {
+					cache.Get(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// Cache should still be consistent
+	if cache.Size() > MaxSize {
+		t.Error("Size should be at most", MaxSize, "got", cache.Size())
+	}
+	if len(cache.Keys()) != cache.Len() || cache.Size() != cache.Len() * 10 {
+		t.Error("Cache is inconsistent,", len(cache.Keys()), "keys,", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func BenchmarkLRUCacheGetParallel(b *testing.B) {
+	benchmarkGetParallel(b, CreateLRUCache(MaxSize), 100)
+}
+
+func BenchmarkLRUCacheReadOptimizedGetParallel(b *testing.B) {
+	benchmarkGetParallel(b, CreateLRUCacheReadOptimized(MaxSize), 100)
+}
+
+func BenchmarkLRUCacheGetOnlyParallel(b *testing.B) {
+	benchmarkGetParallel(b, CreateLRUCache(MaxSize), 0)
+}
+
+func BenchmarkLRUCacheReadOptimizedGetOnlyParallel(b *testing.B) {
+	benchmarkGetParallel(b, CreateLRUCacheReadOptimized(MaxSize), 0)
+}
+
+// benchmarkGetParallel fills the cache and then Gets from it in parallel, with an Add every addEvery operations (never
+// if its 0)
+func benchmarkGetParallel(b *testing.B, cache Cache, addEvery int) {
+	keys := make([]string, 10)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+		cache.Add(keys[i], &DummyCacheItem{DummySize: 10})
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i % len(keys)]
+			if addEvery > 0 && i % addEvery == 0 {
+				cache.Add(key, &DummyCacheItem{DummySize: 10})
+			} else {
+				cache.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func TestLRUCacheUpdateSize(t *testing.T) {
+	testUpdateSize(t, CreateLRUCache(30))
+}
+
+func TestLFUCacheUpdateSize(t *testing.T) {
+	testUpdateSize(t, CreateLFUCache(30))
+}
+
+func TestARCCacheUpdateSize(t *testing.T) {
+	testUpdateSize(t, CreateARCCache(30))
+}
+
+func TestTwoQueueCacheUpdateSize(t *testing.T) {
+	testUpdateSize(t, CreateTwoQueueCache(30))
+}
+
+func TestClockCacheUpdateSize(t *testing.T) {
+	testUpdateSize(t, CreateClockCache(30))
+}
+
+// testUpdateSize grows an item in place and checks the cache size follows it when UpdateSize is called
+func testUpdateSize(t *testing.T, cache Cache) {
+	t.Helper()
+
+	grows := &DataCacheItem{Data: []byte("0123456789")}
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("grows", grows)
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// Growing the data on its own doesn't change the cache size
+	grows.Data = append(grows.Data, "0123456789"...)
+	if cache.Size() != 30 {
+		t.Error("Size should still be 30 before UpdateSize, got", cache.Size())
+	}
+
+	// Update should take the size up and push "a" out to make room
+	if err := cache.UpdateSize("grows"); err != nil {
+		t.Error("Unexpected error updating size:", err)
+	}
+	if cache.Size() != 30 || cache.Len() != 2 {
+		t.Error("Expected 2 items of size 30, got", cache.Len(), "items of size", cache.Size())
+	}
+	if cache.Contains("a") || !cache.Contains("grows") || !cache.Contains("b") {
+		t.Error("Expected a to be evicted, got", cache.Keys())
+	}
+
+	// Removing should take off the new size, not the old one
+	cache.Remove("grows")
+	if cache.Size() != 10 {
+		t.Error("Size should be 10 after Remove, got", cache.Size())
+	}
+
+	// Growing past max size should remove it
+	cache.Add("grows", grows)
+	grows.Data = append(grows.Data, "0123456789012345678901234567890"...)
+	if err := cache.UpdateSize("grows"); err == nil {
+		t.Error("Expected an error as the item no longer fits")
+	}
+	if cache.Contains("grows") || cache.Size() != 10 {
+		t.Error("Expected grows to be removed, got", cache.Keys(), "of size", cache.Size())
+	}
+
+	// Missing keys are ignored
+	if err := cache.UpdateSize("missing"); err != nil {
+		t.Error("Unexpected error updating a missing key:", err)
+	}
+}
+
+func TestLRUCacheReAddLarger(t *testing.T) {
+	testReAddLarger(t, CreateLRUCache(30))
+}
+
+func TestLFUCacheReAddLarger(t *testing.T) {
+	testReAddLarger(t, CreateLFUCache(30))
+}
+
+func TestARCCacheReAddLarger(t *testing.T) {
+	testReAddLarger(t, CreateARCCache(30))
+}
+
+func TestTwoQueueCacheReAddLarger(t *testing.T) {
+	testReAddLarger(t, CreateTwoQueueCache(30))
+}
+
+func TestClockCacheReAddLarger(t *testing.T) {
+	testReAddLarger(t, CreateClockCache(30))
+}
+
+// testReAddLarger re-adds keys with larger values, both the same item grown in place and a different item
+func testReAddLarger(t *testing.T, cache Cache) {
+	t.Helper()
+
+	// Same item that's grown since it was added, re-adding should pick up the new size and make room
+	grows := &DataCacheItem{Data: []byte("0123456789")}
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("grows", grows)
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	grows.Data = append(grows.Data, "0123456789"...)
+	if err := cache.Add("grows", grows); err != nil {
+		t.Error("Unexpected error re-adding:", err)
+	}
+	if cache.Size() != 30 || cache.Len() != 2 || cache.Contains("a") {
+		t.Error("Expected a to be evicted leaving 2 items of size 30, got", cache.Keys(), "of size", cache.Size())
+	}
+
+	// Different item under the same key that's larger, old size should come off and the new one go on
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	if err := cache.Add("b", &DummyCacheItem{DummySize: 20}); err != nil {
+		t.Error("Unexpected error replacing:", err)
+	}
+	if cache.Size() != 20 || cache.Len() != 1 || !cache.Contains("b") {
+		t.Error("Expected only b of size 20, got", cache.Keys(), "of size", cache.Size())
+	}
+
+	// Different item that's too big should leave the old one where it is
+	if err := cache.Add("b", &DummyCacheItem{DummySize: 40}); err == nil {
+		t.Error("Expected an error as the item is too big")
+	}
+	if item, present := cache.Peek("b"); !present || item.Size() != 20 || cache.Size() != 20 {
+		t.Error("Expected b of size 20 to be left, got", item, "of size", cache.Size())
+	}
+
+	// Same item that's grown too big should be removed as it can't stay
+	cache.Add("grows", grows)
+	grows.Data = append(grows.Data, "0123456789012345678901234567890"...)
+	if err := cache.Add("grows", grows); err == nil {
+		t.Error("Expected an error as the item has grown too big")
+	}
+	if cache.Contains("grows") {
+		t.Error("Expected grows to be removed")
+	}
+}
+
+func TestLRUCacheByCount(t *testing.T) {
+	cache := CreateLRUCacheByCount(5)
+
+	// Sizes are way over anything we'd allow by size, but only the count matters
+	for i := 0; i < 8; i++ {
+		if err := cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 1000}); err != nil {
+			t.Error("Unexpected error adding:", err)
+		}
+	}
+
+	// Oldest should have been evicted to leave exactly 5
+	if cache.Len() != 5 || cache.Size() != 5000 {
+		t.Error("Expected 5 items of size 5000, got", cache.Len(), "items of size", cache.Size())
+	}
+	assertKeys(t, cache.Keys(), []string{"7", "6", "5", "4", "3"})
+
+	// Re-adding a present key shouldn't evict anything
+	cache.Get("3")
+	cache.Add("4", &DummyCacheItem{DummySize: 1})
+	assertKeys(t, cache.Keys(), []string{"4", "3", "7", "6", "5"})
+}
+
+func TestLRUCacheBounded(t *testing.T) {
+	// Only bounded by size
+	cache := CreateLRUCacheBounded(30, 0)
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	assertKeys(t, cache.Keys(), []string{"9", "8", "7"})
+
+	// Only bounded by count
+	cache = CreateLRUCacheBounded(0, 3)
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 1000})
+	}
+	assertKeys(t, cache.Keys(), []string{"9", "8", "7"})
+	if cache.Size() != 3000 {
+		t.Error("Size should be 3000, got", cache.Size())
+	}
+
+	// Bounded by both, small items hit the count limit first and big items the size limit
+	cache = CreateLRUCacheBounded(100, 4)
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 1})
+	}
+	assertKeys(t, cache.Keys(), []string{"9", "8", "7", "6"})
+	cache.Add("big", &DummyCacheItem{DummySize: 98})
+	assertKeys(t, cache.Keys(), []string{"big", "9", "8"})
+
+	// Neither bounded
+	cache = CreateLRUCacheBounded(0, 0)
+	for i := 0; i < 100; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 1000})
+	}
+	if cache.Len() != 100 {
+		t.Error("Expected all 100 items to be kept, got", cache.Len())
+	}
+}
+
+func TestAddReturning(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"arc": CreateARCCache(MaxSize),
+		"2q": CreateTwoQueueCache(MaxSize),
+		"clock": CreateClockCache(MaxSize),
+		"random": CreateRandomCache(MaxSize),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+	} {
+		// Nothing there to begin with
+		original := &DummyCacheItem{DummySize: 10}
+		if prev, existed, err := cache.AddReturning("a", original); prev != nil || existed || err != nil {
+			t.Error(name, "expected nothing to be replaced, got", prev, existed, err)
+		}
+
+		// Replacing with a different item should return the original
+		replacement := &DummyCacheItem{DummySize: 10}
+		if prev, existed, err := cache.AddReturning("a", replacement); prev != original || !existed || err != nil {
+			t.Error(name, "expected original to be replaced, got", prev, existed, err)
+		}
+
+		// Re-adding the same item returns itself
+		if prev, existed, err := cache.AddReturning("a", replacement); prev != replacement || !existed || err != nil {
+			t.Error(name, "expected replacement to be returned, got", prev, existed, err)
+		}
+
+		// Failing to add returns nothing
+		if prev, existed, err := cache.AddReturning("a", &DummyCacheItem{DummySize: MaxSize + 1}); prev != nil || existed || err == nil {
+			t.Error(name, "expected an error and nothing replaced, got", prev, existed, err)
+		}
+	}
+}
+
+func TestTouch(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(30),
+		"lfu": CreateLFUCache(30),
+		"arc": CreateARCCache(30),
+		"clock": CreateClockCache(30),
+	} {
+		cache.Add("a", &DummyCacheItem{DummySize: 10})
+		cache.Add("b", &DummyCacheItem{DummySize: 10})
+		cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+		// Touching "a" should save it from being the next evicted
+		if !cache.Touch("a") || cache.Touch("missing") {
+			t.Error(name, "expected Touch to return whether the key is present")
+		}
+		cache.Add("d", &DummyCacheItem{DummySize: 10})
+		if !cache.Contains("a") || cache.Contains("b") {
+			t.Error(name, "expected b to be evicted instead of a, got", cache.Keys())
+		}
+		if stats := cache.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+			t.Error(name, "Touch shouldn't count as a hit or miss, got", stats)
+		}
+	}
+}
+
+func TestCreateLRUCacheChecked(t *testing.T) {
+	for _, maxsize := range []int{0, -1} {
+		if cache, err := CreateLRUCacheChecked(maxsize); cache != nil || err == nil || err.Error() != ErrorInvalidMaxSize {
+			t.Error("Expected an error for max size", maxsize, "got", cache, err)
+		}
+	}
+
+	cache, err := CreateLRUCacheChecked(MaxSize)
+	if cache == nil || err != nil {
+		t.Error("Expected a cache for a valid max size, got", cache, err)
+	}
+	if cache.Add("a", &DummyCacheItem{DummySize: 10}) != nil || cache.Cap() != MaxSize {
+		t.Error("Expected a working cache with cap", MaxSize)
+	}
+}
+
+func TestLRUCacheZeroMaxSize(t *testing.T) {
+	cache := CreateLRUCache(0)
+
+	// Unchecked caches just reject everything with a size
+	if err := cache.Add("a", &DummyCacheItem{DummySize: 1}); err == nil || err.Error() != ErrorExceedsMaxSize {
+		t.Error("Expected", ErrorExceedsMaxSize, "got", err)
+	}
+	if cache.Len() != 0 {
+		t.Error("Expected nothing to be stored, got", cache.Keys())
+	}
+}
+
+func TestLRUCacheWithMaxItemSize(t *testing.T) {
+	cache := CreateLRUCacheWithMaxItemSize(MaxSize, MaxSize / 10)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// Fits in the cache, but is too big for a single item so nothing should be flushed out
+	if err := cache.Add("big", &DummyCacheItem{DummySize: 50}); !errors.Is(err, ErrExceedsMaxItemSize) {
+		t.Error("Expected ErrExceedsMaxItemSize, got", err)
+	}
+	assertKeys(t, cache.Keys(), []string{"b", "a"})
+
+	// Bigger than the whole cache is still ErrExceedsMaxSize
+	if err := cache.Add("huge", &DummyCacheItem{DummySize: MaxSize + 1}); !errors.Is(err, ErrExceedsMaxSize) {
+		t.Error("Expected ErrExceedsMaxSize, got", err)
+	}
+
+	// Growing past the max item size should remove it
+	item := &DummyCacheItem{DummySize: 10}
+	cache.Add("c", item)
+	item.DummySize = 11
+	if err := cache.UpdateSize("c"); !errors.Is(err, ErrExceedsMaxItemSize) || cache.Contains("c") {
+		t.Error("Expected c to be removed with ErrExceedsMaxItemSize, got", err, cache.Keys())
+	}
+	if cache.Size() != 20 {
+		t.Error("Expected size 20, got", cache.Size())
+	}
+}
+
+func TestAddWithCost(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"arc": CreateARCCache(MaxSize),
+		"2q": CreateTwoQueueCache(MaxSize),
+		"clock": CreateClockCache(MaxSize),
+	} {
+		// Same Size but the costs add up to more than max size, so the cheap ones push out the expensive one
+		cache.AddWithCost("expensive", &DummyCacheItem{DummySize: 10}, 80)
+		cache.AddWithCost("cheap1", &DummyCacheItem{DummySize: 10}, 10)
+		cache.AddWithCost("cheap2", &DummyCacheItem{DummySize: 10}, 10)
+		if cache.Size() != 100 || cache.Len() != 3 {
+			t.Error(name, "expected 3 items of size 100, got", cache.Len(), "items of size", cache.Size())
+		}
+		cache.AddWithCost("cheap3", &DummyCacheItem{DummySize: 10}, 10)
+		if cache.Contains("expensive") || cache.Size() != 30 {
+			t.Error(name, "expected expensive to be evicted leaving a size of 30, got", cache.Keys(), cache.Size())
+		}
+
+		// Removing takes the cost back off, not the Size
+		cache.Remove("cheap1")
+		if cache.Size() != 20 {
+			t.Error(name, "expected size of 20 after Remove, got", cache.Size())
+		}
+
+		// Cost over max size is rejected
+		if err := cache.AddWithCost("huge", &DummyCacheItem{DummySize: 1}, MaxSize + 1); err == nil {
+			t.Error(name, "expected an error for a cost over max size")
+		}
+	}
+}
+
+func TestShardedCacheAddWithCost(t *testing.T) {
+	cache := CreateShardedCache(Shards, MaxSize)
+	cache.AddWithCost("a", &DummyCacheItem{DummySize: 10}, 50)
+	if cache.Size() != 50 {
+		t.Error("Expected size of 50, got", cache.Size())
+	}
+	cache.Remove("a")
+	if cache.Size() != 0 {
+		t.Error("Expected size of 0 after Remove, got", cache.Size())
+	}
+}
+
+func TestGetWithAge(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"arc": CreateARCCache(MaxSize),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+	} {
+		item := &DummyCacheItem{DummySize: 10}
+		cache.Add("a", item)
+
+		// Age should go up as time passes
+		got, first, present := cache.GetWithAge("a")
+		if !present || got != item {
+			t.Error(name, "expected a to be present, got", got, present)
+		}
+		time.Sleep(10 * time.Millisecond)
+		_, second, _ := cache.GetWithAge("a")
+		if second < first + 10 * time.Millisecond {
+			t.Error(name, "expected age to go up by at least 10ms, got", first, "then", second)
+		}
+
+		// Adding again restarts it
+		cache.Add("a", item)
+		if _, third, _ := cache.GetWithAge("a"); third >= second {
+			t.Error(name, "expected age to restart when added again, got", third, "after", second)
+		}
+
+		// Missing items have no age, and all the lookups count like Gets
+		if got, age, present := cache.GetWithAge("missing"); got != nil || age != 0 || present {
+			t.Error(name, "expected nothing for a missing key, got", got, age, present)
+		}
+		if stats := cache.Stats(); stats.Hits != 3 || stats.Misses != 1 {
+			t.Error(name, "expected 3 hits and 1 miss, got", stats)
+		}
+	}
+}
+
+func TestLRUCacheGetWithAgePromotes(t *testing.T) {
+	cache := CreateLRUCache(30)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Counts as an access so "a" moves to the head
+	cache.GetWithAge("a")
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"d", "a", "c"})
+}
+
+func TestLRUCacheDoubleRemove(t *testing.T) {
+	cache := CreateLRUCache(MaxSize).(*lruCache)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Removing the same item twice should only take its size off once and leave the list alone
+	item := cache.keyValMap["b"]
+	item.Remove(cache)
+	item.Remove(cache)
+	if cache.Size() != 20 || cache.Len() != 2 {
+		t.Error("Expected 2 items of size 20, got", cache.Len(), "items of size", cache.Size())
+	}
+	assertKeys(t, cache.Keys(), []string{"c", "a"})
+
+	// Same if its been replaced by a different item under the key
+	old := cache.keyValMap["a"]
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	old.Remove(cache)
+	if cache.Size() != 20 || !cache.Contains("a") {
+		t.Error("Expected the new a to be left alone, got", cache.Keys(), "of size", cache.Size())
+	}
+	assertKeys(t, cache.Keys(), []string{"a", "c"})
+}
+
+func TestLRUCacheResize(t *testing.T) {
+	testResize(t, CreateLRUCache(MaxSize))
+}
+
+func TestLFUCacheResize(t *testing.T) {
+	testResize(t, CreateLFUCache(MaxSize))
+}
+
+func TestARCCacheResize(t *testing.T) {
+	testResize(t, CreateARCCache(MaxSize))
+}
+
+func TestTwoQueueCacheResize(t *testing.T) {
+	testResize(t, CreateTwoQueueCache(MaxSize))
+}
+
+func TestClockCacheResize(t *testing.T) {
+	testResize(t, CreateClockCache(MaxSize))
+}
+
+// testResize shrinks and grows a full cache of items all accessed once, so every policy should evict the same ones
+func testResize(t *testing.T, cache Cache) {
+	t.Helper()
+
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Shrinking should drop the oldest items
+	cache.Resize(50)
+	if cache.Cap() != 50 || cache.Size() != 50 {
+		t.Error("Expected cap and size of 50, got", cache.Cap(), cache.Size())
+	}
+	assertKeys(t, cache.Keys(), []string{"9", "8", "7", "6", "5"})
+
+	// Growing should just raise the ceiling, nothing removed
+	cache.Resize(MaxSize)
+	cache.Add("10", &DummyCacheItem{DummySize: 10})
+	if cache.Cap() != MaxSize || cache.Len() != 6 {
+		t.Error("Expected cap of", MaxSize, "and 6 items, got", cache.Cap(), cache.Len())
+	}
+
+	// Shrinking below the size of an item should remove everything
+	cache.Resize(5)
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected empty cache, got", cache.Len(), "items of size", cache.Size())
+	}
+	if cache.Add("small", &DummyCacheItem{DummySize: 5}) != nil || !cache.Contains("small") {
+		t.Error("Expected an item within the new size to be added")
+	}
+}
+
+func TestRemoveFunc(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"arc": CreateARCCache(MaxSize),
+		"2q": CreateTwoQueueCache(MaxSize),
+		"clock": CreateClockCache(MaxSize),
+		"random": CreateRandomCache(MaxSize),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+	} {
+		for i := 0; i < 4; i++ {
+			cache.Add("tenant1:" + strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+			cache.Add("tenant2:" + strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+		}
+
+		cache.RemoveFunc(func(key string, item CacheItem) bool {
+			return strings.HasPrefix(key, "tenant1:")
+		})
+
+		// Only tenant2 should be left, and the size should have come down
+		if cache.Len() != 4 || cache.Size() != 40 {
+			t.Error(name, "expected 4 items of size 40, got", cache.Len(), "items of size", cache.Size())
+		}
+		for i := 0; i < 4; i++ {
+			if cache.Contains("tenant1:" + strconv.Itoa(i)) || !cache.Contains("tenant2:" + strconv.Itoa(i)) {
+				t.Error(name, "expected only tenant2 keys, got", cache.Keys())
+			}
+		}
+
+		// Cache should still work afterwards
+		cache.Add("tenant1:new", &DummyCacheItem{DummySize: 10})
+		if !cache.Contains("tenant1:new") || len(cache.Keys()) != 5 {
+			t.Error(name, "expected 5 keys after adding, got", cache.Keys())
+		}
+	}
+}
+
+func TestForEach(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"arc": CreateARCCache(MaxSize),
+		"2q": CreateTwoQueueCache(MaxSize),
+		"clock": CreateClockCache(MaxSize),
+		"random": CreateRandomCache(MaxSize),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+	} {
+		for i := 0; i < 5; i++ {
+			cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+		}
+		cache.Get("2")
+		keys := cache.Keys()
+		stats := cache.Stats()
+
+		// Should visit everything in the same order as Keys
+		visited := []string{}
+		cache.ForEach(func(key string, item CacheItem) bool {
+			visited = append(visited, key)
+			return true
+		})
+		assertKeys(t, visited, keys)
+
+		// Stopping early should stop
+		visited = []string{}
+		cache.ForEach(func(key string, item CacheItem) bool {
+			visited = append(visited, key)
+			return len(visited) < 2
+		})
+		assertKeys(t, visited, keys[:2])
+
+		// Shouldn't have reordered anything or counted as accesses
+		assertKeys(t, cache.Keys(), keys)
+		if cache.Stats() != stats {
+			t.Errorf("%s expected ForEach not to change stats %+v, got %+v", name, stats, cache.Stats())
+		}
+	}
+}
+
+func assertStats(t *testing.T, actual Stats, expected Stats) {
+	t.Helper()
+	if actual != expected {
+		t.Errorf("Expected stats %+v got %+v", expected, actual)
+	}
+}
+
+func assertKeys(t *testing.T, actual []string, expected []string) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Error("Expected keys", expected, "got", actual)
+		return
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Error("Expected keys", expected, "got", actual)
+			return
+		}
+	}
+}
+
 type DummyCacheItem struct {
 	DummySize int
 }
 
 func (this *DummyCacheItem) Size() int {
 	return this.DummySize
+}
+
+type DataCacheItem struct {
+	Data []byte
+}
+
+func (this *DataCacheItem) Size() int {
+	return len(this.Data)
+}
+
+func TestGetOrErr(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"timed": CreateTimedCache(TestTTL),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+		"namespaced": NewNamespacedCache(CreateLRUCache(MaxSize), "ns"),
+	} {
+		item := &DummyCacheItem{DummySize: 10}
+		cache.Add("a", item)
+		if got, err := cache.GetOrErr("a"); got != item || err != nil {
+			t.Error(name, "expected a to be found, got", got, err)
+		}
+
+		// A miss should be the sentinel, even once its been wrapped
+		_, err := cache.GetOrErr("missing")
+		if !errors.Is(fmt.Errorf("loading missing: %w", err), ErrNotFound) {
+			t.Error(name, "expected ErrNotFound, got", err)
+		}
+		assertStats(t, cache.Stats(), Stats{Hits: 1, Misses: 1, Adds: 1})
+	}
+
+	if _, err := CreateNullCache().GetOrErr("a"); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound from the null cache, got", err)
+	}
+}
+
+func TestErrExceedsMaxSize(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+	} {
+		// Should be the sentinel, with the same message as before
+		err := cache.Add("a", &DummyCacheItem{DummySize: MaxSize + 1})
+		if !errors.Is(err, ErrExceedsMaxSize) || err.Error() != ErrorExceedsMaxSize {
+			t.Error(name, "expected ErrExceedsMaxSize from Add, got", err)
+		}
+
+		// Growing past the max size in UpdateSize should be the same error
+		item := &DummyCacheItem{DummySize: 10}
+		cache.Add("b", item)
+		item.DummySize = MaxSize + 1
+		if err := cache.UpdateSize("b"); !errors.Is(err, ErrExceedsMaxSize) {
+			t.Error(name, "expected ErrExceedsMaxSize from UpdateSize, got", err)
+		}
+	}
+}
+
+func TestGetAll(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"timed": CreateTimedCache(TestTTL),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+		"namespaced": NewNamespacedCache(CreateLRUCache(MaxSize), "ns"),
+	} {
+		items := map[string]CacheItem{}
+		for _, key := range []string{"a", "b", "c"} {
+			items[key] = &DummyCacheItem{DummySize: 10}
+			cache.Add(key, items[key])
+		}
+
+		// Should be exactly what's resident, without counting as accesses or moving anything (timed keys come back in no
+		// particular order so they're sorted)
+		keys := cache.Keys()
+		slices.Sort(keys)
+		all := cache.GetAll()
+		if len(all) != 3 || all["a"] != items["a"] || all["b"] != items["b"] || all["c"] != items["c"] {
+			t.Error(name, "expected a snapshot of a, b and c, got", all)
+		}
+		after := cache.Keys()
+		slices.Sort(after)
+		assertKeys(t, after, keys)
+		assertStats(t, cache.Stats(), Stats{Adds: 3})
+
+		// Changing the cache shouldn't change the snapshot, or the other way round
+		cache.Remove("a")
+		cache.Add("d", &DummyCacheItem{DummySize: 10})
+		if len(all) != 3 || all["a"] != items["a"] || all["d"] != nil {
+			t.Error(name, "expected the snapshot not to change, got", all)
+		}
+		delete(all, "b")
+		if !cache.Contains("b") {
+			t.Error(name, "expected b to still be in the cache")
+		}
+	}
+}
+
+func TestNegativeSize(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"clock": CreateClockCache(MaxSize),
+		"timed": CreateTimedCache(TestTTL),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+	} {
+		// Should be rejected rather than taking the size below 0
+		if err := cache.Add("a", &DummyCacheItem{DummySize: -10}); !errors.Is(err, ErrNegativeSize) {
+			t.Error(name, "expected ErrNegativeSize from Add, got", err)
+		}
+		if err := cache.AddWithCost("a", &DummyCacheItem{DummySize: 10}, -1); !errors.Is(err, ErrNegativeSize) {
+			t.Error(name, "expected ErrNegativeSize from AddWithCost, got", err)
+		}
+		if cache.Len() != 0 || cache.Size() != 0 {
+			t.Error(name, "expected nothing to be stored, got", cache.Len(), "items of size", cache.Size())
+		}
+
+		// Going negative after its added should remove it
+		item := &DummyCacheItem{DummySize: 10}
+		cache.Add("b", item)
+		item.DummySize = -10
+		if err := cache.UpdateSize("b"); !errors.Is(err, ErrNegativeSize) || cache.Contains("b") || cache.Size() != 0 {
+			t.Error(name, "expected b to be removed with ErrNegativeSize, got", err, cache.Keys())
+		}
+	}
+
+	if err := ValidateItem(&DummyCacheItem{DummySize: -1}); !errors.Is(err, ErrNegativeSize) {
+		t.Error("Expected ValidateItem to return ErrNegativeSize, got", err)
+	}
+	if err := ValidateItem(&DummyCacheItem{DummySize: 0}); err != nil {
+		t.Error("Expected ValidateItem to accept a size of 0, got", err)
+	}
+}
+
+func TestNilItem(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"sizefunc": CreateLRUCacheWithSizeFunc(MaxSize, func(item CacheItem) int { return item.Size() }),
+		"lfu": CreateLFUCache(MaxSize),
+		"clock": CreateClockCache(MaxSize),
+		"timed": CreateTimedCache(TestTTL),
+		"sharded": CreateShardedCache(Shards, MaxSize),
+	} {
+		// Fresh adds should be rejected without panicking
+		if err := cache.Add("a", nil); !errors.Is(err, ErrNilItem) {
+			t.Error(name, "expected ErrNilItem from Add, got", err)
+		}
+		if _, _, err := cache.AddReturning("a", nil); !errors.Is(err, ErrNilItem) {
+			t.Error(name, "expected ErrNilItem from AddReturning, got", err)
+		}
+		if added, err := cache.AddIfAbsent("a", nil); added || !errors.Is(err, ErrNilItem) {
+			t.Error(name, "expected ErrNilItem from AddIfAbsent, got", added, err)
+		}
+		if cache.Len() != 0 || cache.Contains("a") {
+			t.Error(name, "expected nothing to be stored, got", cache.Keys())
+		}
+
+		// Overwriting should leave the existing item in place
+		item := &DummyCacheItem{DummySize: 10}
+		cache.Add("b", item)
+		if err := cache.Add("b", nil); !errors.Is(err, ErrNilItem) {
+			t.Error(name, "expected ErrNilItem overwriting, got", err)
+		}
+		if replaced, err := cache.Replace("b", nil); replaced || !errors.Is(err, ErrNilItem) {
+			t.Error(name, "expected ErrNilItem from Replace, got", replaced, err)
+		}
+		if got, _ := cache.Get("b"); got != item || cache.Size() != 10 || cache.Stats().Adds != 1 {
+			t.Error(name, "expected b to be unchanged, got", got, cache.Size(), cache.Stats())
+		}
+	}
+
+	if err := ValidateItem(nil); !errors.Is(err, ErrNilItem) {
+		t.Error("Expected ValidateItem to return ErrNilItem, got", err)
+	}
+}
+
+func TestAddIfAbsent(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(30),
+		"lfu": CreateLFUCache(30),
+		"timed": CreateTimedCache(TestTTL),
+		"sharded": CreateShardedCache(1, 30),
+		"namespaced": NewNamespacedCache(CreateLRUCache(30), "ns"),
+	} {
+		first, second := &DummyCacheItem{DummySize: 10}, &DummyCacheItem{DummySize: 10}
+		if added, err := cache.AddIfAbsent("a", first); !added || err != nil {
+			t.Error(name, "expected a to be added, got", added, err)
+		}
+		cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+		// Already there, so the first item should stay and not be treated as accessed
+		if added, err := cache.AddIfAbsent("a", second); added || err != nil {
+			t.Error(name, "expected a not to be added again, got", added, err)
+		}
+		if item, _ := cache.Peek("a"); item != first {
+			t.Error(name, "expected the first item to be left, got", item)
+		}
+		if keys := cache.Keys(); name != "timed" && keys[len(keys) - 1] != "a" {
+			t.Error(name, "expected a to still be next to go, got", keys)
+		}
+
+		// Errors from adding come back as they are
+		if added, err := cache.AddIfAbsent("c", &DummyCacheItem{DummySize: -1}); added || !errors.Is(err, ErrNegativeSize) {
+			t.Error(name, "expected ErrNegativeSize, got", added, err)
+		}
+	}
+}
+
+func TestAddIfAbsentConcurrent(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(MaxSize),
+		"lfu": CreateLFUCache(MaxSize),
+		"timed": CreateTimedCache(TestTTL),
+	} {
+		// Only one goroutine should win each key
+		var wg sync.WaitGroup
+		var mutex sync.Mutex
+		winners := map[string]int{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, key := range []string{"a", "b", "c"} {
+					if added, _ := cache.AddIfAbsent(key, &DummyCacheItem{DummySize: 1}); added {
+						mutex.Lock()
+						winners[key]++
+						mutex.Unlock()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		for _, key := range []string{"a", "b", "c"} {
+			if winners[key] != 1 {
+				t.Error(name, "expected exactly one goroutine to add", key, "got", winners[key])
+			}
+		}
+	}
+}
+
+func TestLRUCacheAddIfAbsentExpired(t *testing.T) {
+	cache := CreateLRUCacheWithTTL(MaxSize, TestTTL)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	time.Sleep(TestTTL * 2)
+
+	// Expired counts as absent
+	item := &DummyCacheItem{DummySize: 10}
+	if added, err := cache.AddIfAbsent("a", item); !added || err != nil {
+		t.Error("Expected the expired item to be replaced, got", added, err)
+	}
+	if got, _ := cache.Get("a"); got != item || cache.Size() != 10 {
+		t.Error("Expected the new item, got", got, "with size", cache.Size())
+	}
+}
+
+func TestReplace(t *testing.T) {
+	for name, cache := range map[string]Cache {
+		"lru": CreateLRUCache(30),
+		"lfu": CreateLFUCache(30),
+		"timed": CreateTimedCache(TestTTL),
+		"sharded": CreateShardedCache(1, 30),
+		"namespaced": NewNamespacedCache(CreateLRUCache(30), "ns"),
+	} {
+		// Missing keys are left missing
+		if replaced, err := cache.Replace("a", &DummyCacheItem{DummySize: 10}); replaced || err != nil {
+			t.Error(name, "expected nothing to be replaced, got", replaced, err)
+		}
+		if cache.Len() != 0 || cache.Stats().Adds != 0 {
+			t.Error(name, "expected Replace on a missing key to do nothing, got", cache.Keys())
+		}
+
+		// Present keys get the new item and its size
+		cache.Add("a", &DummyCacheItem{DummySize: 10})
+		cache.Add("b", &DummyCacheItem{DummySize: 10})
+		item := &DummyCacheItem{DummySize: 15}
+		if replaced, err := cache.Replace("a", item); !replaced || err != nil {
+			t.Error(name, "expected a to be replaced, got", replaced, err)
+		}
+		if got, _ := cache.Peek("a"); got != item || cache.Size() != 25 {
+			t.Error(name, "expected the new item and size 25, got", got, cache.Size())
+		}
+		if name == "lru" && cache.Keys()[0] != "a" {
+			t.Error(name, "expected a to be moved to the head, got", cache.Keys())
+		}
+	}
+}
+
+func TestLRUCacheString(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+	if got := fmt.Sprint(cache); got != "size 0/100, 0 items []" {
+		t.Error("Unexpected dump of an empty cache", got)
+	}
+
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 20})
+	cache.Add("c", &DummyCacheItem{DummySize: 5})
+	cache.Get("a")
+
+	// Dumping twice shouldn't change anything, it isn't an access
+	expected := "size 35/100, 3 items [a:10 c:5 b:20]"
+	for i := 0; i < 2; i++ {
+		if got := fmt.Sprint(cache); got != expected {
+			t.Error("Expected", expected, "got", got)
+		}
+	}
+	assertKeys(t, cache.Keys(), []string{"a", "c", "b"})
+	assertStats(t, cache.Stats(), Stats{Hits: 1, Adds: 3})
 }
\ No newline at end of file
@@ -0,0 +1,46 @@
+package memcache
+
+const (
+	// MetricHits is the Metrics key for the number of Gets that found their item
+	MetricHits = "hits"
+
+	// MetricMisses is the Metrics key for the number of Gets that didn't find their item
+	MetricMisses = "misses"
+
+	// MetricEvictions is the Metrics key for the number of items removed to make room for others
+	MetricEvictions = "evictions"
+
+	// MetricExpirations is the Metrics key for the number of items removed because they'd expired
+	MetricExpirations = "expirations"
+
+	// MetricAdds is the Metrics key for the number of items successfully added
+	MetricAdds = "adds"
+
+	// MetricSize is the Metrics key for the total size of the items in the cache
+	MetricSize = "size"
+
+	// MetricLen is the Metrics key for the number of items in the cache
+	MetricLen = "len"
+
+	// MetricCapacity is the Metrics key for the maximum total size of the cache
+	MetricCapacity = "capacity"
+)
+
+// Metrics returns the cache's Stats, Size, Len and Cap in a map keyed by the Metric constants
+//
+// Its meant for feeding into expvar or a metrics library without the cache having to know about it. The keys won't
+// change, new metrics may be added under new keys. The values are read one after another so they're not a consistent
+// snapshot if the cache is being used at the same time
+func Metrics(cache Cache) map[string]int64 {
+	stats := cache.Stats()
+	return map[string]int64 {
+		MetricHits: int64(stats.Hits),
+		MetricMisses: int64(stats.Misses),
+		MetricEvictions: int64(stats.Evictions),
+		MetricExpirations: int64(stats.Expirations),
+		MetricAdds: int64(stats.Adds),
+		MetricSize: int64(cache.Size()),
+		MetricLen: int64(cache.Len()),
+		MetricCapacity: int64(cache.Cap()),
+	}
+}
\ No newline at end of file
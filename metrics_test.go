@@ -0,0 +1,50 @@
+package memcache
+
+import (
+	"expvar"
+	"fmt"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	cache := CreateLRUCache(20)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 5})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	cache.Get("c")
+	cache.Get("a")
+
+	expected := map[string]int64 {
+		"hits": 1,
+		"misses": 1,
+		"evictions": 1,
+		"expirations": 0,
+		"adds": 3,
+		"size": 15,
+		"len": 2,
+		"capacity": 20,
+	}
+	metrics := Metrics(cache)
+	if len(metrics) != len(expected) {
+		t.Error("Expected", len(expected), "metrics, got", metrics)
+	}
+	for key, value := range expected {
+		if got, present := metrics[key]; !present || got != value {
+			t.Error("Expected", key, "to be", value, "got", got, present)
+		}
+	}
+}
+
+func ExampleMetrics() {
+	cache := CreateLRUCache(1024)
+
+	// expvar.Func is called each time /debug/vars is read, so the metrics are always current
+	expvar.Publish("cache", expvar.Func(func() any {
+		return Metrics(cache)
+	}))
+
+	cache.Add("a", NewString("hello"))
+	cache.Get("a")
+	fmt.Println(expvar.Get("cache"))
+	// Output: {"adds":1,"capacity":1024,"evictions":0,"expirations":0,"hits":1,"len":1,"misses":0,"size":5}
+}
\ No newline at end of file
@@ -0,0 +1,197 @@
+package memcache
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// prefixEscaper escapes the separator in namespace prefixes, and the escape character itself
+var prefixEscaper = strings.NewReplacer(`\`, `\\`, ":", `\:`)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// NewNamespacedCache creates and returns a view of underlying that only sees keys in its own namespace
+//
+// Keys are stored in underlying as prefix + ":" + key, and the prefix is taken off again by Keys and ForEach. Any ":" or
+// "\" in the prefix is escaped with a "\", so the first unescaped ":" always ends the prefix and a namespace like "a"
+// doesn't see the keys of "a:b". Several views with different prefixes can share one underlying cache without their keys
+// colliding. Clear, RemoveFunc, Len and Size only cover the view's namespace, but Cap, Resize and Stats are the shared
+// underlying cache's
+func NewNamespacedCache(underlying Cache, prefix string) (Cache) {
+	return &namespacedCache { Cache: underlying, prefix: prefixEscaper.Replace(prefix) + ":" }
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: namespacedCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// namespacedCache wraps a Cache and puts a prefix in front of every key
+type namespacedCache struct {
+	Cache
+
+	// prefix is put in front of every key, escaped and including the ":" separator
+	prefix string
+}
+
+// key returns the key used in the underlying cache
+func (this *namespacedCache) key(key string) string {
+	return this.prefix + key
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Cache Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// Add adds a CacheItem to the namespace
+func (this *namespacedCache) Add(key string, val CacheItem) error {
+	return this.Cache.Add(this.key(key), val)
+}
+
+// Get retrieves an item from the namespace if its present
+func (this *namespacedCache) Get(key string) (CacheItem, bool) {
+	return this.Cache.Get(this.key(key))
+}
+
+// GetOrErr retrieves an item from the namespace, returning ErrNotFound if it isn't present
+func (this *namespacedCache) GetOrErr(key string) (CacheItem, error) {
+	return getOrErr(this.Get(key))
+}
+
+// Remove removes an item from the namespace
+func (this *namespacedCache) Remove(key string) {
+	this.Cache.Remove(this.key(key))
+}
+
+// Len returns the number of items in the namespace
+//
+// The underlying cache has to be gone through to count them, so this is O(n) in the size of the whole cache
+func (this *namespacedCache) Len() int {
+	count := 0
+	this.ForEach(func(key string, item CacheItem) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+// Size returns the total Size of the items in the namespace
+//
+// Like Len this goes through the whole underlying cache. Its the items' Size, so items added with AddWithCost count
+// their Size rather than their cost
+func (this *namespacedCache) Size() int {
+	size := 0
+	this.ForEach(func(key string, item CacheItem) bool {
+		size += item.Size()
+		return true
+	})
+	return size
+}
+
+// Clear removes all the items in the namespace, items in other namespaces are left alone
+func (this *namespacedCache) Clear() {
+	this.RemoveFunc(func(key string, item CacheItem) bool {
+		return true
+	})
+}
+
+// Keys returns the keys of the items in the namespace, without the prefix
+func (this *namespacedCache) Keys() []string {
+	keys := []string{}
+	for _, key := range this.Cache.Keys() {
+		if stripped, inNamespace := strings.CutPrefix(key, this.prefix); inNamespace {
+			keys = append(keys, stripped)
+		}
+	}
+	return keys
+}
+
+// GetAll returns a snapshot of the items in the namespace, keyed without the prefix
+func (this *namespacedCache) GetAll() map[string]CacheItem {
+	all := map[string]CacheItem{}
+	this.ForEach(func(key string, item CacheItem) bool {
+		all[key] = item
+		return true
+	})
+	return all
+}
+
+// Peek retrieves an item from the namespace if its present, without counting as an access
+func (this *namespacedCache) Peek(key string) (CacheItem, bool) {
+	return this.Cache.Peek(this.key(key))
+}
+
+// Contains returns true if the item is present in the namespace, without counting as an access
+func (this *namespacedCache) Contains(key string) bool {
+	return this.Cache.Contains(this.key(key))
+}
+
+// GetOrAdd retrieves an item from the namespace, computing and adding it if its missing
+func (this *namespacedCache) GetOrAdd(key string, compute func() (CacheItem, error)) (CacheItem, error) {
+	return this.Cache.GetOrAdd(this.key(key), compute)
+}
+
+// UpdateSize re-reads the Size of an item in the namespace
+func (this *namespacedCache) UpdateSize(key string) error {
+	return this.Cache.UpdateSize(this.key(key))
+}
+
+// AddReturning adds a CacheItem to the namespace like Add, and returns the item it replaced
+func (this *namespacedCache) AddReturning(key string, val CacheItem) (CacheItem, bool, error) {
+	return this.Cache.AddReturning(this.key(key), val)
+}
+
+// RemoveFunc removes every item in the namespace that pred returns true for, pred is called with the prefix taken off
+func (this *namespacedCache) RemoveFunc(pred func(key string, item CacheItem) bool) {
+	this.Cache.RemoveFunc(func(key string, item CacheItem) bool {
+		stripped, inNamespace := strings.CutPrefix(key, this.prefix)
+		return inNamespace && pred(stripped, item)
+	})
+}
+
+// ForEach calls fn for every item in the namespace until it returns false, fn is called with the prefix taken off
+func (this *namespacedCache) ForEach(fn func(key string, item CacheItem) bool) {
+	this.Cache.ForEach(func(key string, item CacheItem) bool {
+		if stripped, inNamespace := strings.CutPrefix(key, this.prefix); inNamespace {
+			return fn(stripped, item)
+		}
+		return true
+	})
+}
+
+// GetOrAddContext retrieves an item from the namespace like GetOrAdd, giving up if ctx is done first
+func (this *namespacedCache) GetOrAddContext(ctx context.Context, key string, compute func(ctx context.Context) (CacheItem, error)) (CacheItem, error) {
+	return this.Cache.GetOrAddContext(ctx, this.key(key), compute)
+}
+
+// Touch marks an item in the namespace as used without retrieving it
+func (this *namespacedCache) Touch(key string) bool {
+	return this.Cache.Touch(this.key(key))
+}
+
+// AddWithCost adds a CacheItem to the namespace like Add, counting cost towards the size of the underlying cache
+func (this *namespacedCache) AddWithCost(key string, val CacheItem, cost int) error {
+	return this.Cache.AddWithCost(this.key(key), val, cost)
+}
+
+// AddIfAbsent adds a CacheItem to the namespace, but only if nothing is stored under the key
+func (this *namespacedCache) AddIfAbsent(key string, val CacheItem) (bool, error) {
+	return this.Cache.AddIfAbsent(this.key(key), val)
+}
+
+// Replace replaces the item stored under the key in the namespace, but only if there is one
+func (this *namespacedCache) Replace(key string, val CacheItem) (bool, error) {
+	return this.Cache.Replace(this.key(key), val)
+}
+
+// GetWithAge retrieves an item from the namespace, along with how long its been since it was added
+func (this *namespacedCache) GetWithAge(key string) (CacheItem, time.Duration, bool) {
+	return this.Cache.GetWithAge(this.key(key))
+}
+
+// GetStaleWhileRevalidate retrieves an item from the namespace like GetStaleWhileRevalidate
+func (this *namespacedCache) GetStaleWhileRevalidate(key string, ttl, staleWindow time.Duration, refresh func() (CacheItem, error)) (CacheItem, bool) {
+	return this.Cache.GetStaleWhileRevalidate(this.key(key), ttl, staleWindow, refresh)
+}
\ No newline at end of file
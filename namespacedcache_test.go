@@ -0,0 +1,95 @@
+package memcache
+
+import (
+	"testing"
+)
+
+func TestNamespacedCache(t *testing.T) {
+	backing := CreateLRUCache(MaxSize)
+	users := NewNamespacedCache(backing, "users")
+	posts := NewNamespacedCache(backing, "posts")
+
+	// Same key in both namespaces shouldn't collide
+	userItem, postItem := &DummyCacheItem{DummySize: 10}, &DummyCacheItem{DummySize: 20}
+	users.Add("1", userItem)
+	posts.Add("1", postItem)
+	if item, _ := users.Get("1"); item != userItem {
+		t.Error("Expected users to get its own item")
+	}
+	if item, _ := posts.Get("1"); item != postItem {
+		t.Error("Expected posts to get its own item")
+	}
+
+	// Both are stored in the backing cache under their prefixed keys
+	assertKeys(t, backing.Keys(), []string{"posts:1", "users:1"})
+	assertKeys(t, users.Keys(), []string{"1"})
+	if users.Len() != 1 || users.Size() != 10 {
+		t.Error("Expected users to have 1 item of size 10, got", users.Len(), "items of size", users.Size())
+	}
+
+	// Removing from one namespace leaves the other
+	users.Remove("1")
+	if users.Contains("1") || !posts.Contains("1") {
+		t.Error("Expected only users' 1 to be removed")
+	}
+}
+
+func TestNamespacedCacheIsolation(t *testing.T) {
+	backing := CreateLRUCache(MaxSize)
+	users := NewNamespacedCache(backing, "users")
+	posts := NewNamespacedCache(backing, "posts")
+	for _, key := range []string{"a", "b", "c"} {
+		users.Add(key, &DummyCacheItem{DummySize: 10})
+		posts.Add(key, &DummyCacheItem{DummySize: 10})
+	}
+	backing.Add("unprefixed", &DummyCacheItem{DummySize: 10})
+
+	// ForEach should only see its own keys, without the prefix
+	seen := []string{}
+	users.ForEach(func(key string, item CacheItem) bool {
+		seen = append(seen, key)
+		return true
+	})
+	assertKeys(t, seen, []string{"c", "b", "a"})
+
+	// RemoveFunc is passed keys without the prefix and only removes from its own namespace
+	users.RemoveFunc(func(key string, item CacheItem) bool {
+		return key == "a" || key == "unprefixed"
+	})
+	assertKeys(t, users.Keys(), []string{"c", "b"})
+	assertKeys(t, posts.Keys(), []string{"c", "b", "a"})
+	if !backing.Contains("unprefixed") {
+		t.Error("Expected unprefixed key to be left alone")
+	}
+
+	// Clear only empties its own namespace
+	posts.Clear()
+	if posts.Len() != 0 {
+		t.Error("Expected posts to be empty, got", posts.Len(), "items")
+	}
+	assertKeys(t, backing.Keys(), []string{"unprefixed", "users:c", "users:b"})
+}
+
+func TestNamespacedCacheNestedPrefixes(t *testing.T) {
+	backing := CreateLRUCache(MaxSize)
+	outer := NewNamespacedCache(backing, "a")
+	nested := NewNamespacedCache(backing, "a:b")
+	escaped := NewNamespacedCache(backing, `a\`)
+	outer.Add("x", &DummyCacheItem{DummySize: 10})
+	nested.Add("x", &DummyCacheItem{DummySize: 10})
+	escaped.Add("x", &DummyCacheItem{DummySize: 10})
+
+	// A prefix that looks like it's inside another namespace is still separate from it
+	assertKeys(t, outer.Keys(), []string{"x"})
+	assertKeys(t, nested.Keys(), []string{"x"})
+	assertKeys(t, escaped.Keys(), []string{"x"})
+	if outer.Len() != 1 || outer.Size() != 10 {
+		t.Error("Expected a to have 1 item of size 10, got", outer.Len(), "items of size", outer.Size())
+	}
+
+	// Clearing one leaves the others
+	outer.Clear()
+	if !nested.Contains("x") || !escaped.Contains("x") {
+		t.Error("Expected clearing a to leave the other namespaces alone, got", backing.Keys())
+	}
+}
\ No newline at end of file
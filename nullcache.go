@@ -0,0 +1,159 @@
+package memcache
+
+import (
+	"context"
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateNullCache creates and returns a Cache that never stores anything
+//
+// Add always succeeds and Get always misses, so it can be passed in wherever a Cache is needed to turn caching off. The
+// GetOrAdd functions call compute every time and return its result
+func CreateNullCache() (Cache) {
+	return nullCache { }
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: nullCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// nullCache is the Cache returned by CreateNullCache, it has no state so every method is a no-op
+type nullCache struct {
+}
+
+// Add does nothing and returns nil
+func (this nullCache) Add(key string, val CacheItem) error {
+	return nil
+}
+
+// Get always returns nil, false
+func (this nullCache) Get(key string) (CacheItem, bool) {
+	return nil, false
+}
+
+// GetOrErr always returns nil, ErrNotFound
+func (this nullCache) GetOrErr(key string) (CacheItem, error) {
+	return nil, ErrNotFound
+}
+
+// Remove does nothing
+func (this nullCache) Remove(key string) {
+}
+
+// Len always returns 0
+func (this nullCache) Len() int {
+	return 0
+}
+
+// Size always returns 0
+func (this nullCache) Size() int {
+	return 0
+}
+
+// Cap always returns 0
+func (this nullCache) Cap() int {
+	return 0
+}
+
+// Clear does nothing
+func (this nullCache) Clear() {
+}
+
+// Keys always returns an empty slice
+func (this nullCache) Keys() []string {
+	return []string{}
+}
+
+// GetAll always returns an empty map
+func (this nullCache) GetAll() map[string]CacheItem {
+	return map[string]CacheItem{}
+}
+
+// Stats always returns zero counts
+func (this nullCache) Stats() Stats {
+	return Stats { }
+}
+
+// Peek always returns nil, false
+func (this nullCache) Peek(key string) (CacheItem, bool) {
+	return nil, false
+}
+
+// Contains always returns false
+func (this nullCache) Contains(key string) bool {
+	return false
+}
+
+// GetOrAdd calls compute and returns its result, nothing is stored
+func (this nullCache) GetOrAdd(key string, compute func() (CacheItem, error)) (CacheItem, error) {
+	return compute()
+}
+
+// UpdateSize does nothing and returns nil
+func (this nullCache) UpdateSize(key string) error {
+	return nil
+}
+
+// AddReturning does nothing and returns nil, false, nil as there's never anything to replace
+func (this nullCache) AddReturning(key string, val CacheItem) (CacheItem, bool, error) {
+	return nil, false, nil
+}
+
+// Resize does nothing
+func (this nullCache) Resize(newMax int) {
+}
+
+// RemoveFunc does nothing, pred is never called
+func (this nullCache) RemoveFunc(pred func(key string, item CacheItem) bool) {
+}
+
+// ForEach does nothing, fn is never called
+func (this nullCache) ForEach(fn func(key string, item CacheItem) bool) {
+}
+
+// GetOrAddContext calls compute and returns its result, nothing is stored. If ctx is already done then compute isn't
+// called and ctx's error is returned
+func (this nullCache) GetOrAddContext(ctx context.Context, key string, compute func(ctx context.Context) (CacheItem, error)) (CacheItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return compute(ctx)
+}
+
+// Touch always returns false
+func (this nullCache) Touch(key string) bool {
+	return false
+}
+
+// AddWithCost does nothing and returns nil
+func (this nullCache) AddWithCost(key string, val CacheItem, cost int) error {
+	return nil
+}
+
+// AddIfAbsent does nothing and returns true, nil as the key is never present
+func (this nullCache) AddIfAbsent(key string, val CacheItem) (bool, error) {
+	return true, nil
+}
+
+// Replace does nothing and returns false, nil as there's never anything to replace
+func (this nullCache) Replace(key string, val CacheItem) (bool, error) {
+	return false, nil
+}
+
+// GetWithAge always returns nil, 0, false
+func (this nullCache) GetWithAge(key string) (CacheItem, time.Duration, bool) {
+	return nil, 0, false
+}
+
+// GetStaleWhileRevalidate calls refresh and returns its result, as there's never anything cached to serve
+func (this nullCache) GetStaleWhileRevalidate(key string, ttl, staleWindow time.Duration, refresh func() (CacheItem, error)) (CacheItem, bool) {
+	item, err := refresh()
+	if err != nil {
+		return nil, false
+	}
+	return item, true
+}
\ No newline at end of file
@@ -0,0 +1,88 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNullCache(t *testing.T) {
+	cache := CreateNullCache()
+
+	// Everything succeeds but nothing is kept
+	item := &DummyCacheItem{DummySize: 10}
+	if err := cache.Add("a", item); err != nil {
+		t.Error("Add should always succeed, got", err)
+	}
+	if err := cache.AddWithCost("b", item, 10); err != nil {
+		t.Error("AddWithCost should always succeed, got", err)
+	}
+	if prev, existed, err := cache.AddReturning("a", item); prev != nil || existed || err != nil {
+		t.Error("AddReturning should never replace anything, got", prev, existed, err)
+	}
+	if got, present := cache.Get("a"); got != nil || present {
+		t.Error("Get should always miss, got", got, present)
+	}
+	if got, present := cache.Peek("a"); got != nil || present {
+		t.Error("Peek should always miss, got", got, present)
+	}
+	if cache.Contains("a") || cache.Touch("a") {
+		t.Error("Nothing should ever be present")
+	}
+	if cache.Len() != 0 || cache.Size() != 0 || cache.Cap() != 0 || len(cache.Keys()) != 0 {
+		t.Error("Expected an empty cache, got", cache.Len(), cache.Size(), cache.Cap(), cache.Keys())
+	}
+	assertStats(t, cache.Stats(), Stats{})
+
+	// The rest are no-ops that shouldn't panic
+	cache.Remove("a")
+	cache.Clear()
+	cache.Resize(MaxSize)
+	if err := cache.UpdateSize("a"); err != nil {
+		t.Error("UpdateSize should always succeed, got", err)
+	}
+	cache.RemoveFunc(func(key string, item CacheItem) bool {
+		t.Error("RemoveFunc shouldn't call pred")
+		return true
+	})
+	cache.ForEach(func(key string, item CacheItem) bool {
+		t.Error("ForEach shouldn't call fn")
+		return true
+	})
+}
+
+func TestNullCacheGetOrAdd(t *testing.T) {
+	cache := CreateNullCache()
+
+	// compute is called every time
+	calls := 0
+	item := &DummyCacheItem{DummySize: 10}
+	for i := 0; i < 3; i++ {
+		got, err := cache.GetOrAdd("a", func() (CacheItem, error) {
+			calls++
+			return item, nil
+		})
+		if got != item || err != nil {
+			t.Error("Expected computed item, got", got, err)
+		}
+	}
+	if calls != 3 {
+		t.Error("Expected compute to be called 3 times, got", calls)
+	}
+
+	// Errors are passed back
+	computeErr := errors.New("compute failed")
+	if _, err := cache.GetOrAdd("a", func() (CacheItem, error) { return nil, computeErr }); err != computeErr {
+		t.Error("Expected compute's error, got", err)
+	}
+
+	// Cancelled contexts don't compute
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.GetOrAddContext(ctx, "a", func(ctx context.Context) (CacheItem, error) {
+		t.Error("compute shouldn't be called")
+		return nil, nil
+	}); err != context.Canceled {
+		t.Error("Expected context.Canceled, got", err)
+	}
+}
\ No newline at end of file
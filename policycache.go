@@ -0,0 +1,639 @@
+package memcache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Interface: evictionPolicy (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// evictionPolicy decides which item a policyCache removes when it goes over max size
+//
+// The policyCache takes care of the hash, sizes and locking. The policy only has to keep track of the entries it's told
+// about and pick a victim when asked. Its methods are only ever called with the policyCache mutex held
+type evictionPolicy interface {
+
+	// added is called when an entry has been stored in the cache. Adding a key thats already present replaces its entry,
+	// the new entry's freq carries on from the old one so freq > 1 means the key has been seen before
+	added(entry *policyEntry)
+
+	// accessed is called when an entry already in the cache is retrieved with Get. The entry's freq has already been
+	// incremented
+	accessed(entry *policyEntry)
+
+	// admitting is called before a key is added, before anything is evicted to make room for it. Gives the policy a
+	// chance to adapt using what it remembers about the key
+	admitting(key string)
+
+	// removed is called when an entry leaves the cache. evicted is true if the cache picked it to make room (or its
+	// grown too big), false if it was removed, replaced or cleared out by the caller
+	removed(entry *policyEntry, evicted bool)
+
+	// victim returns the entry that should be evicted next, nil if the policy has no entries
+	victim() *policyEntry
+
+	// peekVictim returns the entry victim would return, without changing anything. Lets NextEviction predict the next
+	// victim without moving entries around or clearing what the policy knows about them
+	peekVictim() *policyEntry
+
+	// each calls fn for every entry, in the order they'd be kept (so the next victim is last). Stops if fn returns false
+	each(fn func(entry *policyEntry) bool)
+
+	// reset drops all entries
+	reset()
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: policyEntry (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// policyEntry represents a single item in a policyCache
+type policyEntry struct {
+
+	// cacheItem is the underlying item
+	cacheItem CacheItem
+
+	// key is the key we'd use in Get(key) to retrieve the item
+	key string
+
+	// size is the size of the item when it was added, so the same size is taken off when its removed
+	size int
+
+	// freq is the number of times the item has been added or accessed, for policies that evict by frequency
+	freq int
+
+	// referenced is set when the item has been used since the policy last looked at it, for policies that give items a
+	// second chance
+	referenced bool
+
+	// index is the position of the entry in a slice, for policies that keep their entries in one
+	index int
+
+	// priority is what the item was added with by AddWithPriority, for policies that evict lower priorities first
+	priority int
+
+	// added is when the item was added to the cache
+	added time.Time
+
+	// prev is the previous entry in the entryList, nil if we're the head
+	prev *policyEntry
+
+	// next is the next entry in the entryList, nil if we're the tail
+	next *policyEntry
+
+	// list is the entryList the entry currently belongs to, nil if it isn't in one
+	list *entryList
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: entryList (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// entryList is a doubly linked-list of policyEntry, policies use it to keep entries in order
+//
+// An entry can only be in one entryList at a time
+type entryList struct {
+
+	// head is the head of the linkedlist
+	head *policyEntry
+
+	// tail is the tail of the linkedlist
+	tail *policyEntry
+
+	// len is the number of entries in the list
+	len int
+
+	// size is the total size of the entries in the list
+	size int
+}
+
+// pushFront adds the entry to the head of the list
+func (this *entryList) pushFront(entry *policyEntry) {
+	entry.list = this
+	entry.prev = nil
+	entry.next = this.head
+	if this.head != nil {
+		this.head.prev = entry
+	} else {
+		this.tail = entry
+	}
+	this.head = entry
+	this.len++
+	this.size += entry.size
+}
+
+// insertBefore adds the entry to the list just before mark, which must already be in the list
+func (this *entryList) insertBefore(entry *policyEntry, mark *policyEntry) {
+	entry.list = this
+	entry.prev = mark.prev
+	entry.next = mark
+	if mark.prev != nil {
+		mark.prev.next = entry
+	} else {
+		this.head = entry
+	}
+	mark.prev = entry
+	this.len++
+	this.size += entry.size
+}
+
+// remove takes the entry out of the list, pairing up sibling entries and moving head/tail if it was either
+func (this *entryList) remove(entry *policyEntry) {
+	if entry.prev != nil {
+		entry.prev.next = entry.next
+	} else {
+		this.head = entry.next
+	}
+	if entry.next != nil {
+		entry.next.prev = entry.prev
+	} else {
+		this.tail = entry.prev
+	}
+	entry.prev = nil
+	entry.next = nil
+	entry.list = nil
+	this.len--
+	this.size -= entry.size
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: policyCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// policyCache implements Cache for the eviction policies other than LRU
+//
+// It keeps a hash of entries and the current size, and asks its evictionPolicy which entry to drop whenever the current
+// size would go over max size
+type policyCache struct {
+
+	// entries is the map of key(string) to entry
+	entries map[string]*policyEntry
+
+	// policy decides which entry gets evicted
+	policy evictionPolicy
+
+	// maxSize holds the maximum size of the cache
+	maxSize int
+
+	// curSize holds the current size of the cache
+	curSize int
+
+	// mutex is used to synchronize cache as it can be accessed by multiple goroutines
+	mutex sync.Mutex
+
+	// stats holds the hit, miss, eviction and add counts
+	stats cacheStats
+
+	// flights makes sure GetOrAdd only computes a missing item once
+	flights flightGroup
+
+	// clock tells the cache the time, nil for the real time
+	clock clock
+}
+
+// createPolicyCache creates a policyCache that evicts using the policy passed in
+func createPolicyCache(maxsize int, policy evictionPolicy) (*policyCache) {
+	return &policyCache { entries: make(map[string]*policyEntry), policy: policy, maxSize: maxsize }
+}
+
+// now returns the current time from the cache's clock, the real time if it hasn't been given one
+func (this *policyCache) now() time.Time {
+	if this.clock == nil {
+		return time.Now()
+	}
+	return this.clock.Now()
+}
+
+// detach removes an entry from the hash and the policy, and takes its size off the cache
+func (this *policyCache) detach(entry *policyEntry, evicted bool) {
+	this.policy.removed(entry, evicted)
+	this.curSize -= entry.size
+	delete(this.entries, entry.key)
+}
+
+// evict detaches an entry the cache has chosen to get rid of and records it in the stats
+func (this *policyCache) evict(entry *policyEntry) {
+	this.detach(entry, true)
+	this.stats.evictions.Add(1)
+}
+
+// evictFor evicts entries until an item of the size passed in will fit
+func (this *policyCache) evictFor(size int) {
+	for this.curSize + size > this.maxSize {
+		victim := this.policy.victim()
+
+		// Nothing left to remove, if we think we still have a size then its drifted so recompute it (empty == 0)
+		if victim == nil {
+			this.curSize = 0
+			break
+		}
+		this.evict(victim)
+	}
+}
+
+// evictDownTo evicts entries until the current size is no more than size
+func (this *policyCache) evictDownTo(size int) {
+	for this.curSize > size {
+		victim := this.policy.victim()
+
+		// Nothing left to remove, if we think we still have a size then its drifted so recompute it (empty == 0)
+		if victim == nil {
+			this.curSize = 0
+			break
+		}
+		this.evict(victim)
+	}
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Cache Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// Add adds a CacheItem to the cache, it can be retrieved using Get and passing in the same key
+//
+// If an item is already present under the key, whether its the same item or not, then its replaced and the new one keeps
+// its access count (plus one for this add). The size is always re-read in case its changed. Entries are evicted, as
+// picked by the policy, until the item fits
+// If the item is bigger than max size then an error is returned. Any different item under the key is left as it was,
+// but if its the same item (its grown since it was added) then its removed
+func (this *policyCache) Add(k string, v CacheItem) error {
+	_, _, err := this.AddReturning(k, v)
+	return err
+}
+
+// AddReturning adds a CacheItem to the cache like Add, and returns the item it replaced
+//
+// If an item was already present under the key then it's returned with existed set to true, even if its the same item.
+// If the item can't be added then nil, false is returned along with the error
+func (this *policyCache) AddReturning(k string, v CacheItem) (CacheItem, bool, error) {
+	return this.add(k, v, itemSize(v))
+}
+
+// AddWithCost adds a CacheItem to the cache like Add, but with cost used as its size instead of v.Size()
+func (this *policyCache) AddWithCost(k string, v CacheItem, cost int) error {
+	_, _, err := this.add(k, v, cost)
+	return err
+}
+
+// AddIfAbsent adds a CacheItem to the cache like Add, but only if nothing is stored under the key
+//
+// Returns true if the item was added. If the key's already present then false is returned and the policy isn't told
+func (this *policyCache) AddIfAbsent(k string, v CacheItem) (bool, error) {
+
+	// Lock method so hash & policy can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if _, present := this.entries[k]; present {
+		return false, nil
+	}
+	_, _, err := this.store(k, v, itemSize(v))
+	return err == nil, err
+}
+
+// Replace replaces the item stored under the key like Add, but only if there is one
+//
+// Returns true if the item was replaced, the policy sees it as being added again. If the key's missing then false is
+// returned and nothing is added
+func (this *policyCache) Replace(k string, v CacheItem) (bool, error) {
+
+	// Lock method so hash & policy can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if _, present := this.entries[k]; !present {
+		return false, nil
+	}
+	_, _, err := this.store(k, v, itemSize(v))
+	return err == nil, err
+}
+
+// add implements Add, AddReturning and AddWithCost. size is what the item counts as towards the size of the cache
+func (this *policyCache) add(k string, v CacheItem, size int) (CacheItem, bool, error) {
+
+	// Lock method so hash & policy can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return this.store(k, v, size)
+}
+
+// store does the work for add, it must be called with the lock held. An item replacing another keeps its priority
+func (this *policyCache) store(k string, v CacheItem, size int) (CacheItem, bool, error) {
+	priority := 0
+	if existing, present := this.entries[k]; present {
+		priority = existing.priority
+	}
+	return this.storeWithPriority(k, v, size, priority)
+}
+
+// storeWithPriority is store with the priority the new entry gets, it must be called with the lock held
+func (this *policyCache) storeWithPriority(k string, v CacheItem, size int, priority int) (CacheItem, bool, error) {
+	// Nothing would be able to use a nil item, leave whatever's under the key as it is
+	if v == nil {
+		return nil, false, ErrNilItem
+	}
+
+	existing, present := this.entries[k]
+
+	// Can't store if it already exceeds max size
+	if err := sizeError(size, this.maxSize); err != nil {
+		if present && existing.cacheItem == v {
+			this.evict(existing)
+		}
+		return nil, false, err
+	}
+
+	// Replacing, take the old one out but remember how often its been accessed
+	freq := 0
+	var prev CacheItem
+	if present {
+		freq = existing.freq
+		prev = existing.cacheItem
+		this.detach(existing, false)
+	}
+
+	this.policy.admitting(k)
+	this.evictFor(size)
+
+	entry := &policyEntry { cacheItem: v, key: k, size: size, freq: freq + 1, priority: priority, added: this.now() }
+	this.entries[k] = entry
+	this.curSize += size
+	this.policy.added(entry)
+	this.stats.adds.Add(1)
+	return prev, present, nil
+}
+
+// Get retrieves an item from the cache if its present, the policy is told its been accessed
+//
+// If item is present then the item, true is returned. Otherwise, nil, false
+func (this *policyCache) Get(key string) (CacheItem, bool) {
+
+	// Lock method so hash & policy can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if entry, present := this.entries[key]; present {
+		entry.freq++
+		this.policy.accessed(entry)
+		this.stats.get(true)
+		return entry.cacheItem, true
+	}
+	this.stats.get(false)
+	return nil, false
+}
+
+// GetOrErr retrieves an item like Get, returning ErrNotFound if it isn't present
+func (this *policyCache) GetOrErr(key string) (CacheItem, error) {
+	return getOrErr(this.Get(key))
+}
+
+// GetWithAge retrieves an item like Get, along with how long its been since it was added
+func (this *policyCache) GetWithAge(key string) (CacheItem, time.Duration, bool) {
+
+	// Lock method so hash & policy can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if entry, present := this.entries[key]; present {
+		entry.freq++
+		this.policy.accessed(entry)
+		this.stats.get(true)
+		return entry.cacheItem, this.now().Sub(entry.added), true
+	}
+	this.stats.get(false)
+	return nil, 0, false
+}
+
+// Remove removes an item from the cache
+func (this *policyCache) Remove(key string) {
+
+	// Lock method so hash & policy can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if entry, present := this.entries[key]; present {
+		this.detach(entry, false)
+	}
+}
+
+// Len returns the number of items currently stored in the cache
+func (this *policyCache) Len() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return len(this.entries)
+}
+
+// Size returns the total size of all the items currently stored in the cache
+func (this *policyCache) Size() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return this.curSize
+}
+
+// Cap returns the maximum total size the cache will hold before it starts removing items
+func (this *policyCache) Cap() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return this.maxSize
+}
+
+// Clear removes all items from the cache
+func (this *policyCache) Clear() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.entries = make(map[string]*policyEntry)
+	this.curSize = 0
+	this.policy.reset()
+}
+
+// Keys returns the keys of all the items currently stored in the cache
+//
+// Keys are returned in the order the policy keeps them, so the last key is the next to be evicted
+func (this *policyCache) Keys() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	keys := make([]string, 0, len(this.entries))
+	this.policy.each(func(entry *policyEntry) bool {
+		keys = append(keys, entry.key)
+		return true
+	})
+	return keys
+}
+
+// GetAll returns a snapshot of every item in the cache, the policy isn't told they've been accessed
+func (this *policyCache) GetAll() map[string]CacheItem {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	all := make(map[string]CacheItem, len(this.entries))
+	for key, entry := range this.entries {
+		all[key] = entry.cacheItem
+	}
+	return all
+}
+
+// Stats returns counts of the hits, misses, evictions and adds since the cache was created
+func (this *policyCache) Stats() Stats {
+	return this.stats.snapshot()
+}
+
+// NextEviction returns the key of the item the policy would evict next to make room, without removing it
+//
+// If the cache is empty then "", false is returned. ARC picks its victim partly on the key being added, this assumes the
+// key isn't one it remembers having evicted
+func (this *policyCache) NextEviction() (string, bool) {
+
+	// Lock method so hash & policy can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	victim := this.policy.peekVictim()
+	if victim == nil {
+		return "", false
+	}
+	return victim.key, true
+}
+
+// Peek retrieves an item from the cache if its present. Unlike Get, the policy isn't told its been accessed
+//
+// If item is present then the item, true is returned. Otherwise, nil, false
+func (this *policyCache) Peek(key string) (CacheItem, bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if entry, present := this.entries[key]; present {
+		return entry.cacheItem, true
+	}
+	return nil, false
+}
+
+// Contains returns true if the item is present in the cache. Like Peek, the policy isn't told its been accessed
+func (this *policyCache) Contains(key string) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	_, present := this.entries[key]
+	return present
+}
+
+// GetOrAdd retrieves an item from the cache if its present, otherwise it calls compute and adds the item it returns
+//
+// The cache isn't locked while compute runs, but concurrent callers for the same key wait for it rather than calling
+// compute themselves. If compute returns an error then nothing is added and the error is returned
+func (this *policyCache) GetOrAdd(key string, compute func() (CacheItem, error)) (CacheItem, error) {
+	return getOrAdd(this, &this.flights, key, compute)
+}
+
+// GetOrAddContext works like GetOrAdd, but gives up if ctx is done before it has the item
+//
+// ctx is passed on to compute. If ctx is done by the time compute returns then nothing is added and ctx's error is
+// returned, so a cancelled compute never leaves a partial item behind
+func (this *policyCache) GetOrAddContext(ctx context.Context, key string, compute func(ctx context.Context) (CacheItem, error)) (CacheItem, error) {
+	return getOrAddContext(ctx, this, &this.flights, key, compute)
+}
+
+// GetStaleWhileRevalidate retrieves an item, serving it stale and refreshing it in the background once its older than
+// ttl. Its only waited for if its older than ttl + staleWindow or missing
+//
+// Refreshes share the flights used by GetOrAdd, so only one runs at a time for a key
+func (this *policyCache) GetStaleWhileRevalidate(key string, ttl, staleWindow time.Duration, refresh func() (CacheItem, error)) (CacheItem, bool) {
+	return getStaleWhileRevalidate(this, &this.flights, key, ttl, staleWindow, refresh)
+}
+
+// UpdateSize re-reads the Size of an item that's changed since it was added, and updates the size of the cache to match
+//
+// The item counts as added again and entries are evicted until the cache is back under max size. If the item is now
+// bigger than max size then its evicted and an error is returned. Nothing happens if the item isn't present
+func (this *policyCache) UpdateSize(key string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	entry, present := this.entries[key]
+	if !present {
+		return nil
+	}
+
+	// Can't store if it now exceeds max size
+	size := entry.cacheItem.Size()
+	if err := sizeError(size, this.maxSize); err != nil {
+		this.evict(entry)
+		return err
+	}
+	this.detach(entry, false)
+
+	// Make room and put it back with the new size
+	this.evictFor(size)
+	entry.size = size
+	this.entries[key] = entry
+	this.curSize += size
+	this.policy.added(entry)
+	return nil
+}
+
+// Resize changes the maximum total size of the cache, if its now over the new size then entries are evicted until it isn't
+func (this *policyCache) Resize(newMax int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.maxSize = newMax
+	this.evictDownTo(newMax)
+}
+
+// RemoveFunc removes every item that pred returns true for
+//
+// pred is called with the cache locked so it mustn't call back into the cache
+func (this *policyCache) RemoveFunc(pred func(key string, item CacheItem) bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	// Policies can't be changed while we're iterating over them, so find the entries first
+	var matches []*policyEntry
+	this.policy.each(func(entry *policyEntry) bool {
+		if pred(entry.key, entry.cacheItem) {
+			matches = append(matches, entry)
+		}
+		return true
+	})
+
+	for _, entry := range matches {
+		this.detach(entry, false)
+	}
+}
+
+// ForEach calls fn for every item in the cache, in the order the policy keeps them, until fn returns false
+//
+// The policy isn't told the items have been accessed. fn is called with the cache locked so it mustn't call back into
+// the cache
+func (this *policyCache) ForEach(fn func(key string, item CacheItem) bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.policy.each(func(entry *policyEntry) bool {
+		return fn(entry.key, entry.cacheItem)
+	})
+}
+
+// Touch tells the policy an item has been accessed, without retrieving it or counting as a hit. Returns false if the key
+// is missing
+func (this *policyCache) Touch(key string) bool {
+
+	// Lock method so hash & policy can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	entry, present := this.entries[key]
+	if present {
+		entry.freq++
+		this.policy.accessed(entry)
+	}
+	return present
+}
\ No newline at end of file
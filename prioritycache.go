@@ -0,0 +1,131 @@
+package memcache
+
+import (
+	"sort"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreatePriorityCache creates and returns a Cache that evicts lower priority items first, least recently used first
+// within a priority
+//
+// Items are given a priority with AddWithPriority, the returned Cache implements PriorityCache. Add gives new items a
+// priority of 0, or keeps the priority of the item its replacing. Nothing of a higher priority is evicted while there are
+// lower priority items left, but once there aren't high priority items go like any other so the cache never goes over
+// its max size
+func CreatePriorityCache(maxsize int) (Cache) {
+	return &priorityCache { createPolicyCache(maxsize, &priorityPolicy { lists: make(map[int]*entryList) }) }
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: priorityCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// priorityCache is a policyCache with a priorityPolicy, it adds AddWithPriority
+type priorityCache struct {
+	*policyCache
+}
+
+// AddWithPriority adds a CacheItem to the cache like Add, with the priority passed in
+//
+// A higher priority is kept for longer. Adding an item under a key thats already present replaces the item and its
+// priority
+func (this *priorityCache) AddWithPriority(k string, v CacheItem, priority int) error {
+
+	// Lock method so hash & policy can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	_, _, err := this.storeWithPriority(k, v, itemSize(v), priority)
+	return err
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: priorityPolicy (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// priorityPolicy is the evictionPolicy for the Priority cache
+//
+// Each priority has its own LRU list. priorities keeps the priorities that have entries in order, so the lowest can be
+// found in O(1). Adding the first entry of a new priority is O(number of priorities)
+type priorityPolicy struct {
+
+	// lists is the map of priority to its entries, most recently used at the head. Only priorities with entries are kept
+	lists map[int]*entryList
+
+	// priorities holds the keys of lists, lowest first
+	priorities []int
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// evictionPolicy Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// admitting does nothing, the priority of a key isn't remembered once its gone
+func (this *priorityPolicy) admitting(key string) {
+}
+
+// added puts the entry at the head of the list for its priority
+func (this *priorityPolicy) added(entry *policyEntry) {
+	list, present := this.lists[entry.priority]
+	if !present {
+		list = &entryList { }
+		this.lists[entry.priority] = list
+		i := sort.SearchInts(this.priorities, entry.priority)
+		this.priorities = append(this.priorities, 0)
+		copy(this.priorities[i + 1:], this.priorities[i:])
+		this.priorities[i] = entry.priority
+	}
+	list.pushFront(entry)
+}
+
+// accessed moves the entry to the head of its list
+func (this *priorityPolicy) accessed(entry *policyEntry) {
+	list := entry.list
+	list.remove(entry)
+	list.pushFront(entry)
+}
+
+// removed takes the entry out of its list, dropping the list if its now empty
+func (this *priorityPolicy) removed(entry *policyEntry, evicted bool) {
+	list := entry.list
+	list.remove(entry)
+	if list.len > 0 {
+		return
+	}
+	delete(this.lists, entry.priority)
+	i := sort.SearchInts(this.priorities, entry.priority)
+	this.priorities = append(this.priorities[:i], this.priorities[i + 1:]...)
+}
+
+// victim returns the tail of the lowest priority list
+func (this *priorityPolicy) victim() *policyEntry {
+	if len(this.priorities) == 0 {
+		return nil
+	}
+	return this.lists[this.priorities[0]].tail
+}
+
+// peekVictim returns victim, which only reads the lists so calling it doesn't change anything
+func (this *priorityPolicy) peekVictim() *policyEntry {
+	return this.victim()
+}
+
+// each iterates from the highest priority down, most recently used first within each
+func (this *priorityPolicy) each(fn func(entry *policyEntry) bool) {
+	for i := len(this.priorities) - 1; i >= 0; i-- {
+		for entry := this.lists[this.priorities[i]].head; entry != nil; entry = entry.next {
+			if !fn(entry) {
+				return
+			}
+		}
+	}
+}
+
+// reset drops all the lists
+func (this *priorityPolicy) reset() {
+	this.lists = make(map[int]*entryList)
+	this.priorities = nil
+}
\ No newline at end of file
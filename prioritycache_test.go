@@ -0,0 +1,66 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestPriorityCache(t *testing.T) {
+	cache := CreatePriorityCache(MaxSize)
+
+	// Check cache is created correctly
+	if cache == nil {
+		t.Error("Cache is nil")
+	}
+
+	// Way more than will fit, should stay at max size
+	for i := 0; i < 50; i++ {
+		cache.(PriorityCache).AddWithPriority(strconv.Itoa(i), &DummyCacheItem{DummySize: 10}, i % 3)
+	}
+	if cache.Len() != 10 || cache.Size() != MaxSize {
+		t.Error("Expected 10 items of size", MaxSize, "got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestPriorityCacheEviction(t *testing.T) {
+	cache := CreatePriorityCache(40)
+	priority := cache.(PriorityCache)
+	priority.AddWithPriority("high", &DummyCacheItem{DummySize: 10}, 2)
+	priority.AddWithPriority("low1", &DummyCacheItem{DummySize: 10}, 0)
+	priority.AddWithPriority("mid", &DummyCacheItem{DummySize: 10}, 1)
+	priority.AddWithPriority("low2", &DummyCacheItem{DummySize: 10}, 0)
+	assertKeys(t, cache.Keys(), []string{"high", "mid", "low2", "low1"})
+
+	// Low priority goes first even though high is the oldest, least recently used first within it
+	cache.Get("low1")
+	priority.AddWithPriority("high2", &DummyCacheItem{DummySize: 10}, 2)
+	assertKeys(t, cache.Keys(), []string{"high2", "high", "mid", "low1"})
+	priority.AddWithPriority("high3", &DummyCacheItem{DummySize: 10}, 2)
+	priority.AddWithPriority("high4", &DummyCacheItem{DummySize: 10}, 2)
+	assertKeys(t, cache.Keys(), []string{"high4", "high3", "high2", "high"})
+
+	// Only high priority left so the least recently used of them goes, however cold
+	priority.AddWithPriority("high5", &DummyCacheItem{DummySize: 10}, 2)
+	assertKeys(t, cache.Keys(), []string{"high5", "high4", "high3", "high2"})
+	if cache.Size() != 40 {
+		t.Error("Expected a size of 40, got", cache.Size())
+	}
+}
+
+func TestPriorityCacheReplace(t *testing.T) {
+	cache := CreatePriorityCache(20)
+	priority := cache.(PriorityCache)
+	priority.AddWithPriority("a", &DummyCacheItem{DummySize: 10}, 1)
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// Add keeps a's priority, AddWithPriority changes b's
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	priority.AddWithPriority("b", &DummyCacheItem{DummySize: 10}, 2)
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"b", "c"})
+
+	// Clear empties every priority
+	cache.Clear()
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"d"})
+}
\ No newline at end of file
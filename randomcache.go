@@ -0,0 +1,106 @@
+package memcache
+
+import (
+	"math/rand"
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateRandomCache creates and returns a 'Random' implementation of Cache
+//
+// When the cache goes over max size a random item is removed, every item has the same chance of going whether its been
+// accessed or not. Mostly useful as a baseline to compare other caches against
+func CreateRandomCache(maxsize int) (Cache) {
+	return CreateRandomCacheSeeded(maxsize, time.Now().UnixNano())
+}
+
+// CreateRandomCacheSeeded creates a Random Cache like CreateRandomCache, picking the items to evict from a random source
+// seeded with the value passed in. The same seed with the same adds will evict the same items
+func CreateRandomCacheSeeded(maxsize int, seed int64) (Cache) {
+	return createPolicyCache(maxsize, &randomPolicy { random: rand.New(rand.NewSource(seed)) })
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: randomPolicy (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// randomPolicy is the evictionPolicy for the Random cache
+//
+// Entries are kept in a slice so a random one can be picked in O(1). Removing swaps the last entry into the gap
+type randomPolicy struct {
+
+	// entries holds every entry, in no particular order
+	entries []*policyEntry
+
+	// random picks the victims
+	random *rand.Rand
+
+	// picked is the victim already picked by victim, nil if it needs picking again. Its kept so asking for the victim
+	// more than once (like NextEviction then Add) gives the same entry
+	picked *policyEntry
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// evictionPolicy Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// admitting does nothing, Random doesn't remember keys once they've gone
+func (this *randomPolicy) admitting(key string) {
+}
+
+// added appends the entry to the slice, any victim already picked is forgotten so the new entry has a chance too
+func (this *randomPolicy) added(entry *policyEntry) {
+	this.picked = nil
+	entry.index = len(this.entries)
+	this.entries = append(this.entries, entry)
+}
+
+// accessed doesn't change the entry's chances, any victim already picked is forgotten as the Sampled LFU cache picks by
+// how often entries have been accessed
+func (this *randomPolicy) accessed(entry *policyEntry) {
+	this.picked = nil
+}
+
+// removed moves the last entry into the gap left by the one being removed, and forgets any victim already picked
+func (this *randomPolicy) removed(entry *policyEntry, evicted bool) {
+	this.picked = nil
+	last := this.entries[len(this.entries) - 1]
+	this.entries[entry.index] = last
+	last.index = entry.index
+	this.entries[len(this.entries) - 1] = nil
+	this.entries = this.entries[:len(this.entries) - 1]
+}
+
+// victim returns a random entry, the same one until the entries change
+func (this *randomPolicy) victim() *policyEntry {
+	if len(this.entries) == 0 {
+		return nil
+	}
+	if this.picked == nil {
+		this.picked = this.entries[this.random.Intn(len(this.entries))]
+	}
+	return this.picked
+}
+
+// peekVictim returns victim, which only remembers its pick so its still the one evicted next
+func (this *randomPolicy) peekVictim() *policyEntry {
+	return this.victim()
+}
+
+// each iterates over the entries in the order they're held in the slice, theres no way to know the next victim
+func (this *randomPolicy) each(fn func(entry *policyEntry) bool) {
+	for _, entry := range this.entries {
+		if !fn(entry) {
+			return
+		}
+	}
+}
+
+// reset drops all entries
+func (this *randomPolicy) reset() {
+	this.entries = nil
+	this.picked = nil
+}
\ No newline at end of file
@@ -0,0 +1,49 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRandomCache(t *testing.T) {
+	cache := CreateRandomCache(MaxSize)
+
+	// Check cache is created correctly
+	if cache == nil {
+		t.Error("Cache is nil")
+	}
+
+	// Way more than will fit, should stay at max size
+	for i := 0; i < 50; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Len() != 10 || cache.Size() != MaxSize {
+		t.Error("Expected 10 items of size", MaxSize, "got", cache.Len(), "items of size", cache.Size())
+	}
+
+	// Last one added should always be there, nothing is evicted after it goes in
+	if !cache.Contains("49") {
+		t.Error("49 should be present")
+	}
+}
+
+func TestRandomCacheSeeded(t *testing.T) {
+	cache := CreateRandomCacheSeeded(30, 42)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Add(key, &DummyCacheItem{DummySize: 10})
+	}
+
+	// Same seed should always evict the same keys
+	evicted := []string{}
+	for i := 0; i < 5; i++ {
+		key := strconv.Itoa(i)
+		before := cache.Keys()
+		cache.Add(key, &DummyCacheItem{DummySize: 10})
+		for _, k := range before {
+			if !cache.Contains(k) {
+				evicted = append(evicted, k)
+			}
+		}
+	}
+	assertKeys(t, evicted, []string{"c", "0", "1", "a", "b"})
+}
\ No newline at end of file
@@ -0,0 +1,76 @@
+package memcache
+
+import (
+	"math/rand"
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateSampledLFUCache creates and returns an approximate 'Least Frequently Used' implementation of Cache
+//
+// When the cache goes over max size sampleSize items are picked at random and the least used of them is removed. Nothing
+// has to be kept in frequency order so it's cheaper than the LFU Cache for big caches, the bigger the sample the closer
+// it gets to real LFU. A sampleSize below 1 is treated as 1, which makes it the same as the Random Cache
+func CreateSampledLFUCache(maxsize, sampleSize int) (Cache) {
+	return CreateSampledLFUCacheSeeded(maxsize, sampleSize, time.Now().UnixNano())
+}
+
+// CreateSampledLFUCacheSeeded creates a Sampled LFU Cache like CreateSampledLFUCache, picking the samples from a random
+// source seeded with the value passed in. The same seed with the same adds and gets will evict the same items
+func CreateSampledLFUCacheSeeded(maxsize, sampleSize int, seed int64) (Cache) {
+	if sampleSize < 1 {
+		sampleSize = 1
+	}
+	return createPolicyCache(maxsize, &sampledLFUPolicy { randomPolicy: randomPolicy { random: rand.New(rand.NewSource(seed)) }, 
+		sampleSize: sampleSize })
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: sampledLFUPolicy (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// sampledLFUPolicy is the evictionPolicy for the Sampled LFU cache
+//
+// Entries are kept in a slice the same way as the Random cache, only picking the victim is different
+type sampledLFUPolicy struct {
+	randomPolicy
+
+	// sampleSize is how many entries are looked at to find a victim
+	sampleSize int
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// evictionPolicy Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// victim returns the entry with the lowest freq out of sampleSize random entries, the first sampled wins a tie
+//
+// The sample is shuffled to the front of the slice so no entry is picked twice, if there are fewer entries than
+// sampleSize then they're all looked at. The same entry is returned until the entries change or one's accessed
+func (this *sampledLFUPolicy) victim() *policyEntry {
+	if this.picked != nil {
+		return this.picked
+	}
+
+	var victim *policyEntry
+	for i := 0; i < this.sampleSize && i < len(this.entries); i++ {
+		j := i + this.random.Intn(len(this.entries) - i)
+		this.entries[i], this.entries[j] = this.entries[j], this.entries[i]
+		this.entries[i].index = i
+		this.entries[j].index = j
+
+		if victim == nil || this.entries[i].freq < victim.freq {
+			victim = this.entries[i]
+		}
+	}
+	this.picked = victim
+	return victim
+}
+
+// peekVictim returns victim, which only remembers its pick so its still the one evicted next
+func (this *sampledLFUPolicy) peekVictim() *policyEntry {
+	return this.victim()
+}
\ No newline at end of file
@@ -0,0 +1,90 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSampledLFUCache(t *testing.T) {
+	cache := CreateSampledLFUCache(MaxSize, 5)
+
+	// Check cache is created correctly
+	if cache == nil {
+		t.Error("Cache is nil")
+	}
+
+	// Way more than will fit, should stay at max size
+	for i := 0; i < 50; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Len() != 10 || cache.Size() != MaxSize {
+		t.Error("Expected 10 items of size", MaxSize, "got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestSampledLFUCacheHotKeys(t *testing.T) {
+	cache := CreateSampledLFUCacheSeeded(MaxSize, 5, 42)
+
+	// Get the hot keys a few times so they're used more than anything added after
+	hot := []string{"a", "b", "c"}
+	for _, key := range hot {
+		cache.Add(key, &DummyCacheItem{DummySize: 10})
+		for i := 0; i < 3; i++ {
+			cache.Get(key)
+		}
+	}
+
+	// Any sample of 5 out of 10 items has at least 2 cold ones in it, so a hot key should never be picked
+	for i := 0; i < 50; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	for _, key := range hot {
+		if !cache.Contains(key) {
+			t.Error(key, "should have survived")
+		}
+	}
+	if cache.Len() != 10 {
+		t.Error("Expected 10 items, got", cache.Len())
+	}
+}
+
+func TestSampledLFUCacheSeeded(t *testing.T) {
+	cache := CreateSampledLFUCacheSeeded(30, 2, 42)
+
+	// Same seed should always evict the same keys
+	evicted := []string{}
+	for i := 0; i < 10; i++ {
+		key := strconv.Itoa(i)
+		before := cache.Keys()
+		cache.Add(key, &DummyCacheItem{DummySize: 10})
+		for _, k := range before {
+			if !cache.Contains(k) {
+				evicted = append(evicted, k)
+			}
+		}
+	}
+	assertKeys(t, evicted, []string{"2", "3", "0", "1", "6", "5", "4"})
+}
+
+func TestSampledLFUCacheSampleSize(t *testing.T) {
+	// A sample as big as the cache looks at everything, so its the same as LFU
+	cache := CreateSampledLFUCache(30, 3)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Add(key, &DummyCacheItem{DummySize: 10})
+	}
+	cache.Get("a")
+	cache.Get("c")
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	if cache.Contains("b") {
+		t.Error("b should have been evicted as it was used the least")
+	}
+
+	// Too small a sample is treated as 1, which should still evict
+	cache = CreateSampledLFUCache(30, 0)
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Len() != 3 {
+		t.Error("Expected 3 items, got", cache.Len())
+	}
+}
\ No newline at end of file
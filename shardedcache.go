@@ -0,0 +1,237 @@
+package memcache
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateShardedCache creates and returns a Cache split into a number of independent LRU Cache shards
+//
+// Each key is hashed to pick the shard it lives in, and each shard has its own lock so operations on keys in different
+// shards don't have to wait for each other. maxsizePerShard is the max size of each shard, not the whole cache, so the
+// total capacity is shards * maxsizePerShard. Items are only evicted to make room in their own shard. A shards value
+// below 1 is treated as 1, so there's always a shard for keys to go to
+func CreateShardedCache(shards, maxsizePerShard int) (Cache) {
+	if shards < 1 {
+		shards = 1
+	}
+	cache := &shardedCache { shards: make([]Cache, shards) }
+	for i := range cache.shards {
+		cache.shards[i] = CreateLRUCache(maxsizePerShard)
+	}
+	return cache
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: shardedCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// shardedCache routes each key to one of a number of underlying caches
+type shardedCache struct {
+
+	// shards are the underlying caches, a key always goes to the same one
+	shards []Cache
+}
+
+// shardFor returns the shard that owns a key, using an fnv hash of the key so keys spread evenly
+func (this *shardedCache) shardFor(key string) Cache {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return this.shards[hash.Sum32() % uint32(len(this.shards))]
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Cache Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// Add adds a CacheItem to the shard that owns the key, evicting from that shard if it goes over its max size
+func (this *shardedCache) Add(key string, val CacheItem) error {
+	return this.shardFor(key).Add(key, val)
+}
+
+// Get retrieves an item from the shard that owns the key if its present
+//
+// If item is present then the item, true is returned. Otherwise, nil, false
+func (this *shardedCache) Get(key string) (CacheItem, bool) {
+	return this.shardFor(key).Get(key)
+}
+
+// GetOrErr retrieves an item from the shard that owns the key, returning ErrNotFound if it isn't present
+func (this *shardedCache) GetOrErr(key string) (CacheItem, error) {
+	return this.shardFor(key).GetOrErr(key)
+}
+
+// GetWithAge retrieves an item from the shard that owns the key, along with how long its been since it was added
+func (this *shardedCache) GetWithAge(key string) (CacheItem, time.Duration, bool) {
+	return this.shardFor(key).GetWithAge(key)
+}
+
+// Remove removes an item from the shard that owns the key
+func (this *shardedCache) Remove(key string) {
+	this.shardFor(key).Remove(key)
+}
+
+// Len returns the number of items currently stored across all the shards
+func (this *shardedCache) Len() int {
+	total := 0
+	for _, shard := range this.shards {
+		total += shard.Len()
+	}
+	return total
+}
+
+// Size returns the total size of all the items currently stored across all the shards
+func (this *shardedCache) Size() int {
+	total := 0
+	for _, shard := range this.shards {
+		total += shard.Size()
+	}
+	return total
+}
+
+// Cap returns the total max size of all the shards
+func (this *shardedCache) Cap() int {
+	total := 0
+	for _, shard := range this.shards {
+		total += shard.Cap()
+	}
+	return total
+}
+
+// Clear removes all items from every shard
+func (this *shardedCache) Clear() {
+	for _, shard := range this.shards {
+		shard.Clear()
+	}
+}
+
+// Keys returns the keys of all the items currently stored across all the shards
+//
+// Keys are in LRU order within each shard, one shard after another. There's no order across shards as each evicts
+// independently
+func (this *shardedCache) Keys() []string {
+	keys := []string{}
+	for _, shard := range this.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+// GetAll returns a snapshot of every item across all the shards
+//
+// Each shard is copied in turn, so its only a snapshot of each shard at the instant it was copied rather than all of them
+// at once
+func (this *shardedCache) GetAll() map[string]CacheItem {
+	all := map[string]CacheItem{}
+	for _, shard := range this.shards {
+		for key, item := range shard.GetAll() {
+			all[key] = item
+		}
+	}
+	return all
+}
+
+// Stats returns counts of the hits, misses, evictions, expirations and adds across all the shards
+func (this *shardedCache) Stats() Stats {
+	total := Stats{}
+	for _, shard := range this.shards {
+		stats := shard.Stats()
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Evictions += stats.Evictions
+		total.Expirations += stats.Expirations
+		total.Adds += stats.Adds
+	}
+	return total
+}
+
+// Peek retrieves an item from the shard that owns the key, without counting as an access
+//
+// If item is present then the item, true is returned. Otherwise, nil, false
+func (this *shardedCache) Peek(key string) (CacheItem, bool) {
+	return this.shardFor(key).Peek(key)
+}
+
+// Contains returns true if the item is present in the shard that owns the key, without counting as an access
+func (this *shardedCache) Contains(key string) bool {
+	return this.shardFor(key).Contains(key)
+}
+
+// GetOrAdd retrieves an item from the shard that owns the key, otherwise it calls compute and adds the item it returns
+func (this *shardedCache) GetOrAdd(key string, compute func() (CacheItem, error)) (CacheItem, error) {
+	return this.shardFor(key).GetOrAdd(key, compute)
+}
+
+// GetOrAddContext retrieves an item from the shard that owns the key like GetOrAdd, giving up if ctx is done first
+func (this *shardedCache) GetOrAddContext(ctx context.Context, key string, compute func(ctx context.Context) (CacheItem, error)) (CacheItem, error) {
+	return this.shardFor(key).GetOrAddContext(ctx, key, compute)
+}
+
+// GetStaleWhileRevalidate retrieves an item from the shard that owns the key, serving it stale and refreshing it in the
+// background once its older than ttl
+func (this *shardedCache) GetStaleWhileRevalidate(key string, ttl, staleWindow time.Duration, refresh func() (CacheItem, error)) (CacheItem, bool) {
+	return this.shardFor(key).GetStaleWhileRevalidate(key, ttl, staleWindow, refresh)
+}
+
+// UpdateSize re-reads the Size of an item that's changed since it was added, in the shard that owns the key
+func (this *shardedCache) UpdateSize(key string) error {
+	return this.shardFor(key).UpdateSize(key)
+}
+
+// AddReturning adds a CacheItem to the shard that owns the key like Add, and returns the item it replaced
+func (this *shardedCache) AddReturning(key string, val CacheItem) (CacheItem, bool, error) {
+	return this.shardFor(key).AddReturning(key, val)
+}
+
+// AddWithCost adds a CacheItem to the shard that owns the key like Add, counting cost towards the shard's size
+func (this *shardedCache) AddWithCost(key string, val CacheItem, cost int) error {
+	return this.shardFor(key).AddWithCost(key, val, cost)
+}
+
+// AddIfAbsent adds a CacheItem to the shard that owns the key, but only if nothing is stored under the key
+func (this *shardedCache) AddIfAbsent(key string, val CacheItem) (bool, error) {
+	return this.shardFor(key).AddIfAbsent(key, val)
+}
+
+// Replace replaces the item stored under the key in the shard that owns it, but only if there is one
+func (this *shardedCache) Replace(key string, val CacheItem) (bool, error) {
+	return this.shardFor(key).Replace(key, val)
+}
+
+// Resize changes the maximum total size of the cache, newMax is split evenly between the shards
+func (this *shardedCache) Resize(newMax int) {
+	for _, shard := range this.shards {
+		shard.Resize(newMax / len(this.shards))
+	}
+}
+
+// RemoveFunc removes every item that pred returns true for from every shard
+func (this *shardedCache) RemoveFunc(pred func(key string, item CacheItem) bool) {
+	for _, shard := range this.shards {
+		shard.RemoveFunc(pred)
+	}
+}
+
+// ForEach calls fn for every item in every shard, one shard after another, until fn returns false
+func (this *shardedCache) ForEach(fn func(key string, item CacheItem) bool) {
+	stopped := false
+	for _, shard := range this.shards {
+		shard.ForEach(func(key string, item CacheItem) bool {
+			stopped = !fn(key, item)
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+	}
+}
+
+// Touch marks an item as used in the shard that owns the key, returning true if it was present
+func (this *shardedCache) Touch(key string) bool {
+	return this.shardFor(key).Touch(key)
+}
\ No newline at end of file
@@ -0,0 +1,98 @@
+package memcache
+
+import (
+	"testing"
+	"strconv"
+)
+
+const (
+	Shards = 8
+)
+
+func TestShardedCache(t *testing.T) {
+	cache := CreateShardedCache(Shards, MaxSize)
+
+	if cache.Cap() != Shards * MaxSize {
+		t.Error("Cap should be", Shards * MaxSize, "got", cache.Cap())
+	}
+
+	// Add few enough items that no shard should need to evict
+	for i := 0; i < 20; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 1})
+	}
+	for i := 0; i < 20; i++ {
+		if _, present := cache.Get(strconv.Itoa(i)); !present {
+			t.Error(strconv.Itoa(i), "should be present")
+		}
+	}
+
+	// Len, Size & Keys should cover all the shards
+	if cache.Len() != 20 || cache.Size() != 20 || len(cache.Keys()) != 20 {
+		t.Error("Expected 20 items of size 20, got", cache.Len(), "items of size", cache.Size(), "and", len(cache.Keys()), "keys")
+	}
+	assertStats(t, cache.Stats(), Stats{Hits: 20, Adds: 20})
+
+	cache.Remove("0")
+	if cache.Contains("0") || cache.Len() != 19 {
+		t.Error("0 should have been removed")
+	}
+
+	cache.Clear()
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected empty cache after Clear, got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestShardedCacheMaxSizeIsPerShard(t *testing.T) {
+	cache := CreateShardedCache(Shards, MaxSize)
+
+	// Each shard can hold one item of MaxSize, so adding lots should leave at most one per shard
+	for i := 0; i < 100; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: MaxSize})
+	}
+	if cache.Len() > Shards || cache.Size() > Shards * MaxSize {
+		t.Error("Expected at most", Shards, "items, got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestShardedCacheNoShards(t *testing.T) {
+	cache := CreateShardedCache(0, MaxSize)
+
+	// Zero shards is clamped to one, so adds and resizes shouldn't divide by zero
+	if err := cache.Add("a", &DummyCacheItem{DummySize: 1}); err != nil {
+		t.Error("Expected add to succeed, got", err)
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("Expected a to be present")
+	}
+	cache.Resize(MaxSize * 2)
+	if cache.Cap() != MaxSize * 2 {
+		t.Error("Expected cap", MaxSize * 2, "got", cache.Cap())
+	}
+}
+
+func TestShardedCacheDistribution(t *testing.T) {
+	cache := CreateShardedCache(Shards, MaxSize).(*shardedCache)
+
+	// Count how many keys go to each shard
+	counts := make(map[Cache]int)
+	keys := 8000
+	for i := 0; i < keys; i++ {
+		counts[cache.shardFor("key" + strconv.Itoa(i))]++
+	}
+
+	// Every shard should be used, and none should be far off an even split
+	expected := keys / Shards
+	if len(counts) != Shards {
+		t.Error("Expected all", Shards, "shards to be used, got", len(counts))
+	}
+	for _, count := range counts {
+		if count < expected * 8 / 10 || count > expected * 12 / 10 {
+			t.Error("Expected roughly", expected, "keys per shard, got", count)
+		}
+	}
+}
+
+func BenchmarkShardedCacheGetParallel(b *testing.B) {
+	benchmarkGetParallel(b, CreateShardedCache(Shards, MaxSize), 100)
+}
@@ -0,0 +1,60 @@
+package memcache
+
+import (
+	"encoding/gob"
+	"io"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: snapshotItem (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// snapshotItem is a single item written by Save
+type snapshotItem struct {
+
+	// Key is the key the item was stored under
+	Key string
+
+	// Item is the item itself, its concrete type must be registered with gob.Register
+	Item CacheItem
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Persistence functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// Save writes all the items in the cache to w, so they can be added back to a cache with Load
+//
+// Items are encoded with encoding/gob. As CacheItem is an interface, every concrete type stored in the cache has to be
+// registered with gob.Register before calling Save or Load. Items are written in the order returned by Keys, and reading
+// them doesn't count as an access
+func Save(cache Cache, w io.Writer) error {
+	keys := cache.Keys()
+	items := make([]snapshotItem, 0, len(keys))
+	for _, key := range keys {
+		// Could have been removed since we got the keys
+		if item, present := cache.Peek(key); present {
+			items = append(items, snapshotItem { Key: key, Item: item })
+		}
+	}
+	return gob.NewEncoder(w).Encode(items)
+}
+
+// Load reads items written by Save from r and adds them to the cache
+//
+// Items are added in reverse so the first key written ends up at the head, an LRU cache ends up in the same order as the
+// cache that was saved. Nothing is added if r can't be decoded, the error is returned instead. If there's an error adding
+// an item then it's returned, items before it will have been added
+func Load(cache Cache, r io.Reader) error {
+	var items []snapshotItem
+	if err := gob.NewDecoder(r).Decode(&items); err != nil {
+		return err
+	}
+
+	for i := len(items) - 1; i >= 0; i-- {
+		if err := cache.Add(items[i].Key, items[i].Item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
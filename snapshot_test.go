@@ -0,0 +1,70 @@
+package memcache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strconv"
+	"testing"
+)
+
+func init() {
+	gob.Register(&DummyCacheItem{})
+}
+
+func TestSaveLoad(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: i + 1})
+	}
+	cache.Get("1")
+
+	var buffer bytes.Buffer
+	if err := Save(cache, &buffer); err != nil {
+		t.Fatal("Unexpected error saving:", err)
+	}
+
+	// Saving shouldn't have changed the order
+	assertKeys(t, cache.Keys(), []string{"1", "4", "3", "2", "0"})
+
+	// Loaded cache should be in the same order with the same items
+	loaded := CreateLRUCache(MaxSize)
+	if err := Load(loaded, &buffer); err != nil {
+		t.Fatal("Unexpected error loading:", err)
+	}
+	assertKeys(t, loaded.Keys(), cache.Keys())
+	for i := 0; i < 5; i++ {
+		item, present := loaded.Peek(strconv.Itoa(i))
+		if !present || item.(*DummyCacheItem).DummySize != i + 1 {
+			t.Error("Expected", strconv.Itoa(i), "of size", i + 1, "got", item)
+		}
+	}
+	if loaded.Size() != cache.Size() {
+		t.Error("Expected size", cache.Size(), "got", loaded.Size())
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	cache := CreateLRUCache(MaxSize)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+
+	var buffer bytes.Buffer
+	if err := Save(cache, &buffer); err != nil {
+		t.Fatal("Unexpected error saving:", err)
+	}
+	saved := buffer.Bytes()
+
+	// Cut short and garbage input should both error without adding anything
+	for name, input := range map[string][]byte {
+		"truncated": saved[:len(saved) / 2],
+		"garbage": []byte("this isn't a snapshot"),
+		"empty": {},
+	} {
+		loaded := CreateLRUCache(MaxSize)
+		if err := Load(loaded, bytes.NewReader(input)); err == nil {
+			t.Error(name, "expected an error loading")
+		}
+		if loaded.Len() != 0 {
+			t.Error(name, "expected nothing to be loaded, got", loaded.Keys())
+		}
+	}
+}
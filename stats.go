@@ -0,0 +1,48 @@
+package memcache
+
+import (
+	"sync/atomic"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: cacheStats (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// cacheStats holds the counters behind Stats, they're atomic so they can be updated without holding the cache mutex
+type cacheStats struct {
+
+	// hits is the number of times Get found the item it was looking for
+	hits atomic.Uint64
+
+	// misses is the number of times Get didn't find the item it was looking for
+	misses atomic.Uint64
+
+	// evictions is the number of items removed to make room for others
+	evictions atomic.Uint64
+
+	// expirations is the number of items removed because they'd expired
+	expirations atomic.Uint64
+
+	// adds is the number of items successfully added
+	adds atomic.Uint64
+}
+
+// get records a Get, as a hit if found
+func (this *cacheStats) get(found bool) {
+	if found {
+		this.hits.Add(1)
+	} else {
+		this.misses.Add(1)
+	}
+}
+
+// snapshot returns the current value of the counters
+func (this *cacheStats) snapshot() Stats {
+	return Stats {
+		Hits: this.hits.Load(),
+		Misses: this.misses.Load(),
+		Evictions: this.evictions.Load(),
+		Expirations: this.expirations.Load(),
+		Adds: this.adds.Load(),
+	}
+}
@@ -0,0 +1,571 @@
+package memcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxDiskBytes is how much disk space NewTieredCache lets its disk tier use
+	DefaultMaxDiskBytes = 256 << 20
+
+	// diskFileExt is the extension of the files the disk tier writes, so they can be told apart from anything else in the
+	// directory
+	diskFileExt = ".memcache"
+
+	// diskDirPattern is the pattern for the subdirectory of diskDir each tiered cache writes its files to
+	diskDirPattern = "tiered-*"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// NewTieredCache creates and returns a Cache that spills items evicted from memory into files in diskDir
+//
+// It's the same as NewTieredCacheWithLimit with a limit of DefaultMaxDiskBytes
+func NewTieredCache(memory Cache, diskDir string) (Cache, error) {
+	return NewTieredCacheWithLimit(memory, diskDir, DefaultMaxDiskBytes)
+}
+
+// NewTieredCacheWithLimit creates and returns a Cache that spills items evicted from memory into files in diskDir, using
+// at most maxDiskBytes of disk
+//
+// Get looks in memory first, then on disk. An item found on disk is added back into memory and its file is removed, so
+// it's only ever in one tier. Items are encoded with encoding/gob into a file named from a hash of their key, so like
+// Save every concrete item type has to be registered with gob.Register. The files are kept in LRU order, once they take
+// up more than maxDiskBytes the least recently spilled are deleted
+//
+// The files go in a new subdirectory of diskDir that only this cache uses, so other caches (or anything else) can share
+// diskDir without their files being touched. An error is returned if diskDir or the subdirectory can't be created.
+// Nothing knows what's in a subdirectory once the cache that made it has gone, so it's up to the caller to clear out
+// diskDir when the process starts if it's been used before
+//
+// To spill items memory has to implement EvictableCache and FreezableCache (the LRU and FIFO caches do). Its frozen while
+// an item is added, so it charges the item whatever it normally would (its SizeFunc or the cost its added with), then
+// items are evicted from it with Evict and spilled until its back under its max size. It's unfrozen again afterwards so
+// nothing else should freeze it. Other caches evict items themselves so every item is written to disk as its added
+// instead, and the file is kept when its loaded back. If an item can't be written then its dropped, as it would have been
+// without the disk tier
+func NewTieredCacheWithLimit(memory Cache, diskDir string, maxDiskBytes int) (Cache, error) {
+	if err := os.MkdirAll(diskDir, 0o755); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(diskDir, diskDirPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &tieredCache { memory: memory, dir: dir }
+	cache.spillable, _ = memory.(spillableCache)
+	cache.disk = CreateLRUCacheWithCallback(maxDiskBytes, func(key string, item CacheItem, reason EvictReason) {
+		os.Remove(item.(*diskFile).path)
+	}, true)
+	return cache, nil
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: diskFile (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// diskFile is a file written by a tieredCache, its stored in the disk index so the files are evicted in LRU order
+type diskFile struct {
+
+	// path is where the file is
+	path string
+
+	// size is how big the file is
+	size int
+}
+
+// Size returns the size of the file, so the disk index is bounded by disk usage
+func (this *diskFile) Size() int {
+	return this.size
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Interface: spillableCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// spillableCache is a memory cache a tieredCache can spill items from, it can be frozen while items are added and then
+// have items evicted one at a time
+type spillableCache interface {
+	EvictableCache
+	FreezableCache
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: tieredCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// tieredCache wraps a Cache and keeps items it evicts on disk
+//
+// disk is an LRU Cache of the files written, keyed by the item's key. Its evict callback deletes the file, so removing a
+// key from the index (or it being evicted) cleans up the file too
+type tieredCache struct {
+
+	// memory is the cache items are kept in until they're spilled
+	memory Cache
+
+	// spillable is the memory cache if it implements EvictableCache and FreezableCache, nil otherwise
+	spillable spillableCache
+
+	// dir is the subdirectory of diskDir the files are written to, only this cache uses it
+	dir string
+
+	// disk is the index of files written
+	disk Cache
+
+	// mutex makes sure making room, adding and moving items between the tiers happens for one key at a time
+	mutex sync.Mutex
+
+	// flights makes sure GetOrAdd only calls compute once for a key, however many callers are waiting on it
+	flights flightGroup
+}
+
+// path returns the file an item with the key is written to
+func (this *tieredCache) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(this.dir, hex.EncodeToString(hash[:]) + diskFileExt)
+}
+
+// spill writes an item to disk and adds it to the disk index, its dropped if it can't be written
+func (this *tieredCache) spill(key string, item CacheItem) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&snapshotItem { Key: key, Item: item }); err != nil {
+		return
+	}
+	path := this.path(key)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return
+	}
+	if err := this.disk.Add(key, &diskFile { path: path, size: buf.Len() }); err != nil {
+		os.Remove(path)
+	}
+}
+
+// load reads an item back from disk, nil, false if there isn't one (or it can't be read)
+func (this *tieredCache) load(key string) (CacheItem, bool) {
+	file, present := this.disk.Get(key)
+	if !present {
+		return nil, false
+	}
+	return this.read(key, file.(*diskFile))
+}
+
+// read decodes the item in a file from the disk index. If it can't be read then its file is removed too
+func (this *tieredCache) read(key string, file *diskFile) (CacheItem, bool) {
+	var item snapshotItem
+	data, err := os.ReadFile(file.path)
+	if err == nil {
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(&item)
+	}
+	if err != nil || item.Key != key {
+		this.disk.Remove(key)
+		return nil, false
+	}
+	return item.Item, true
+}
+
+// spilled calls fn for every item on disk that isn't also in memory, in LRU order, until fn returns false. Files that
+// can't be read are skipped, and none are read once fn has returned false
+func (this *tieredCache) spilled(fn func(key string, item CacheItem) bool) {
+	for _, key := range this.disk.Keys() {
+		if this.memory.Contains(key) {
+			continue
+		}
+		file, present := this.disk.Peek(key)
+		if !present {
+			continue
+		}
+		if item, ok := this.read(key, file.(*diskFile)); ok && !fn(key, item) {
+			return
+		}
+	}
+}
+
+// contains returns true if the key is in memory or on disk
+func (this *tieredCache) contains(key string) bool {
+	return this.memory.Contains(key) || this.disk.Contains(key)
+}
+
+// grow calls op with memory frozen, so memory doesn't evict anything to make room for what op adds, then spills items
+// to disk until memory's back under its max size. Memory's own Size is used throughout, so items count for whatever
+// memory charges them
+func (this *tieredCache) grow(op func() error) error {
+	this.spillable.Freeze()
+	defer this.spillable.Unfreeze()
+
+	err := op()
+	this.spillDownTo(this.spillable.Cap())
+	return err
+}
+
+// spillDownTo evicts items from memory and spills them to disk until memory's size is no more than target
+func (this *tieredCache) spillDownTo(target int) {
+	for this.spillable.Size() > target {
+		evictedKey, evicted, ok := this.spillable.Evict()
+		if !ok {
+			return
+		}
+		this.spill(evictedKey, evicted)
+	}
+}
+
+// store adds an item to memory through add, locking so it doesn't happen at the same time as other moves between tiers
+func (this *tieredCache) store(key string, val CacheItem, add func() error) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.put(key, val, add)
+}
+
+// put adds an item to memory through add, spilling items to make room for it. Once its been added any copy on disk is
+// out of date so its removed, if memory isn't a spillableCache then the item is written to disk instead. The mutex must
+// be held
+func (this *tieredCache) put(key string, val CacheItem, add func() error) error {
+	if val == nil {
+		return ErrNilItem
+	}
+
+	if this.spillable == nil {
+		if err := add(); err != nil {
+			return err
+		}
+		this.disk.Remove(key)
+		this.spill(key, val)
+		return nil
+	}
+	return this.grow(func() error {
+		if err := add(); err != nil {
+			return err
+		}
+		this.disk.Remove(key)
+		return nil
+	})
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Cache Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// Add adds the item to memory, spilling items to disk to make room for it
+func (this *tieredCache) Add(key string, val CacheItem) error {
+	return this.store(key, val, func() error {
+		return this.memory.Add(key, val)
+	})
+}
+
+// AddReturning adds the item to memory like Add, and returns the item it replaced there
+func (this *tieredCache) AddReturning(key string, val CacheItem) (CacheItem, bool, error) {
+	var prev CacheItem
+	var existed bool
+	err := this.store(key, val, func() (err error) {
+		prev, existed, err = this.memory.AddReturning(key, val)
+		return err
+	})
+	return prev, existed, err
+}
+
+// AddWithCost adds the item to memory with that cost like Add, spilling items to make room for the cost
+func (this *tieredCache) AddWithCost(key string, val CacheItem, cost int) error {
+	return this.store(key, val, func() error {
+		return this.memory.AddWithCost(key, val, cost)
+	})
+}
+
+// AddIfAbsent adds the item like Add, but only if the key is in neither memory nor on disk
+func (this *tieredCache) AddIfAbsent(key string, val CacheItem) (bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.contains(key) {
+		return false, nil
+	}
+	if err := this.put(key, val, func() error { return this.memory.Add(key, val) }); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Replace replaces the item like Add, but only if the key is already in memory or on disk
+func (this *tieredCache) Replace(key string, val CacheItem) (bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if !this.contains(key) {
+		return false, nil
+	}
+	if err := this.put(key, val, func() error { return this.memory.Add(key, val) }); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Get retrieves an item from memory, or from disk if its been spilled. Items found on disk are moved back into memory,
+// or left on disk if memory won't take them
+//
+// If item is present then the item, true is returned. Otherwise, nil, false
+func (this *tieredCache) Get(key string) (CacheItem, bool) {
+	if item, present := this.memory.Get(key); present {
+		return item, true
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	item, present := this.load(key)
+	if !present {
+		return nil, false
+	}
+
+	// Written through, so the file can stay as it is
+	if this.spillable == nil {
+		this.memory.Add(key, item)
+		return item, true
+	}
+
+	// The file's only removed once the item's in memory, before anything's spilled so it isn't counted against the disk
+	// limit. If memory won't take the item then it stays on disk
+	this.grow(func() error {
+		if err := this.memory.Add(key, item); err != nil {
+			return err
+		}
+		this.disk.Remove(key)
+		return nil
+	})
+	return item, true
+}
+
+// GetOrErr retrieves an item like Get, returning ErrNotFound if its in neither tier
+func (this *tieredCache) GetOrErr(key string) (CacheItem, error) {
+	return getOrErr(this.Get(key))
+}
+
+// GetWithAge retrieves an item like Get, along with how long its been since it was added to memory. An item loaded back
+// from disk counts as just added
+func (this *tieredCache) GetWithAge(key string) (CacheItem, time.Duration, bool) {
+	if item, age, present := this.memory.GetWithAge(key); present {
+		return item, age, true
+	}
+	item, present := this.Get(key)
+	if !present {
+		return nil, 0, false
+	}
+	return item, 0, true
+}
+
+// GetOrAdd retrieves an item like Get, loading it from disk if its been spilled. Otherwise compute is called and the
+// item it returns is added like Add
+func (this *tieredCache) GetOrAdd(key string, compute func() (CacheItem, error)) (CacheItem, error) {
+	return getOrAdd(this, &this.flights, key, compute)
+}
+
+// GetOrAddContext works like GetOrAdd, but gives up if ctx is done before it has the item
+func (this *tieredCache) GetOrAddContext(ctx context.Context, key string, compute func(ctx context.Context) (CacheItem, error)) (CacheItem, error) {
+	return getOrAddContext(ctx, this, &this.flights, key, compute)
+}
+
+// GetStaleWhileRevalidate retrieves an item, serving it stale and refreshing it in the background once its older than
+// ttl. Refreshed items are added like Add, so making room for them spills to disk
+func (this *tieredCache) GetStaleWhileRevalidate(key string, ttl, staleWindow time.Duration, refresh func() (CacheItem, error)) (CacheItem, bool) {
+	return getStaleWhileRevalidate(this, &this.flights, key, ttl, staleWindow, refresh)
+}
+
+// Peek retrieves an item from memory, or reads it from disk if its been spilled. It doesn't count as an access, so an
+// item on disk is left there
+func (this *tieredCache) Peek(key string) (CacheItem, bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if item, present := this.memory.Peek(key); present {
+		return item, true
+	}
+	file, present := this.disk.Peek(key)
+	if !present {
+		return nil, false
+	}
+	return this.read(key, file.(*diskFile))
+}
+
+// Contains returns true if the item is in memory or on disk, without counting as an access
+func (this *tieredCache) Contains(key string) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.contains(key)
+}
+
+// Touch marks an item as used, returning true if it was present. Items on disk are marked as the most recently spilled
+// rather than being loaded back
+func (this *tieredCache) Touch(key string) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.memory.Touch(key) {
+		return true
+	}
+	_, present := this.disk.Get(key)
+	return present
+}
+
+// UpdateSize re-reads the Size of an item in memory, spilling items to disk to make room if its grown
+//
+// If its now too big for memory then its removed from both tiers and an error is returned. Items on disk were written
+// with the size they had then, so nothing happens for those
+func (this *tieredCache) UpdateSize(key string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	item, present := this.memory.Peek(key)
+	if !present {
+		return nil
+	}
+	if this.spillable == nil {
+		if err := this.memory.UpdateSize(key); err != nil {
+			this.disk.Remove(key)
+			return err
+		}
+		this.spill(key, item)
+		return nil
+	}
+
+	return this.grow(func() error {
+		return this.memory.UpdateSize(key)
+	})
+}
+
+// Remove removes an item from memory and deletes its file if its been spilled
+func (this *tieredCache) Remove(key string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.memory.Remove(key)
+	this.disk.Remove(key)
+}
+
+// RemoveFunc removes every item that pred returns true for, from memory and from disk
+//
+// Items on disk have to be read back to be passed to pred. pred is called with the cache locked so it mustn't call back
+// into the cache
+func (this *tieredCache) RemoveFunc(pred func(key string, item CacheItem) bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	// Written through items are in both tiers, those removed from memory go from disk too
+	removed := make(map[string]bool)
+	this.memory.RemoveFunc(func(key string, item CacheItem) bool {
+		if pred(key, item) {
+			removed[key] = true
+			return true
+		}
+		return false
+	})
+	for key := range removed {
+		this.disk.Remove(key)
+	}
+	this.spilled(func(key string, item CacheItem) bool {
+		if pred(key, item) {
+			this.disk.Remove(key)
+		}
+		return true
+	})
+}
+
+// Clear removes all items from memory and deletes all the files on disk
+func (this *tieredCache) Clear() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.memory.Clear()
+	this.disk.(TrimmableCache).Trim(0)
+}
+
+// Resize changes the max size of memory, spilling items to disk until its under the new size
+func (this *tieredCache) Resize(newMax int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.spillable != nil {
+		this.spillDownTo(newMax)
+	}
+	this.memory.Resize(newMax)
+}
+
+// Len returns the number of items in memory and on disk
+func (this *tieredCache) Len() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	total := this.memory.Len()
+	for _, key := range this.disk.Keys() {
+		if !this.memory.Contains(key) {
+			total++
+		}
+	}
+	return total
+}
+
+// Size returns the total size of the items in memory, items on disk don't count towards it
+func (this *tieredCache) Size() int {
+	return this.memory.Size()
+}
+
+// Cap returns the max size of memory
+func (this *tieredCache) Cap() int {
+	return this.memory.Cap()
+}
+
+// Stats returns the Stats of memory
+func (this *tieredCache) Stats() Stats {
+	return this.memory.Stats()
+}
+
+// Keys returns the keys of the items in memory in memory's order, followed by the keys of those on disk from the most
+// to the least recently spilled
+func (this *tieredCache) Keys() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	keys := this.memory.Keys()
+	for _, key := range this.disk.Keys() {
+		if !this.memory.Contains(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ForEach calls fn for every item in memory and then every item on disk, in the same order as Keys, until fn returns
+// false. Items on disk are read back to be passed to fn
+func (this *tieredCache) ForEach(fn func(key string, item CacheItem) bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	stopped := false
+	this.memory.ForEach(func(key string, item CacheItem) bool {
+		stopped = !fn(key, item)
+		return !stopped
+	})
+	if !stopped {
+		this.spilled(fn)
+	}
+}
+
+// GetAll returns a snapshot of every item in memory and on disk, keyed by their keys
+func (this *tieredCache) GetAll() map[string]CacheItem {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	items := this.memory.GetAll()
+	this.spilled(func(key string, item CacheItem) bool {
+		items[key] = item
+		return true
+	})
+	return items
+}
\ No newline at end of file
@@ -0,0 +1,260 @@
+package memcache
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// newTieredCache creates a tiered cache in a temporary directory, failing the test if it can't be
+func newTieredCache(t *testing.T, memory Cache, maxDiskBytes int) Cache {
+	cache, err := NewTieredCacheWithLimit(memory, t.TempDir(), maxDiskBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+// diskFiles returns the files the disk tier of a tiered cache has written
+func diskFiles(t *testing.T, cache Cache) []string {
+	files, err := filepath.Glob(filepath.Join(cache.(*tieredCache).dir, "*" + diskFileExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestTieredCache(t *testing.T) {
+	cache := newTieredCache(t, CreateLRUCache(20), DefaultMaxDiskBytes)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// a is evicted from memory to make room, it should be on disk
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"c", "b", "a"})
+	if files := diskFiles(t, cache); len(files) != 1 || files[0] != cache.(*tieredCache).path("a") {
+		t.Error("Expected a to have been written to disk, got", files)
+	}
+
+	// Getting it loads it back into memory, spilling b, and the file for a goes
+	item, present := cache.Get("a")
+	if !present || item.(*DummyCacheItem).DummySize != 10 {
+		t.Error("Expected a to be loaded from disk, got", item, present)
+	}
+	assertKeys(t, cache.Keys(), []string{"a", "c", "b"})
+	if files := diskFiles(t, cache); len(files) != 1 || files[0] != cache.(*tieredCache).path("b") {
+		t.Error("Expected only b to be on disk, got", files)
+	}
+
+	// Adding b again makes its file out of date, removing it from both takes it off disk
+	cache.Add("b", &DummyCacheItem{DummySize: 5})
+	if item, _ := cache.Get("b"); item.Size() != 5 {
+		t.Error("Expected the new b, got", item)
+	}
+	cache.Remove("c")
+	cache.Remove("b")
+	if _, present := cache.Get("c"); present {
+		t.Error("c should have been removed from both tiers")
+	}
+
+	// Clear deletes everything on disk
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	cache.Clear()
+	if files := diskFiles(t, cache); len(files) != 0 || cache.Len() != 0 {
+		t.Error("Expected Clear to empty both tiers, got", cache.Keys(), "and", files)
+	}
+}
+
+func TestTieredCacheSpilledKey(t *testing.T) {
+	cache := newTieredCache(t, CreateLRUCache(20), DefaultMaxDiskBytes)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// a is on disk, it still counts as being in the cache
+	if !cache.Contains("a") {
+		t.Error("Expected a to be found on disk")
+	}
+	if item, present := cache.Peek("a"); !present || item.Size() != 10 {
+		t.Error("Expected to peek a from disk, got", item, present)
+	}
+	if cache.Len() != 3 {
+		t.Error("Expected 3 items across both tiers, got", cache.Len())
+	}
+
+	// So it isn't replaced, and adding d spills b rather than losing it
+	if added, err := cache.AddIfAbsent("a", &DummyCacheItem{DummySize: 5}); added || err != nil {
+		t.Error("Expected a not to be added, got", added, err)
+	}
+	if added, err := cache.AddIfAbsent("d", &DummyCacheItem{DummySize: 10}); !added || err != nil {
+		t.Error("Expected d to be added, got", added, err)
+	}
+	assertKeys(t, cache.Keys(), []string{"d", "c", "b", "a"})
+
+	// GetOrAdd loads from disk instead of computing
+	item, err := cache.GetOrAdd("a", func() (CacheItem, error) {
+		t.Error("compute shouldn't be called for a key on disk")
+		return &DummyCacheItem{DummySize: 5}, nil
+	})
+	if err != nil || item.Size() != 10 {
+		t.Error("Expected a from disk, got", item, err)
+	}
+	assertKeys(t, cache.Keys(), []string{"a", "d", "c", "b"})
+	if len(cache.GetAll()) != 4 {
+		t.Error("Expected all 4 items, got", cache.GetAll())
+	}
+}
+
+func TestTieredCacheGetKeepsFile(t *testing.T) {
+	memory := CreateLRUCache(20)
+	cache := newTieredCache(t, memory, DefaultMaxDiskBytes)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Memory can't take a back any more, so it should stay on disk rather than being lost from both
+	memory.Resize(5)
+	if _, present := cache.Get("a"); !present {
+		t.Error("Expected a to be loaded from disk")
+	}
+	if files := diskFiles(t, cache); len(files) != 1 || files[0] != cache.(*tieredCache).path("a") {
+		t.Error("Expected a to still be on disk, got", files)
+	}
+	if _, present := cache.Get("a"); !present {
+		t.Error("Expected a to still be present")
+	}
+}
+
+func TestTieredCacheChargedSize(t *testing.T) {
+	// Memory charges ten times the item's Size, spilling has to go by that rather than Size
+	memory := CreateLRUCacheWithSizeFunc(100, func(item CacheItem) int {
+		return item.Size() * 10
+	})
+	cache := newTieredCache(t, memory, DefaultMaxDiskBytes)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		cache.Add(key, &DummyCacheItem{DummySize: 3})
+	}
+	assertKeys(t, cache.Keys(), []string{"d", "c", "b", "a"})
+	if memory.Size() != 90 {
+		t.Error("Expected 3 items charged 30 each in memory, got", memory.Size())
+	}
+
+	// Costs work the same way, replacing b with a bigger cost spills what no longer fits
+	cache.AddWithCost("b", &DummyCacheItem{DummySize: 1}, 60)
+	if cache.Len() != 4 || memory.Size() > memory.Cap() {
+		t.Error("Expected nothing to be lost, got", cache.Keys(), "with", memory.Size(), "in memory")
+	}
+	assertKeys(t, memory.Keys(), []string{"b", "d"})
+}
+
+func TestTieredCacheForEachStops(t *testing.T) {
+	cache := newTieredCache(t, CreateLRUCache(10), DefaultMaxDiskBytes)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// A file that can't be read is dropped when its read, so it shows whether ForEach went on to disk
+	os.WriteFile(cache.(*tieredCache).path("a"), []byte("corrupt"), 0o644)
+	calls := 0
+	cache.ForEach(func(key string, item CacheItem) bool {
+		calls++
+		return false
+	})
+	if calls != 1 || cache.Len() != 2 {
+		t.Error("Expected ForEach to stop without reading from disk, got", calls, "calls and", cache.Len(), "items")
+	}
+}
+
+func TestTieredCacheResize(t *testing.T) {
+	cache := newTieredCache(t, CreateLRUCache(30), DefaultMaxDiskBytes)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Shrinking memory spills what no longer fits
+	cache.Resize(10)
+	if cache.Size() != 10 || cache.Len() != 3 {
+		t.Error("Expected 1 item in memory and 3 in total, got size", cache.Size(), "and", cache.Len(), "items")
+	}
+
+	// Items on disk are removed by RemoveFunc too
+	cache.RemoveFunc(func(key string, item CacheItem) bool {
+		return key != "b"
+	})
+	assertKeys(t, cache.Keys(), []string{"b"})
+}
+
+func TestTieredCacheDiskLimit(t *testing.T) {
+	probe := newTieredCache(t, CreateLRUCache(10), DefaultMaxDiskBytes)
+	probe.Add("0", &DummyCacheItem{DummySize: 10})
+	probe.Add("1", &DummyCacheItem{DummySize: 10})
+	fileSize := probe.(*tieredCache).disk.Size()
+
+	// Room on disk for 3 files, the oldest spilled should be deleted
+	cache := newTieredCache(t, CreateLRUCache(10), fileSize * 3)
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if files := diskFiles(t, cache); len(files) != 3 {
+		t.Error("Expected 3 files on disk, got", files)
+	}
+	for i, expected := range []bool{false, false, false, false, false, false, true, true, true, true} {
+		if _, present := cache.Get(strconv.Itoa(i)); present != expected {
+			t.Error("Expected", i, "present", expected, "got", present)
+		}
+	}
+}
+
+func TestTieredCacheSharedDir(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(dir, "other" + diskFileExt)
+	os.WriteFile(other, []byte("keep"), 0o644)
+	first, err := NewTieredCache(CreateLRUCache(10), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Add("a", &DummyCacheItem{DummySize: 10})
+	first.Add("b", &DummyCacheItem{DummySize: 10})
+
+	// A second cache in the same directory gets its own files, the first cache's and anything else are left alone
+	second, err := NewTieredCache(CreateLRUCache(10), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Add("a", &DummyCacheItem{DummySize: 10})
+	second.Add("c", &DummyCacheItem{DummySize: 10})
+	if files := diskFiles(t, first); len(files) != 1 {
+		t.Error("Expected the first cache to keep its file, got", files)
+	}
+	if item, present := first.Get("a"); !present || item.Size() != 10 {
+		t.Error("Expected a to be loaded from the first cache's file, got", item, present)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Error("Expected other files to be left alone, got", err)
+	}
+}
+
+func TestTieredCacheBadDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, []byte("not a directory"), 0o644)
+
+	// Somewhere a directory can't be made should be an error straight away, not dropped spills later
+	if cache, err := NewTieredCache(CreateLRUCache(10), file); err == nil {
+		t.Error("Expected an error, got", cache)
+	}
+}
+
+func TestTieredCacheWriteThrough(t *testing.T) {
+	// The LFU cache can't say what it evicts, so everything is written to disk as its added
+	cache := newTieredCache(t, CreateLFUCache(10), DefaultMaxDiskBytes)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	if len(diskFiles(t, cache)) != 2 {
+		t.Error("Expected both items on disk, got", diskFiles(t, cache))
+	}
+	if _, present := cache.Get("a"); !present {
+		t.Error("Expected a to be loaded from disk")
+	}
+}
\ No newline at end of file
@@ -0,0 +1,525 @@
+package memcache
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateTimedCache creates and returns a Cache where items expire once they've been in the cache for ttl
+//
+// There's no size limit, nothing is ever evicted to make room so Cap returns math.MaxInt. Expired items are removed when
+// they're next accessed. Adding or touching an item again restarts its ttl
+func CreateTimedCache(ttl time.Duration) (Cache) {
+	return &timedCache { entries: make(map[string]*timedEntry), ttl: ttl }
+}
+
+// CreateTimedCacheWithJanitor creates and returns a Timed Cache with a janitor that removes expired items
+//
+// Every sweepInterval the janitor removes anything that's expired, so expired items don't take up memory until they're
+// next accessed. The returned Cache implements io.Closer, Close must be called to stop the janitor
+func CreateTimedCacheWithJanitor(ttl, sweepInterval time.Duration) (Cache) {
+	cache := &timedCache { entries: make(map[string]*timedEntry), ttl: ttl, stop: make(chan struct{}) }
+	go cache.janitor(sweepInterval)
+	return cache
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: timedEntry (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// timedEntry represents a single item in a timedCache
+type timedEntry struct {
+
+	// cacheItem is the underlying item
+	cacheItem CacheItem
+
+	// key is the key we'd use in Get(key) to retrieve the item
+	key string
+
+	// size is the size of the item when it was added (or last updated), so the same size is taken off when its removed
+	size int
+
+	// added is when the item was added or last touched, its ttl runs from here
+	added time.Time
+
+	// ttl is how long the item stays in the cache after added, 0 if it never expires. Its the cache's ttl unless it was
+	// added with AddWithTTL
+	ttl time.Duration
+
+	// index is the position of the entry in the expiry heap
+	index int
+}
+
+// expiresAt returns when the entry expires, for expiryHeap
+func (this *timedEntry) expiresAt() time.Time {
+	return expiry(this.added, this.ttl)
+}
+
+// setHeapIndex records the entry's position in the expiry heap
+func (this *timedEntry) setHeapIndex(index int) {
+	this.index = index
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: timedCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// timedCache implements the Timed cache
+//
+// It keeps a hash of entries for fast access, and a heap of the same entries ordered by when they expire so expired ones
+// can be found without looking at the rest
+type timedCache struct {
+
+	// entries is the map of key(string) to entry
+	entries map[string]*timedEntry
+
+	// expiries holds the entries, next to expire first
+	expiries expiryHeap[*timedEntry]
+
+	// ttl is how long items stay in the cache before they expire, 0 if they never expire
+	ttl time.Duration
+
+	// curSize holds the current size of the cache
+	curSize int
+
+	// mutex is used to synchronize cache as it can be accessed by multiple goroutines
+	mutex sync.Mutex
+
+	// stats holds the hit, miss, eviction and add counts
+	stats cacheStats
+
+	// flights makes sure GetOrAdd only computes a missing item once
+	flights flightGroup
+
+	// stop is closed to stop the janitor goroutine, nil if there isn't one
+	stop chan struct{}
+
+	// closeOnce makes sure stop is only closed once
+	closeOnce sync.Once
+
+	// clock tells the cache the time, nil for the real time
+	clock clock
+}
+
+// now returns the current time from the cache's clock, the real time if it hasn't been given one
+func (this *timedCache) now() time.Time {
+	if this.clock == nil {
+		return time.Now()
+	}
+	return this.clock.Now()
+}
+
+// expired returns true if the entry has been in the cache for longer than its ttl
+func (this *timedCache) expired(entry *timedEntry) bool {
+	return entry.ttl > 0 && this.now().Sub(entry.added) > entry.ttl
+}
+
+// live returns the entry for a key, nil if its missing. If its expired then its removed and nil is returned
+func (this *timedCache) live(key string) *timedEntry {
+	entry, present := this.entries[key]
+	if !present {
+		return nil
+	}
+	if this.expired(entry) {
+		this.remove(entry)
+		this.stats.expirations.Add(1)
+		return nil
+	}
+	return entry
+}
+
+// remove takes an entry out of the hash and the heap, and its size off the cache
+func (this *timedCache) remove(entry *timedEntry) {
+	heap.Remove(&this.expiries, entry.index)
+	delete(this.entries, entry.key)
+	this.curSize -= entry.size
+}
+
+// janitor removes expired items every interval until stop is closed
+func (this *timedCache) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			this.removeExpired()
+		case <-this.stop:
+			return
+		}
+	}
+}
+
+// removeExpired removes entries from the top of the heap until it reaches one that hasn't expired
+func (this *timedCache) removeExpired() {
+	// Lock method so hash & heap can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	for len(this.expiries) > 0 && this.expired(this.expiries[0]) {
+		this.remove(this.expiries[0])
+		this.stats.expirations.Add(1)
+	}
+}
+
+// add implements AddWithTTL, and Add, AddReturning and AddWithCost through addDefault. size is what the item counts as
+// towards the size of the cache and ttl is how long its kept for
+func (this *timedCache) add(k string, v CacheItem, size int, ttl time.Duration) (CacheItem, bool, error) {
+	// Lock method so hash & heap can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return this.store(k, v, size, ttl)
+}
+
+// addDefault is add with the cache's ttl, its read once the lock is held as SetDefaultTTL can change it
+func (this *timedCache) addDefault(k string, v CacheItem, size int) (CacheItem, bool, error) {
+	// Lock method so hash & heap can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return this.store(k, v, size, this.ttl)
+}
+
+// store does the work for add, it must be called with the lock held
+func (this *timedCache) store(k string, v CacheItem, size int, ttl time.Duration) (CacheItem, bool, error) {
+	// Nothing would be able to use a nil item, leave whatever's under the key as it is
+	if v == nil {
+		return nil, false, ErrNilItem
+	}
+
+	// There's no max size, but a negative size would knock the cache size out
+	if size < 0 {
+		return nil, false, ErrNegativeSize
+	}
+	this.stats.adds.Add(1)
+
+	// Already there, replace the item and restart its ttl. It moves in the heap as it now expires later
+	if entry, present := this.entries[k]; present {
+		prev := entry.cacheItem
+		this.curSize += size - entry.size
+		entry.cacheItem = v
+		entry.size = size
+		entry.added = this.now()
+		entry.ttl = ttl
+		heap.Fix(&this.expiries, entry.index)
+		return prev, true, nil
+	}
+
+	entry := &timedEntry { cacheItem: v, key: k, size: size, added: this.now(), ttl: ttl }
+	this.entries[k] = entry
+	heap.Push(&this.expiries, entry)
+	this.curSize += size
+	return nil, false, nil
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Cache Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// Add adds a CacheItem to the cache, it can be retrieved using Get and passing in the same key
+//
+// If an item already exists under the key then its replaced and the ttl restarts. Nothing is ever evicted to make room
+// so Add only fails if the item's size is negative
+func (this *timedCache) Add(k string, v CacheItem) error {
+	_, _, err := this.addDefault(k, v, itemSize(v))
+	return err
+}
+
+// AddReturning adds a CacheItem to the cache like Add, and returns the item it replaced
+//
+// If an item was already present under the key then it's returned with existed set to true, even if its the same item.
+// An expired item that hasn't been removed yet still counts as present
+func (this *timedCache) AddReturning(k string, v CacheItem) (CacheItem, bool, error) {
+	return this.addDefault(k, v, itemSize(v))
+}
+
+// AddWithCost adds a CacheItem to the cache like Add, but with cost used as its size instead of v.Size()
+func (this *timedCache) AddWithCost(k string, v CacheItem, cost int) error {
+	_, _, err := this.addDefault(k, v, cost)
+	return err
+}
+
+// AddIfAbsent adds a CacheItem to the cache like Add, but only if nothing is stored under the key
+//
+// Returns true if the item was added. If the key's already present then false is returned and the existing item and its
+// ttl are left alone. An expired item counts as absent, its removed and replaced
+func (this *timedCache) AddIfAbsent(k string, v CacheItem) (bool, error) {
+	// Lock method so hash & heap can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.live(k) != nil {
+		return false, nil
+	}
+	_, _, err := this.store(k, v, itemSize(v), this.ttl)
+	return err == nil, err
+}
+
+// Replace replaces the item stored under the key like Add, but only if there is one
+//
+// Returns true if the item was replaced, its ttl restarts. If the key's missing (or its item has expired) then false is
+// returned and nothing is added
+func (this *timedCache) Replace(k string, v CacheItem) (bool, error) {
+	// Lock method so hash & heap can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.live(k) == nil {
+		return false, nil
+	}
+	_, _, err := this.store(k, v, itemSize(v), this.ttl)
+	return err == nil, err
+}
+
+// SetDefaultTTL changes the ttl given to items added from now on, items already in the cache keep the ttl they were
+// added with. A ttl of 0 means items added from now on never expire
+func (this *timedCache) SetDefaultTTL(ttl time.Duration) {
+	// Lock method so hash & heap can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.ttl = ttl
+}
+
+// AddWithTTL adds a CacheItem to the cache like Add, but it expires after ttl instead of the cache's ttl
+//
+// A ttl of 0 means the item never expires. Adding the item again with Add goes back to the cache's ttl, Touch restarts
+// the item's own ttl
+func (this *timedCache) AddWithTTL(k string, v CacheItem, ttl time.Duration) error {
+	_, _, err := this.add(k, v, itemSize(v), ttl)
+	return err
+}
+
+// Get retrieves an item from the cache if its present
+//
+// If item is present then the item, true is returned. Otherwise, nil, false. If the item has expired its removed and
+// nil, false is returned
+func (this *timedCache) Get(key string) (CacheItem, bool) {
+	item, _, present := this.GetWithAge(key)
+	return item, present
+}
+
+// GetOrErr retrieves an item like Get, returning ErrNotFound if it isn't present or has expired
+func (this *timedCache) GetOrErr(key string) (CacheItem, error) {
+	return getOrErr(this.Get(key))
+}
+
+// GetWithAge retrieves an item like Get, along with how long its been since it was added or last touched
+func (this *timedCache) GetWithAge(key string) (CacheItem, time.Duration, bool) {
+	// Lock method so hash & heap can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	entry := this.live(key)
+	this.stats.get(entry != nil)
+	if entry == nil {
+		return nil, 0, false
+	}
+	return entry.cacheItem, this.now().Sub(entry.added), true
+}
+
+// Remove removes an item from the cache
+func (this *timedCache) Remove(key string) {
+	// Lock method so hash & heap can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if entry, present := this.entries[key]; present {
+		this.remove(entry)
+	}
+}
+
+// Len returns the number of items currently stored in the cache
+//
+// Expired items that haven't been removed yet are included
+func (this *timedCache) Len() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return len(this.entries)
+}
+
+// Size returns the total size of all the items currently stored in the cache
+//
+// Expired items that haven't been removed yet are included
+func (this *timedCache) Size() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return this.curSize
+}
+
+// Cap returns math.MaxInt, there's no size limit
+func (this *timedCache) Cap() int {
+	return math.MaxInt
+}
+
+// Clear removes all items from the cache
+func (this *timedCache) Clear() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.entries = make(map[string]*timedEntry)
+	this.expiries = nil
+	this.curSize = 0
+}
+
+// Keys returns the keys of all the unexpired items in the cache, in no particular order
+func (this *timedCache) Keys() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	keys := make([]string, 0, len(this.entries))
+	for key, entry := range this.entries {
+		if !this.expired(entry) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// GetAll returns a snapshot of every unexpired item in the cache
+func (this *timedCache) GetAll() map[string]CacheItem {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	all := make(map[string]CacheItem, len(this.entries))
+	for key, entry := range this.entries {
+		if !this.expired(entry) {
+			all[key] = entry.cacheItem
+		}
+	}
+	return all
+}
+
+// Stats returns counts of the hits, misses, expirations and adds since the cache was created. Evictions is always 0 as
+// nothing is ever removed to make room
+func (this *timedCache) Stats() Stats {
+	return this.stats.snapshot()
+}
+
+// Peek retrieves an item from the cache if its present and hasn't expired, without counting as a hit or miss
+func (this *timedCache) Peek(key string) (CacheItem, bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if entry := this.live(key); entry != nil {
+		return entry.cacheItem, true
+	}
+	return nil, false
+}
+
+// Contains returns true if the item is present in the cache and hasn't expired
+func (this *timedCache) Contains(key string) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return this.live(key) != nil
+}
+
+// Close stops the janitor if the cache has one, it's safe to call more than once
+func (this *timedCache) Close() error {
+	this.closeOnce.Do(func() {
+		if this.stop != nil {
+			close(this.stop)
+		}
+	})
+	return nil
+}
+
+// GetOrAdd retrieves an item from the cache if its present, otherwise it calls compute and adds the item it returns
+//
+// The cache isn't locked while compute runs, but concurrent callers for the same key wait for it rather than calling
+// compute themselves. If compute returns an error then nothing is added and the error is returned
+func (this *timedCache) GetOrAdd(key string, compute func() (CacheItem, error)) (CacheItem, error) {
+	return getOrAdd(this, &this.flights, key, compute)
+}
+
+// GetOrAddContext works like GetOrAdd, but gives up if ctx is done before it has the item
+func (this *timedCache) GetOrAddContext(ctx context.Context, key string, compute func(ctx context.Context) (CacheItem, error)) (CacheItem, error) {
+	return getOrAddContext(ctx, this, &this.flights, key, compute)
+}
+
+// GetStaleWhileRevalidate retrieves an item, serving it stale and refreshing it in the background once its older than
+// ttl. The item's own expiry still applies, once its expired theres nothing stale to serve
+func (this *timedCache) GetStaleWhileRevalidate(key string, ttl, staleWindow time.Duration, refresh func() (CacheItem, error)) (CacheItem, bool) {
+	return getStaleWhileRevalidate(this, &this.flights, key, ttl, staleWindow, refresh)
+}
+
+// UpdateSize re-reads the Size of an item that's changed since it was added, and updates the size of the cache to match
+//
+// There's no size limit, it only fails if the item's size is now negative in which case its removed. Nothing happens
+// if the item isn't present
+func (this *timedCache) UpdateSize(key string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if entry, present := this.entries[key]; present {
+		size := entry.cacheItem.Size()
+		if size < 0 {
+			this.remove(entry)
+			return ErrNegativeSize
+		}
+		this.curSize += size - entry.size
+		entry.size = size
+	}
+	return nil
+}
+
+// Resize does nothing, there's no size limit
+func (this *timedCache) Resize(newMax int) {
+}
+
+// RemoveFunc removes every item that pred returns true for
+//
+// pred is called with the cache locked so it mustn't call back into the cache
+func (this *timedCache) RemoveFunc(pred func(key string, item CacheItem) bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	for _, entry := range this.entries {
+		if pred(entry.key, entry.cacheItem) {
+			this.remove(entry)
+		}
+	}
+}
+
+// ForEach calls fn for every unexpired item in the cache, in no particular order, until fn returns false
+//
+// fn is called with the cache locked so it mustn't call back into the cache
+func (this *timedCache) ForEach(fn func(key string, item CacheItem) bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	for _, entry := range this.entries {
+		if this.expired(entry) {
+			continue
+		}
+		if !fn(entry.key, entry.cacheItem) {
+			return
+		}
+	}
+}
+
+// Touch restarts an item's ttl, returning false if the key is missing or the item has expired
+func (this *timedCache) Touch(key string) bool {
+	// Lock method so hash & heap can be accessed safely from multiple go-routines. Unlock when func returns
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	entry := this.live(key)
+	if entry == nil {
+		return false
+	}
+	entry.added = this.now()
+	heap.Fix(&this.expiries, entry.index)
+	return true
+}
\ No newline at end of file
@@ -0,0 +1,165 @@
+package memcache
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTimedCache(t *testing.T) {
+	cache := CreateTimedCache(TestTTL)
+
+	// Check cache is created correctly
+	if cache == nil {
+		t.Error("Cache is nil")
+	}
+
+	// Present until the ttl passes
+	item := &DummyCacheItem{DummySize: 10}
+	cache.Add("a", item)
+	if got, present := cache.Get("a"); !present || got != item {
+		t.Error("Expected a to be present, got", got, present)
+	}
+	time.Sleep(TestTTL + 10 * time.Millisecond)
+	if cache.Contains("a") {
+		t.Error("a should have expired")
+	}
+	if _, present := cache.Get("a"); present {
+		t.Error("a should have expired")
+	}
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Error("Expected expired item to be removed, got", cache.Len(), "items of size", cache.Size())
+	}
+	assertStats(t, cache.Stats(), Stats{Hits: 1, Misses: 1, Expirations: 1, Adds: 1})
+}
+
+func TestTimedCacheUnbounded(t *testing.T) {
+	cache := CreateTimedCache(time.Minute)
+	if cache.Cap() != math.MaxInt {
+		t.Error("Expected no size limit, got", cache.Cap())
+	}
+
+	// Nothing should ever be evicted to make room, however big the items are
+	for i := 0; i < 1000; i++ {
+		if err := cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: math.MaxInt / 2000}); err != nil {
+			t.Error("Unexpected error adding:", err)
+		}
+	}
+	cache.Resize(10)
+	if cache.Len() != 1000 || cache.Stats().Evictions != 0 {
+		t.Error("Expected all 1000 items to be kept, got", cache.Len(), "and", cache.Stats().Evictions, "evictions")
+	}
+}
+
+func TestTimedCacheReAdd(t *testing.T) {
+	cache := CreateTimedCache(TestTTL)
+
+	// Adding again and touching both restart the ttl
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+	time.Sleep(TestTTL / 2)
+	replacement := &DummyCacheItem{DummySize: 20}
+	if prev, existed, _ := cache.AddReturning("a", replacement); !existed || prev == nil {
+		t.Error("Expected a to be replaced, got", prev, existed)
+	}
+	cache.Touch("b")
+	time.Sleep(TestTTL * 3 / 4)
+
+	if got, present := cache.Get("a"); !present || got != replacement {
+		t.Error("a should be present as it was added again, got", got, present)
+	}
+	if !cache.Contains("b") || cache.Contains("c") {
+		t.Error("Expected b to survive after being touched and c to expire, got", cache.Keys())
+	}
+	if cache.Touch("c") {
+		t.Error("Touch shouldn't bring back an expired item")
+	}
+	if cache.Size() != 30 {
+		t.Error("Expected size of 30, got", cache.Size())
+	}
+}
+
+func TestTimedCacheWithJanitor(t *testing.T) {
+	cache := CreateTimedCacheWithJanitor(TestTTL, TestTTL / 4).(*timedCache)
+	defer cache.Close()
+
+	// Expire at different times, janitor should remove them without them being accessed
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	time.Sleep(TestTTL / 2)
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	time.Sleep(TestTTL * 3 / 4)
+
+	if cache.Len() != 1 || cache.Size() != 10 {
+		t.Error("Expected a to have been removed by the janitor, got", cache.Keys())
+	}
+	time.Sleep(TestTTL / 2)
+	cache.mutex.Lock()
+	remaining := len(cache.expiries)
+	cache.mutex.Unlock()
+	if cache.Len() != 0 || remaining != 0 {
+		t.Error("Expected the janitor to have removed everything, got", cache.Keys())
+	}
+}
+
+func TestTimedCacheRemove(t *testing.T) {
+	cache := CreateTimedCache(time.Minute)
+	for i := 0; i < 10; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Removing from the middle of the heap shouldn't break it
+	cache.Remove("5")
+	cache.RemoveFunc(func(key string, item CacheItem) bool {
+		return key == "0" || key == "9"
+	})
+	if cache.Len() != 7 || cache.Size() != 70 {
+		t.Error("Expected 7 items of size 70, got", cache.Len(), "items of size", cache.Size())
+	}
+	expiries := cache.(*timedCache).expiries
+	for i, entry := range expiries {
+		if entry.index != i {
+			t.Error("Expected heap index", i, "got", entry.index)
+		}
+	}
+
+	cache.Clear()
+	if cache.Len() != 0 || cache.Size() != 0 || len(cache.Keys()) != 0 {
+		t.Error("Expected an empty cache, got", cache.Keys())
+	}
+}
+
+func TestTimedCacheAddWithTTL(t *testing.T) {
+	cache := CreateTimedCacheWithJanitor(TestTTL, TestTTL / 4).(*timedCache)
+	defer cache.Close()
+
+	// Added in the order they expire last, so the heap has to put them the other way round
+	cache.AddWithTTL("forever", &DummyCacheItem{DummySize: 10}, 0)
+	cache.AddWithTTL("long", &DummyCacheItem{DummySize: 10}, TestTTL * 3)
+	cache.Add("default", &DummyCacheItem{DummySize: 10})
+	cache.AddWithTTL("short", &DummyCacheItem{DummySize: 10}, TestTTL / 2)
+
+	// Short should have expired on its own before the default
+	time.Sleep(TestTTL * 3 / 4)
+	if _, present := cache.Get("short"); present {
+		t.Error("short should have expired")
+	}
+	if _, present := cache.Get("default"); !present {
+		t.Error("default should still be present")
+	}
+
+	// Janitor should remove the default next, then long
+	time.Sleep(TestTTL)
+	if cache.Len() != 2 || cache.Contains("default") {
+		t.Error("Expected default to have been removed by the janitor, got", cache.Keys())
+	}
+	time.Sleep(TestTTL * 2)
+	if cache.Len() != 1 || !cache.Contains("forever") {
+		t.Error("Expected only forever to be left, got", cache.Keys())
+	}
+}
+
+func TestTimedCacheSetDefaultTTL(t *testing.T) {
+	testSetDefaultTTL(t, CreateTimedCache(TestTTL * 10).(ExpiringCache))
+}
\ No newline at end of file
@@ -0,0 +1,269 @@
+package memcache
+
+import (
+	"hash/fnv"
+)
+
+const (
+	// sketchDepth is the number of rows in a countMinSketch, each key has a counter in every row
+	sketchDepth = 4
+
+	// sketchMinWidth and sketchMaxWidth bound the number of counters in each row of a countMinSketch
+	sketchMinWidth = 64
+	sketchMaxWidth = 1 << 16
+
+	// sketchMaxCount is the most a countMinSketch counter can reach, frequencies above it all look the same
+	sketchMaxCount = 15
+
+	// sketchResetMultiplier is how many increments per counter in a row a countMinSketch takes before it halves them all
+	sketchResetMultiplier = 10
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateWindowTinyLFUCache creates and returns a 'Window TinyLFU' implementation of Cache
+//
+// New items go into a small LRU window (1% of max size, at least 1), the rest of the cache is a main LRU queue. When an item is
+// pushed out of the window while the main queue is full, it only gets into the main queue if its been used more often
+// recently than the item it would evict from there. Otherwise its the one that goes. How often keys have been used is
+// estimated by a count-min sketch, which has a fixed number of counters and halves them all every so often so old
+// popularity fades. Gives much better hit rates than LRU when a few keys are far more popular than the rest
+func CreateWindowTinyLFUCache(maxsize int) (Cache) {
+	policy := &windowTinyLFUPolicy { sketch: newCountMinSketch(maxsize) }
+	cache := createPolicyCache(maxsize, policy)
+	policy.cache = cache
+	return cache
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: countMinSketch (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// countMinSketch estimates how many times each key has been seen, using a fixed amount of memory however many keys
+//
+// Each key has a counter in every row, picked by hashing the key. Keys share counters so a count can only be too high,
+// the estimate is the lowest of the key's counters. Once there have been enough increments every counter is halved, so
+// the counts reflect recent use
+type countMinSketch struct {
+
+	// rows holds the counters, sketchDepth rows of width counters
+	rows [sketchDepth][]uint8
+
+	// mask picks a counter in a row from a hash, width is always a power of 2
+	mask uint64
+
+	// increments is the number of increments since the counters were last halved
+	increments int
+
+	// resetAt is the number of increments after which the counters are halved
+	resetAt int
+}
+
+// newCountMinSketch creates a countMinSketch sized for a cache with the max size passed in, bounded by sketchMinWidth
+// and sketchMaxWidth
+func newCountMinSketch(maxsize int) (*countMinSketch) {
+	width := sketchMinWidth
+	for width < maxsize && width < sketchMaxWidth {
+		width <<= 1
+	}
+	sketch := &countMinSketch { mask: uint64(width - 1), resetAt: width * sketchResetMultiplier }
+	for i := range sketch.rows {
+		sketch.rows[i] = make([]uint8, width)
+	}
+	return sketch
+}
+
+// index returns the counter the key uses in the row passed in
+func (this *countMinSketch) index(hash uint64, row int) uint64 {
+	// Double hashing, each row gets a different mix of the two halves of the hash
+	return (hash + uint64(row) * ((hash >> 32) | 1)) & this.mask
+}
+
+// increment counts another use of the key, halving all the counters if its time to
+func (this *countMinSketch) increment(key string) {
+	hash := sketchHash(key)
+	for i := range this.rows {
+		if counter := &this.rows[i][this.index(hash, i)]; *counter < sketchMaxCount {
+			*counter++
+		}
+	}
+
+	this.increments++
+	if this.increments >= this.resetAt {
+		this.halve()
+	}
+}
+
+// estimate returns how many times the key has been seen recently, it may be more but never less
+func (this *countMinSketch) estimate(key string) uint8 {
+	hash := sketchHash(key)
+	estimate := uint8(sketchMaxCount)
+	for i := range this.rows {
+		estimate = min(estimate, this.rows[i][this.index(hash, i)])
+	}
+	return estimate
+}
+
+// halve halves every counter, so uses from a while ago count for less than recent ones
+func (this *countMinSketch) halve() {
+	for i := range this.rows {
+		for j := range this.rows[i] {
+			this.rows[i][j] >>= 1
+		}
+	}
+	this.increments /= 2
+}
+
+// sketchHash returns the hash of a key used to pick its counters
+func sketchHash(key string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return hash.Sum64()
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: windowTinyLFUPolicy (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// windowTinyLFUPolicy is the evictionPolicy for the Window TinyLFU cache
+//
+// window and main are both LRU queues. The sketch is told about every add and access, including keys that aren't in the
+// cache any more, so a key that keeps coming back builds up a count even if its evicted each time
+type windowTinyLFUPolicy struct {
+
+	// cache is the cache the policy belongs to, the queue sizes are worked out from its max size
+	cache *policyCache
+
+	// sketch estimates how often keys have been used
+	sketch *countMinSketch
+
+	// window holds new entries, most recently used at the head
+	window entryList
+
+	// main holds entries let in from the window, most recently used at the head
+	main entryList
+}
+
+// windowSize returns the size of the window, once its full entries are moved to main or evicted to make room
+//
+// Its at least 1, or for caches under 100 main would get the whole cache and new entries would always evict its tail
+// without having to beat it
+func (this *windowTinyLFUPolicy) windowSize() int {
+	return max(this.cache.maxSize / 100, 1)
+}
+
+// mainSize returns the size main can grow to before entries from the window have to beat its tail to get in
+func (this *windowTinyLFUPolicy) mainSize() int {
+	return this.cache.maxSize - this.windowSize()
+}
+
+// promote moves an entry from the tail of the window to the head of main
+func (this *windowTinyLFUPolicy) promote(entry *policyEntry) {
+	this.window.remove(entry)
+	this.main.pushFront(entry)
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// evictionPolicy Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// admitting counts the add in the sketch, whether or not the key's been seen before
+func (this *windowTinyLFUPolicy) admitting(key string) {
+	this.sketch.increment(key)
+}
+
+// added puts the entry at the head of the window
+func (this *windowTinyLFUPolicy) added(entry *policyEntry) {
+	this.window.pushFront(entry)
+}
+
+// accessed counts the access in the sketch and moves the entry to the head of whichever queue its in
+func (this *windowTinyLFUPolicy) accessed(entry *policyEntry) {
+	this.sketch.increment(entry.key)
+	list := entry.list
+	list.remove(entry)
+	list.pushFront(entry)
+}
+
+// removed takes the entry out of its queue, the sketch keeps its count
+func (this *windowTinyLFUPolicy) removed(entry *policyEntry, evicted bool) {
+	entry.list.remove(entry)
+}
+
+// victim picks between the entry leaving the window and the tail of main, if the window's full
+//
+// The cache is about to add to the window, so once its full the tail has to leave it. If main has room then entries
+// leaving the window just move into it. Otherwise the one the sketch thinks has been used the most stays, the window's
+// entry only wins if its estimate is higher so a tie keeps what's in main. If the window isn't full then main's tail is
+// evicted
+func (this *windowTinyLFUPolicy) victim() *policyEntry {
+	for this.window.tail != nil && this.window.size >= this.windowSize() {
+		candidate := this.window.tail
+		if this.main.size + candidate.size <= this.mainSize() {
+			this.promote(candidate)
+			continue
+		}
+
+		victim := this.main.tail
+		if victim == nil || this.sketch.estimate(candidate.key) <= this.sketch.estimate(victim.key) {
+			return candidate
+		}
+		this.promote(candidate)
+		return victim
+	}
+
+	if this.main.tail != nil {
+		return this.main.tail
+	}
+	return this.window.tail
+}
+
+// peekVictim returns the entry victim would return, working out which entries victim would move from the window into
+// main without moving them
+func (this *windowTinyLFUPolicy) peekVictim() *policyEntry {
+	windowSize, mainSize := this.window.size, this.main.size
+
+	// Promoted entries go to the head of main, so if main's empty the first promoted becomes its tail
+	mainTail := this.main.tail
+	candidate := this.window.tail
+	for candidate != nil && windowSize >= this.windowSize() {
+		if mainSize + candidate.size <= this.mainSize() {
+			windowSize -= candidate.size
+			mainSize += candidate.size
+			if mainTail == nil {
+				mainTail = candidate
+			}
+			candidate = candidate.prev
+			continue
+		}
+
+		if mainTail == nil || this.sketch.estimate(candidate.key) <= this.sketch.estimate(mainTail.key) {
+			return candidate
+		}
+		return mainTail
+	}
+
+	if mainTail != nil {
+		return mainTail
+	}
+	return candidate
+}
+
+// each iterates over the window then main, most recently used first within each
+func (this *windowTinyLFUPolicy) each(fn func(entry *policyEntry) bool) {
+	for _, list := range []*entryList { &this.window, &this.main } {
+		for entry := list.head; entry != nil; entry = entry.next {
+			if !fn(entry) {
+				return
+			}
+		}
+	}
+}
+
+// reset drops all entries, the sketch keeps its counts
+func (this *windowTinyLFUPolicy) reset() {
+	this.window = entryList { }
+	this.main = entryList { }
+}
\ No newline at end of file
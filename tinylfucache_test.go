@@ -0,0 +1,129 @@
+package memcache
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestWindowTinyLFUCache(t *testing.T) {
+	cache := CreateWindowTinyLFUCache(MaxSize)
+
+	// Check cache is created correctly
+	if cache == nil {
+		t.Error("Cache is nil")
+	}
+
+	// Way more than will fit, should stay at max size
+	for i := 0; i < 50; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Len() != 10 || cache.Size() != MaxSize {
+		t.Error("Expected 10 items of size", MaxSize, "got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestWindowTinyLFUCacheAdmission(t *testing.T) {
+	cache := CreateWindowTinyLFUCache(MaxSize)
+	for i := 0; i < 10; i++ {
+		key := strconv.Itoa(i)
+		cache.Add(key, &DummyCacheItem{DummySize: 10})
+		cache.Get(key)
+	}
+
+	// One-off keys haven't been used as much as anything in the cache, so they shouldn't push any of it out
+	for i := 0; i < 50; i++ {
+		cache.Add("new" + strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	for i := 0; i < 9; i++ {
+		if !cache.Contains(strconv.Itoa(i)) {
+			t.Error(i, "should have been kept over the one-off keys, got", cache.Keys())
+		}
+	}
+
+	// A key that keeps coming back builds up a count and gets in
+	for i := 0; i < 5; i++ {
+		cache.Add("popular", &DummyCacheItem{DummySize: 10})
+		cache.Add("other" + strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if !cache.Contains("popular") {
+		t.Error("popular should have been admitted, got", cache.Keys())
+	}
+}
+
+func TestWindowTinyLFUCacheSmall(t *testing.T) {
+	// Under 100 the window is still there, so one-off keys have to get past the sketch like in a bigger cache
+	cache := CreateWindowTinyLFUCache(10)
+	for i := 0; i < 10; i++ {
+		key := strconv.Itoa(i)
+		cache.Add(key, &DummyCacheItem{DummySize: 1})
+		cache.Get(key)
+	}
+	for i := 0; i < 50; i++ {
+		cache.Add("new" + strconv.Itoa(i), &DummyCacheItem{DummySize: 1})
+	}
+	for i := 0; i < 9; i++ {
+		if !cache.Contains(strconv.Itoa(i)) {
+			t.Error(i, "should have been kept over the one-off keys, got", cache.Keys())
+		}
+	}
+	if cache.Len() != 10 || cache.Size() != 10 {
+		t.Error("Expected 10 items of size 10, got", cache.Len(), "items of size", cache.Size())
+	}
+}
+
+func TestWindowTinyLFUCacheHitRate(t *testing.T) {
+	tinyLFU := CreateWindowTinyLFUCache(MaxSize * 10)
+	lru := CreateLRUCache(MaxSize * 10)
+
+	// Zipfian keys, a few are far more popular than the rest
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, 10000)
+	for i := 0; i < 50000; i++ {
+		key := strconv.FormatUint(zipf.Uint64(), 10)
+		for _, cache := range []Cache { tinyLFU, lru } {
+			if _, present := cache.Get(key); !present {
+				cache.Add(key, &DummyCacheItem{DummySize: 10})
+			}
+		}
+	}
+
+	tinyLFUStats, lruStats := tinyLFU.Stats(), lru.Stats()
+	if tinyLFUStats.Hits <= lruStats.Hits {
+		t.Error("Expected Window TinyLFU to get more hits than LRU, got", tinyLFUStats.Hits, "vs", lruStats.Hits)
+	}
+	t.Log("Window TinyLFU hits", tinyLFUStats.Hits, "LRU hits", lruStats.Hits)
+}
+
+func TestCountMinSketch(t *testing.T) {
+	sketch := newCountMinSketch(10)
+	for i := 0; i < 10; i++ {
+		sketch.increment("a")
+	}
+	sketch.increment("b")
+	if sketch.estimate("a") < 10 || sketch.estimate("b") < 1 {
+		t.Error("Estimates should never be too low, got", sketch.estimate("a"), sketch.estimate("b"))
+	}
+
+	// Counters stop at the max, and are halved once there have been enough increments
+	for i := 0; i < 10; i++ {
+		sketch.increment("a")
+	}
+	if sketch.estimate("a") != sketchMaxCount {
+		t.Error("Expected a to be capped at", sketchMaxCount, "got", sketch.estimate("a"))
+	}
+	for i := 0; sketch.increments > 0 && i < sketch.resetAt; i++ {
+		sketch.increment("c" + strconv.Itoa(i))
+	}
+	if sketch.estimate("a") > sketchMaxCount / 2 + 1 {
+		t.Error("Expected a's count to have decayed, got", sketch.estimate("a"))
+	}
+
+	// The number of counters is bounded however big the cache
+	if len(sketch.rows[0]) != sketchMinWidth {
+		t.Error("Expected", sketchMinWidth, "counters per row, got", len(sketch.rows[0]))
+	}
+	if big := newCountMinSketch(math.MaxInt); len(big.rows[0]) != sketchMaxWidth {
+		t.Error("Expected", sketchMaxWidth, "counters per row, got", len(big.rows[0]))
+	}
+}
\ No newline at end of file
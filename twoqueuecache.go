@@ -0,0 +1,143 @@
+package memcache
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateTwoQueueCache creates and returns a '2Q' implementation of Cache
+//
+// New items go into a small FIFO queue (a quarter of max size). Items pushed out of it have their keys remembered, and
+// if one is added again while its still remembered its gone into the main LRU queue. A one-off scan only churns the
+// FIFO queue, leaving the items in the main queue in place
+func CreateTwoQueueCache(maxsize int) (Cache) {
+	policy := &twoQueuePolicy { ghosts: make(map[string]*policyEntry) }
+	cache := createPolicyCache(maxsize, policy)
+	policy.cache = cache
+	return cache
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: twoQueuePolicy (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// twoQueuePolicy is the evictionPolicy for the 2Q cache
+//
+// a1in is the FIFO queue for new entries and am is the LRU queue for entries seen again. a1out is the 'ghost' list, the
+// keys and sizes of entries recently evicted from a1in. Ghosts don't hold onto the item so only cost the key
+type twoQueuePolicy struct {
+
+	// cache is the cache the policy belongs to, the queue sizes are worked out from its max size
+	cache *policyCache
+
+	// a1in holds new entries, in the order they were added
+	a1in entryList
+
+	// am holds entries that have been seen again, most recently used at the head
+	am entryList
+
+	// a1out holds the ghosts of entries evicted from a1in, most recently evicted at the head
+	a1out entryList
+
+	// ghosts is the map of key(string) to ghost entry, for keys in a1out
+	ghosts map[string]*policyEntry
+
+	// ghostHit is set by admitting when the key being added was a ghost, so it goes straight into am
+	ghostHit bool
+}
+
+// inSize returns the size a1in can grow to before its entries are evicted ahead of am's
+func (this *twoQueuePolicy) inSize() int {
+	return this.cache.maxSize / 4
+}
+
+// outSize returns the total size of the keys a1out remembers
+func (this *twoQueuePolicy) outSize() int {
+	return this.cache.maxSize / 2
+}
+
+// outLen returns the number of keys a1out remembers, as many as there are entries in the cache (at least one)
+func (this *twoQueuePolicy) outLen() int {
+	return max(this.a1in.len + this.am.len, 1)
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// evictionPolicy Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// admitting forgets the ghost if the key was recently evicted from a1in, and remembers to put it in am
+func (this *twoQueuePolicy) admitting(key string) {
+	if ghost, present := this.ghosts[key]; present {
+		this.a1out.remove(ghost)
+		delete(this.ghosts, key)
+		this.ghostHit = true
+	}
+}
+
+// added puts the entry at the head of am if the key has been seen before, otherwise at the head of a1in
+func (this *twoQueuePolicy) added(entry *policyEntry) {
+	if entry.freq > 1 || this.ghostHit {
+		this.am.pushFront(entry)
+	} else {
+		this.a1in.pushFront(entry)
+	}
+	this.ghostHit = false
+}
+
+// accessed moves the entry to the head of am if its there. Entries in a1in stay where they are, its a FIFO queue
+func (this *twoQueuePolicy) accessed(entry *policyEntry) {
+	if entry.list == &this.am {
+		this.am.remove(entry)
+		this.am.pushFront(entry)
+	}
+}
+
+// removed takes the entry out of its queue. If it was evicted from a1in then its remembered as a ghost in a1out, either
+// way there's one less entry so a1out is trimmed
+func (this *twoQueuePolicy) removed(entry *policyEntry, evicted bool) {
+	from := entry.list
+	from.remove(entry)
+	if evicted && from == &this.a1in {
+		ghost := &policyEntry { key: entry.key, size: entry.size }
+		this.a1out.pushFront(ghost)
+		this.ghosts[ghost.key] = ghost
+	}
+
+	// Forget the oldest ghosts so the list stays bounded, by count too as zero size ghosts take up no size
+	for (this.a1out.size > this.outSize() || this.a1out.len > this.outLen()) && this.a1out.tail != nil {
+		delete(this.ghosts, this.a1out.tail.key)
+		this.a1out.remove(this.a1out.tail)
+	}
+}
+
+// victim returns the oldest entry in a1in if its over its size, otherwise the least recently used entry in am
+func (this *twoQueuePolicy) victim() *policyEntry {
+	if this.a1in.tail != nil && (this.a1in.size > this.inSize() || this.am.tail == nil) {
+		return this.a1in.tail
+	}
+	return this.am.tail
+}
+
+// peekVictim returns victim, which only reads the queues so calling it doesn't change anything
+func (this *twoQueuePolicy) peekVictim() *policyEntry {
+	return this.victim()
+}
+
+// each iterates over am then a1in, most recent first within each
+func (this *twoQueuePolicy) each(fn func(entry *policyEntry) bool) {
+	for _, list := range []*entryList { &this.am, &this.a1in } {
+		for entry := list.head; entry != nil; entry = entry.next {
+			if !fn(entry) {
+				return
+			}
+		}
+	}
+}
+
+// reset drops all entries and ghosts
+func (this *twoQueuePolicy) reset() {
+	this.a1in = entryList { }
+	this.am = entryList { }
+	this.a1out = entryList { }
+	this.ghosts = make(map[string]*policyEntry)
+	this.ghostHit = false
+}
\ No newline at end of file
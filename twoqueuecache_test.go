@@ -0,0 +1,118 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestTwoQueueCache(t *testing.T) {
+	cache := CreateTwoQueueCache(MaxSize)
+
+	// Check cache is created correctly
+	if cache == nil {
+		t.Error("Cache is nil")
+	}
+
+	// Get the hot key into the main queue, it has to be pushed out of the FIFO queue and come back
+	cache.Add("hot", &DummyCacheItem{DummySize: 10})
+	for i := 0; i < 10; i++ {
+		cache.Add("warmup" + strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Contains("hot") {
+		t.Error("hot should have been evicted from the FIFO queue")
+	}
+	cache.Add("hot", &DummyCacheItem{DummySize: 10})
+
+	// Scan through way more unique keys than will fit
+	for i := 0; i < 100; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Hot key should have survived, the scan only churns the FIFO queue
+	if _, present := cache.Get("hot"); !present {
+		t.Error("hot should have survived the scan")
+	}
+	if cache.Contains("90") || !cache.Contains("91") || !cache.Contains("99") {
+		t.Error("Expected the 9 most recent scanned keys, got", cache.Keys())
+	}
+	if cache.Size() != MaxSize {
+		t.Error("Expected size of", MaxSize, "got", cache.Size())
+	}
+}
+
+func TestTwoQueueCacheFIFO(t *testing.T) {
+	cache := CreateTwoQueueCache(30)
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	cache.Add("c", &DummyCacheItem{DummySize: 10})
+
+	// Getting an item in the FIFO queue doesn't move it
+	cache.Get("a")
+	cache.Add("d", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"d", "c", "b"})
+}
+
+func TestTwoQueueCachePromoted(t *testing.T) {
+	cache := CreateTwoQueueCache(40)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		cache.Add(key, &DummyCacheItem{DummySize: 10})
+	}
+
+	// "a" and "b" were evicted and are remembered, adding them again should put them in the main queue
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Add("b", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"b", "a", "f", "e"})
+
+	// The main queue is LRU so Get moves to the front, and new keys only push out others in the FIFO queue
+	cache.Get("a")
+	cache.Add("g", &DummyCacheItem{DummySize: 10})
+	cache.Add("h", &DummyCacheItem{DummySize: 10})
+	cache.Add("i", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, cache.Keys(), []string{"a", "b", "i", "h"})
+}
+
+func TestTwoQueueCacheGhostsBounded(t *testing.T) {
+	cache := CreateTwoQueueCache(MaxSize)
+	for i := 0; i < 1000; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+
+	// Only half of max size worth of keys should be remembered
+	policy := cache.(*policyCache).policy.(*twoQueuePolicy)
+	if len(policy.ghosts) != 5 || policy.a1out.size != 50 {
+		t.Error("Expected 5 ghosts of size 50, got", len(policy.ghosts), "of size", policy.a1out.size)
+	}
+
+	// The oldest have been forgotten, so adding them again just goes in the FIFO queue
+	cache.Add("0", &DummyCacheItem{DummySize: 10})
+	if policy.a1in.head.key != "0" {
+		t.Error("Expected 0 to be treated as new")
+	}
+	cache.Add("994", &DummyCacheItem{DummySize: 10})
+	if policy.am.head.key != "994" {
+		t.Error("Expected 994 to be remembered and go in the main queue")
+	}
+}
+
+func TestTwoQueueCacheZeroSizeGhostsBounded(t *testing.T) {
+	cache := CreateTwoQueueCache(10)
+	policy := cache.(*policyCache).policy.(*twoQueuePolicy)
+
+	// Zero size items are evicted along with the sized ones, their ghosts take up no size so only a count keeps them down
+	for i := 0; i < 100; i++ {
+		for j := 0; j <= 100; j++ {
+			cache.Add(strconv.Itoa(i) + "-" + strconv.Itoa(j), &DummyCacheItem{DummySize: j / 100})
+		}
+	}
+	if len(policy.ghosts) > cache.Len() {
+		t.Error("Expected no more ghosts than the", cache.Len(), "items in the cache, got", len(policy.ghosts))
+	}
+
+	// Removing the items leaves nothing for the ghosts to be ghosts of
+	for _, key := range cache.Keys() {
+		cache.Remove(key)
+	}
+	if len(policy.ghosts) > 1 || policy.a1out.len != len(policy.ghosts) {
+		t.Error("Expected at most 1 ghost, got", len(policy.ghosts))
+	}
+}
\ No newline at end of file
@@ -0,0 +1,48 @@
+package memcache
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// CreateTypedCache creates and returns a TypedCache that stores its items in the underlying Cache passed in
+func CreateTypedCache[V CacheItem](underlying Cache) (*TypedCache[V]) {
+	return &TypedCache[V] { underlying: underlying }
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: TypedCache
+// ------------------------------------------------------------------------------------------------------------------------
+
+// TypedCache wraps a Cache so items go in and come out as V, rather than having to type assert on every Get
+//
+// The underlying Cache can still be shared with other code. If it holds an item under the key that isn't a V then Get
+// treats it as missing rather than panicking
+type TypedCache[V CacheItem] struct {
+
+	// underlying is the Cache the items are stored in
+	underlying Cache
+}
+
+// Add adds an item to the underlying cache, it can be retrieved using Get and passing in the same key
+func (this *TypedCache[V]) Add(key string, val V) error {
+	return this.underlying.Add(key, val)
+}
+
+// Get retrieves an item from the underlying cache if its present and a V
+//
+// If item is present then the item, true is returned. Otherwise, the zero V, false
+func (this *TypedCache[V]) Get(key string) (V, bool) {
+	if item, present := this.underlying.Get(key); present {
+		if val, isV := item.(V); isV {
+			return val, true
+		}
+	}
+
+	var zero V
+	return zero, false
+}
+
+// Remove removes an item from the underlying cache
+func (this *TypedCache[V]) Remove(key string) {
+	this.underlying.Remove(key)
+}
@@ -0,0 +1,46 @@
+package memcache
+
+import (
+	"testing"
+)
+
+func TestTypedCache(t *testing.T) {
+	underlying := CreateLRUCache(MaxSize)
+	dummies := CreateTypedCache[*DummyCacheItem](underlying)
+	others := CreateTypedCache[*OtherCacheItem](underlying)
+
+	dummy := &DummyCacheItem{DummySize: 10}
+	other := &OtherCacheItem{Name: "other"}
+	dummies.Add("dummy", dummy)
+	others.Add("other", other)
+
+	// Each should come back as its own concrete type
+	if item, present := dummies.Get("dummy"); !present || item != dummy {
+		t.Error("Expected to get back the dummy item, got", item, present)
+	}
+	if item, present := others.Get("other"); !present || item.Name != "other" {
+		t.Error("Expected to get back the other item, got", item, present)
+	}
+
+	// Getting an item of the wrong type should be a miss rather than a panic
+	if item, present := dummies.Get("other"); present || item != nil {
+		t.Error("Expected a miss getting the other item as a dummy, got", item, present)
+	}
+	if _, present := dummies.Get("missing"); present {
+		t.Error("missing shouldn't be present")
+	}
+
+	// Remove goes through to the underlying cache
+	others.Remove("other")
+	if underlying.Contains("other") {
+		t.Error("other should have been removed")
+	}
+}
+
+type OtherCacheItem struct {
+	Name string
+}
+
+func (this *OtherCacheItem) Size() int {
+	return len(this.Name)
+}
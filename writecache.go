@@ -0,0 +1,303 @@
+package memcache
+
+import (
+	"sync"
+)
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Creation functions
+// ------------------------------------------------------------------------------------------------------------------------
+
+// NewWriteThroughCache creates and returns a Cache that writes items to a backing store as they're added
+//
+// Add, AddReturning and AddWithCost call writer before adding the item to underlying. If writer returns an error then
+// the item isn't added and the error is returned. Items added by GetOrAdd aren't written, as they're expected to have
+// come from the store
+func NewWriteThroughCache(underlying Cache, writer func(key string, item CacheItem) error) (Cache) {
+	return &writeThroughCache { Cache: underlying, writer: writer }
+}
+
+// NewWriteBehindCache creates and returns a WriteBehindCache that writes items to a backing store in the background
+//
+// Items are added to underlying straight away and queued up for writer once its taken them, so items it rejects are never
+// written. writer is called on a separate goroutine so Add doesn't wait for the store. Everything queued since the
+// goroutine last woke up is written in one go, in the order it was added. If writer returns an error then onError (if
+// its not nil) is called with the item and the error. Close must be called to write anything still queued and stop the
+// goroutine
+func NewWriteBehindCache(underlying Cache, writer func(key string, item CacheItem) error, 
+	onError func(key string, item CacheItem, err error)) (WriteBehindCache) {
+
+	cache := &writeBehindCache { Cache: underlying, writer: writer, onError: onError, wake: make(chan struct{}, 1), 
+		flushes: make(chan chan struct{}), stop: make(chan struct{}), done: make(chan struct{}) }
+	go cache.run()
+	return cache
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Interface: WriteBehindCache
+// ------------------------------------------------------------------------------------------------------------------------
+
+// WriteBehindCache is a Cache that writes items to a backing store in the background
+type WriteBehindCache interface {
+	Cache
+
+	// Flush waits until every item added before it was called has been passed to the writer
+	Flush()
+
+	// Close writes anything still queued and stops the background goroutine. Items can't be added afterwards, Add
+	// returns ErrCacheClosed. Its safe to call more than once
+	Close() error
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: writeThroughCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// writeThroughCache wraps a Cache and writes items to the store before adding them
+type writeThroughCache struct {
+	Cache
+
+	// writer writes an item to the backing store
+	writer func(key string, item CacheItem) error
+}
+
+// Add writes the item to the store, then adds it to the underlying cache if that worked
+func (this *writeThroughCache) Add(key string, val CacheItem) error {
+	if err := this.writer(key, val); err != nil {
+		return err
+	}
+	return this.Cache.Add(key, val)
+}
+
+// AddReturning writes the item to the store, then adds it to the underlying cache like AddReturning if that worked
+func (this *writeThroughCache) AddReturning(key string, val CacheItem) (CacheItem, bool, error) {
+	if err := this.writer(key, val); err != nil {
+		return nil, false, err
+	}
+	return this.Cache.AddReturning(key, val)
+}
+
+// AddWithCost writes the item to the store, then adds it to the underlying cache like AddWithCost if that worked
+func (this *writeThroughCache) AddWithCost(key string, val CacheItem, cost int) error {
+	if err := this.writer(key, val); err != nil {
+		return err
+	}
+	return this.Cache.AddWithCost(key, val, cost)
+}
+
+// AddIfAbsent writes the item to the store and adds it to the underlying cache, but only if the key isn't present
+//
+// The key is checked before the item is written, so if two goroutines add the same missing key at once they can both
+// write to the store even though only one of them ends up in the cache
+func (this *writeThroughCache) AddIfAbsent(key string, val CacheItem) (bool, error) {
+	if this.Cache.Contains(key) {
+		return false, nil
+	}
+	if err := this.writer(key, val); err != nil {
+		return false, err
+	}
+	return this.Cache.AddIfAbsent(key, val)
+}
+
+// Replace writes the item to the store and replaces the one in the underlying cache, but only if the key is present
+//
+// Like AddIfAbsent the key is checked before the item is written, so if its removed at the same time the item can be
+// written without being replaced in the cache
+func (this *writeThroughCache) Replace(key string, val CacheItem) (bool, error) {
+	if !this.Cache.Contains(key) {
+		return false, nil
+	}
+	if err := this.writer(key, val); err != nil {
+		return false, err
+	}
+	return this.Cache.Replace(key, val)
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// Struct: writeBehindCache (not exported)
+// ------------------------------------------------------------------------------------------------------------------------
+
+// writeBehindCache wraps a Cache and queues items up to be written to the store by its run goroutine
+type writeBehindCache struct {
+	Cache
+
+	// writer writes an item to the backing store
+	writer func(key string, item CacheItem) error
+
+	// onError is called when writer fails, nil if nobody wants to know
+	onError func(key string, item CacheItem, err error)
+
+	// pending holds the writes waiting for the goroutine, oldest first
+	pending []pendingWrite
+
+	// closed is set by Close, no more writes are queued after it
+	closed bool
+
+	// mutex is used to synchronize pending / closed as they're accessed by multiple goroutines
+	mutex sync.Mutex
+
+	// wake is signalled when a write is queued, its buffered so queueing never waits
+	wake chan struct{}
+
+	// flushes receives a channel from Flush, its closed once pending has been written
+	flushes chan chan struct{}
+
+	// stop is closed by Close to tell the goroutine to write whats left and return
+	stop chan struct{}
+
+	// done is closed by the goroutine once its returned
+	done chan struct{}
+
+	// closeOnce makes sure stop is only closed once
+	closeOnce sync.Once
+}
+
+// pendingWrite is an item waiting to be written
+type pendingWrite struct {
+
+	// key is the key the item was added under
+	key string
+
+	// item is the item to write
+	item CacheItem
+}
+
+// run writes queued items whenever its woken, until stop is closed
+func (this *writeBehindCache) run() {
+	defer close(this.done)
+
+	for {
+		select {
+		case <-this.wake:
+			this.writePending()
+		case flushed := <-this.flushes:
+			this.writePending()
+			close(flushed)
+		case <-this.stop:
+			this.writePending()
+			return
+		}
+	}
+}
+
+// writePending takes everything thats been queued and writes it, passing any errors to onError
+func (this *writeBehindCache) writePending() {
+	this.mutex.Lock()
+	batch := this.pending
+	this.pending = nil
+	this.mutex.Unlock()
+
+	for _, write := range batch {
+		if err := this.writer(write.key, write.item); err != nil && this.onError != nil {
+			this.onError(write.key, write.item, err)
+		}
+	}
+}
+
+// queue adds an item to pending and wakes the goroutine. Returns an error if the cache has been closed
+func (this *writeBehindCache) queue(key string, val CacheItem) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if this.closed {
+		return ErrCacheClosed
+	}
+	this.pending = append(this.pending, pendingWrite { key: key, item: val })
+
+	// If theres already a wake up waiting then the goroutine will pick this write up too
+	select {
+	case this.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// queueAdded queues an item once the underlying cache has added it, err is the error adding it returned. Nothing is
+// queued if it wasn't added. If the cache has been closed then the item is removed again so the cache doesn't have an
+// item the store never gets
+func (this *writeBehindCache) queueAdded(key string, val CacheItem, err error) error {
+	if err != nil {
+		return err
+	}
+	if err := this.queue(key, val); err != nil {
+		this.Cache.Remove(key)
+		return err
+	}
+	return nil
+}
+
+// ------------------------------------------------------------------------------------------------------------------------
+// WriteBehindCache Implementation
+// ------------------------------------------------------------------------------------------------------------------------
+
+// Add adds the item to the underlying cache, and queues it to be written if it was added
+//
+// Items the underlying cache rejects aren't written. If the cache has been closed then the item is taken back out again
+// and ErrCacheClosed is returned
+func (this *writeBehindCache) Add(key string, val CacheItem) error {
+	return this.queueAdded(key, val, this.Cache.Add(key, val))
+}
+
+// AddReturning adds the item to the underlying cache like AddReturning, and queues it to be written if it was added
+func (this *writeBehindCache) AddReturning(key string, val CacheItem) (CacheItem, bool, error) {
+	prev, existed, err := this.Cache.AddReturning(key, val)
+	if err := this.queueAdded(key, val, err); err != nil {
+		return nil, false, err
+	}
+	return prev, existed, nil
+}
+
+// AddWithCost adds the item to the underlying cache like AddWithCost, and queues it to be written if it was added
+func (this *writeBehindCache) AddWithCost(key string, val CacheItem, cost int) error {
+	return this.queueAdded(key, val, this.Cache.AddWithCost(key, val, cost))
+}
+
+// AddIfAbsent adds the item to the underlying cache if the key isn't present, and queues it to be written if it was
+//
+// If the cache has been closed then the item is taken back out again and ErrCacheClosed is returned
+func (this *writeBehindCache) AddIfAbsent(key string, val CacheItem) (bool, error) {
+	added, err := this.Cache.AddIfAbsent(key, val)
+	if !added || err != nil {
+		return added, err
+	}
+	if err := this.queueAdded(key, val, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Replace replaces the item in the underlying cache if the key is present, and queues it to be written if it was
+// replaced
+func (this *writeBehindCache) Replace(key string, val CacheItem) (bool, error) {
+	replaced, err := this.Cache.Replace(key, val)
+	if !replaced || err != nil {
+		return replaced, err
+	}
+	if err := this.queueAdded(key, val, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Flush waits until every item added before it was called has been passed to the writer. Returns straight away if the
+// cache has been closed, as Close has already written everything
+func (this *writeBehindCache) Flush() {
+	flushed := make(chan struct{})
+	select {
+	case this.flushes <- flushed:
+		<-flushed
+	case <-this.done:
+	}
+}
+
+// Close writes anything still queued and stops the goroutine, its safe to call more than once
+func (this *writeBehindCache) Close() error {
+	this.closeOnce.Do(func() {
+		this.mutex.Lock()
+		this.closed = true
+		this.mutex.Unlock()
+		close(this.stop)
+	})
+	<-this.done
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,164 @@
+package memcache
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingStore is a backing store that remembers the keys written to it, in order
+type recordingStore struct {
+	keys []string
+	mutex sync.Mutex
+}
+
+func (this *recordingStore) write(key string, item CacheItem) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.keys = append(this.keys, key)
+	return nil
+}
+
+func (this *recordingStore) written() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]string{}, this.keys...)
+}
+
+func TestWriteThroughCache(t *testing.T) {
+	store := &recordingStore{}
+	cache := NewWriteThroughCache(CreateLRUCache(MaxSize), store.write)
+
+	// Every add should have been written by the time it returns
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.AddReturning("b", &DummyCacheItem{DummySize: 10})
+	cache.AddWithCost("c", &DummyCacheItem{DummySize: 10}, 10)
+	assertKeys(t, store.written(), []string{"a", "b", "c"})
+	if cache.Len() != 3 {
+		t.Error("Expected 3 items in the cache, got", cache.Keys())
+	}
+
+	// Computed items have come from the store, so aren't written back
+	cache.GetOrAdd("d", func() (CacheItem, error) { return &DummyCacheItem{DummySize: 10}, nil })
+	assertKeys(t, store.written(), []string{"a", "b", "c"})
+
+	// AddIfAbsent only writes if it adds, and Replace if it replaces
+	cache.AddIfAbsent("a", &DummyCacheItem{DummySize: 10})
+	cache.AddIfAbsent("e", &DummyCacheItem{DummySize: 10})
+	cache.Replace("missing", &DummyCacheItem{DummySize: 10})
+	cache.Replace("b", &DummyCacheItem{DummySize: 10})
+	assertKeys(t, store.written(), []string{"a", "b", "c", "e", "b"})
+}
+
+func TestWriteThroughCacheError(t *testing.T) {
+	writeErr := errors.New("write failed")
+	cache := NewWriteThroughCache(CreateLRUCache(MaxSize), func(key string, item CacheItem) error {
+		return writeErr
+	})
+
+	// If the store write fails the item shouldn't be cached
+	if err := cache.Add("a", &DummyCacheItem{DummySize: 10}); err != writeErr {
+		t.Error("Expected the writer's error, got", err)
+	}
+	if cache.Contains("a") {
+		t.Error("a shouldn't have been added")
+	}
+}
+
+func TestWriteBehindCacheFlush(t *testing.T) {
+	store := &recordingStore{}
+	cache := NewWriteBehindCache(CreateLRUCache(MaxSize), store.write, nil)
+	defer cache.Close()
+
+	// Items are in the cache straight away, and in the store once flushed
+	for i := 0; i < 5; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 10})
+	}
+	if cache.Len() != 5 {
+		t.Error("Expected 5 items in the cache, got", cache.Keys())
+	}
+	cache.Flush()
+	assertKeys(t, store.written(), []string{"0", "1", "2", "3", "4"})
+
+	// AddIfAbsent only queues if it adds, and Replace if it replaces
+	cache.AddIfAbsent("0", &DummyCacheItem{DummySize: 10})
+	cache.AddIfAbsent("5", &DummyCacheItem{DummySize: 10})
+	cache.Replace("missing", &DummyCacheItem{DummySize: 10})
+	cache.Replace("1", &DummyCacheItem{DummySize: 10})
+	cache.Flush()
+	assertKeys(t, store.written(), []string{"0", "1", "2", "3", "4", "5", "1"})
+}
+
+func TestWriteBehindCacheRejected(t *testing.T) {
+	store := &recordingStore{}
+	cache := NewWriteBehindCache(CreateLRUCacheWithOverflow(10, OverflowReject), store.write, nil)
+	defer cache.Close()
+
+	// Items the cache won't take shouldn't be written either
+	if err := cache.Add("big", &DummyCacheItem{DummySize: 20}); err != ErrExceedsMaxSize {
+		t.Error("Expected ErrExceedsMaxSize, got", err)
+	}
+	if err := cache.AddWithCost("negative", &DummyCacheItem{DummySize: 1}, -1); err != ErrNegativeSize {
+		t.Error("Expected ErrNegativeSize, got", err)
+	}
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	if _, _, err := cache.AddReturning("b", &DummyCacheItem{DummySize: 5}); err != ErrCacheFull {
+		t.Error("Expected ErrCacheFull, got", err)
+	}
+	cache.Flush()
+	assertKeys(t, store.written(), []string{"a"})
+}
+
+func TestWriteBehindCacheClose(t *testing.T) {
+	store := &recordingStore{}
+	slowWrite := func(key string, item CacheItem) error {
+		time.Sleep(time.Millisecond)
+		return store.write(key, item)
+	}
+	cache := NewWriteBehindCache(CreateLRUCache(MaxSize), slowWrite, nil)
+
+	// Queue up more than can be written before Close, none of them should be lost
+	expected := []string{}
+	for i := 0; i < 20; i++ {
+		cache.Add(strconv.Itoa(i), &DummyCacheItem{DummySize: 1})
+		expected = append(expected, strconv.Itoa(i))
+	}
+	cache.Close()
+	assertKeys(t, store.written(), expected)
+
+	// Closed caches don't take any more and Close / Flush can still be called
+	if err := cache.Add("late", &DummyCacheItem{DummySize: 1}); err != ErrCacheClosed {
+		t.Error("Expected", ErrCacheClosed, "got", err)
+	}
+	if added, err := cache.AddIfAbsent("late", &DummyCacheItem{DummySize: 1}); added || err != ErrCacheClosed {
+		t.Error("Expected", ErrCacheClosed, "got", added, err)
+	}
+	if cache.Contains("late") {
+		t.Error("late shouldn't have been added")
+	}
+	cache.Flush()
+	cache.Close()
+}
+
+func TestWriteBehindCacheError(t *testing.T) {
+	writeErr := errors.New("write failed")
+	var failed []string
+	cache := NewWriteBehindCache(CreateLRUCache(MaxSize), func(key string, item CacheItem) error {
+		return writeErr
+	}, func(key string, item CacheItem, err error) {
+		if err != writeErr {
+			t.Error("Expected the writer's error, got", err)
+		}
+		failed = append(failed, key)
+	})
+
+	// The cache still has the item, the error is reported through the callback
+	cache.Add("a", &DummyCacheItem{DummySize: 10})
+	cache.Close()
+	assertKeys(t, failed, []string{"a"})
+	if !cache.Contains("a") {
+		t.Error("a should still be in the cache")
+	}
+}
\ No newline at end of file